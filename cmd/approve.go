@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+
+	"github.com/spf13/cobra"
+)
+
+type approvePlanInput struct {
+	tfplan *tfplan.Plan
+	filter *tfplan.InspectFilter
+	set    map[string]string
+	signer string
+	key    ed25519.PrivateKey
+}
+
+func approvePlan(ctx context.Context, in *approvePlanInput) error {
+
+	out, err := tfplan.Approve(ctx, in.tfplan, in.signer, in.key,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+	)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("failed to json marshal approval caused by: %w", err)
+	}
+	fmt.Println(string(bytes))
+
+	return nil
+}
+
+type verifyApprovalInput struct {
+	tfplan    *tfplan.Plan
+	approvals []*tfplan.Approval
+	allowed   *tfplan.AllowedSigners
+}
+
+func verifyApproval(ctx context.Context, in *verifyApprovalInput) error {
+
+	if len(in.approvals) == 0 {
+		return fmt.Errorf("at least 1 approval is required to verify")
+	}
+
+	for _, approval := range in.approvals {
+		if err := tfplan.VerifyApproval(ctx, approval, in.tfplan, in.allowed); err != nil {
+			fmt.Printf("approval by %s rejected: %v\n", approval.Signer, err)
+			continue
+		}
+
+		fmt.Printf("plan approved by %s at %s\n", approval.Signer, approval.ApprovedAt)
+		return nil
+	}
+
+	os.Exit(2)
+	return nil
+}
+
+// approveCmd represents the approve command
+var approveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Sign an approval of a plan",
+	Long: `
+Computes a canonical digest of a JSON Terraform plan, covering every planned resource change
+and the filter it was inspected with, and prints an approval record of it signed with your
+ed25519 private key. Run it once you have reviewed the changes tfplan inspect flagged.
+
+The key is a PEM encoded PKCS #8 ed25519 private key, e.g. generated with
+$ openssl genpkey -algorithm ed25519 -out approver.pem
+
+The approval holds the base64 public key of the signer to add to the allowed signers of
+verify-approval.
+
+Example usage:
+$ tfplan approve \
+--plan "$(terraform show --json .plan)" \
+--filter "$(cat filter.json)" \
+--signer alice \
+--key approver.pem > approval.json
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		filterFlg, err := cmd.Flags().GetString("filter")
+		if err != nil {
+			return fmt.Errorf("failed to get filter flag caused by: %w", err)
+		}
+
+		filter := &tfplan.InspectFilter{}
+		if filterFlg != "" && filterFlg != "{}" {
+			filter, err = tfplan.ParseFilter([]byte(filterFlg))
+			if err != nil {
+				return err
+			}
+		}
+
+		setFlg, err := cmd.Flags().GetStringToString("set")
+		if err != nil {
+			return fmt.Errorf("failed to get set flag caused by: %w", err)
+		}
+
+		signerFlg, err := cmd.Flags().GetString("signer")
+		if err != nil {
+			return fmt.Errorf("failed to get signer flag caused by: %w", err)
+		}
+
+		keyFlg, err := cmd.Flags().GetString("key")
+		if err != nil {
+			return fmt.Errorf("failed to get key flag caused by: %w", err)
+		}
+
+		keyBytes, err := os.ReadFile(keyFlg)
+		if err != nil {
+			return fmt.Errorf("failed to read key file caused by: %w", err)
+		}
+
+		key, err := tfplan.ParsePrivateKey(keyBytes)
+		if err != nil {
+			return err
+		}
+
+		return approvePlan(cmd.Context(), &approvePlanInput{
+			tfplan: parsed,
+			filter: filter,
+			set:    setFlg,
+			signer: signerFlg,
+			key:    key,
+		})
+	},
+}
+
+// verifyApprovalCmd represents the verify-approval command
+var verifyApprovalCmd = &cobra.Command{
+	Use:   "verify-approval",
+	Short: "Verify a plan matches a signed approval",
+	Long: `
+Verifies at apply time that a JSON Terraform plan was approved. An approval is accepted when its
+signature is valid, its signer is on the allowed signers list with the same public key, and the
+digest of the plan computed with the approved filter matches the approved digest. Pass several
+--approval flags to accept any of them. Exit code 2 is returned when no approval is accepted.
+
+The allowed signers are JSON, e.g. {"signers": [{"name": "alice", "publicKey": "<base64>"}]}.
+
+Example usage:
+$ tfplan verify-approval \
+--plan "$(terraform show --json .plan)" \
+--approval "$(cat approval.json)" \
+--allowed-signers "$(cat allowed_signers.json)"
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		approvalFlg, err := cmd.Flags().GetStringArray("approval")
+		if err != nil {
+			return fmt.Errorf("failed to get approval flag caused by: %w", err)
+		}
+
+		approvals := []*tfplan.Approval{}
+		for _, a := range approvalFlg {
+			approval, err := tfplan.ParseApproval([]byte(a))
+			if err != nil {
+				return err
+			}
+			approvals = append(approvals, approval)
+		}
+
+		allowedSignersFlg, err := cmd.Flags().GetString("allowed-signers")
+		if err != nil {
+			return fmt.Errorf("failed to get allowed-signers flag caused by: %w", err)
+		}
+
+		allowed, err := tfplan.ParseAllowedSigners([]byte(allowedSignersFlg))
+		if err != nil {
+			return err
+		}
+
+		return verifyApproval(cmd.Context(), &verifyApprovalInput{
+			tfplan:    parsed,
+			approvals: approvals,
+			allowed:   allowed,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(approveCmd)
+	approveCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to approve")
+	approveCmd.PersistentFlags().StringP("filter", "f", "{}", "filter (json format) the plan was inspected with")
+	approveCmd.PersistentFlags().StringToStringP("set", "s", map[string]string{}, "values (key=value) for ${set:key} placeholders in the filter")
+	approveCmd.PersistentFlags().String("signer", "", "name of the approver")
+	approveCmd.PersistentFlags().StringP("key", "k", "", "path of the PEM encoded ed25519 private key to sign with")
+
+	// Required flags
+	approveCmd.MarkPersistentFlagRequired("plan")
+	approveCmd.MarkPersistentFlagRequired("signer")
+	approveCmd.MarkPersistentFlagRequired("key")
+
+	rootCmd.AddCommand(verifyApprovalCmd)
+	verifyApprovalCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to verify")
+	verifyApprovalCmd.PersistentFlags().StringArrayP("approval", "a", []string{}, "approval (json format) to verify the plan against, repeatable")
+	verifyApprovalCmd.PersistentFlags().String("allowed-signers", "", "signers (json format) whose approvals are accepted")
+
+	// Required flags
+	verifyApprovalCmd.MarkPersistentFlagRequired("plan")
+	verifyApprovalCmd.MarkPersistentFlagRequired("approval")
+	verifyApprovalCmd.MarkPersistentFlagRequired("allowed-signers")
+}
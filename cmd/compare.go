@@ -1,56 +1,108 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/orange-car/tfplan/internal/plan"
+	"github.com/orange-car/tfplan/pkg/tfplan"
 	"github.com/spf13/cobra"
 )
 
 type comparePlanInput struct {
-	planA            *plan.Plan
-	planB            *plan.Plan
-	filter           *plan.InspectFilter
+	planA            *tfplan.Plan
+	planB            *tfplan.Plan
+	plans            []tfplan.LabelledPlan
+	filter           *tfplan.InspectFilter
+	set              map[string]string
+	rewrites         *tfplan.RewriteRules
+	scopes           []string
+	metadataA        *tfplan.PlanMetadata
+	metadataB        *tfplan.PlanMetadata
+	options          *tfplan.CompareOptions
+	prettyOptions    *tfplan.PrettyOptions
 	prettyPrint      bool
 	detailedExitCode bool
 }
 
-func comparePlans(in *comparePlanInput) error {
+func compareManyPlans(ctx context.Context, in *comparePlanInput) error {
 
-	if in.planA == nil {
-		return fmt.Errorf("plan-a cannot be empty")
+	if len(in.scopes) > 0 {
+		return fmt.Errorf("scope is only supported with plan-a and plan-b")
 	}
 
-	if in.planB == nil {
-		return fmt.Errorf("plan-b cannot be empty")
+	if in.options != nil {
+		return fmt.Errorf("compare options are only supported with plan-a and plan-b")
 	}
 
-	aOut, err := in.planA.Inspect(&plan.InspectInput{
-		Filter: in.filter,
-	})
+	out, err := tfplan.CompareMany(ctx, in.plans,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+		tfplan.WithRewrites(in.rewrites),
+	)
 	if err != nil {
 		return err
 	}
 
-	bOut, err := in.planB.Inspect(&plan.InspectInput{
-		Filter: in.filter,
-	})
+	if in.prettyPrint {
+		for _, line := range out.Pretty() {
+			fmt.Print(line)
+		}
+	} else {
+		bytes, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("failed to json marshal inspection output caused by: %w", err)
+		}
+		fmt.Println(string(bytes))
+	}
+
+	if !out.IsEmpty() && in.detailedExitCode {
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+func comparePlans(ctx context.Context, in *comparePlanInput) error {
+
+	if len(in.plans) > 0 {
+		return compareManyPlans(ctx, in)
+	}
+
+	if in.planA == nil {
+		return fmt.Errorf("plan-a cannot be empty")
+	}
+
+	if in.planB == nil {
+		return fmt.Errorf("plan-b cannot be empty")
+	}
+
+	out, err := tfplan.Compare(ctx, in.planA, in.planB,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+		tfplan.WithRewrites(in.rewrites),
+		tfplan.WithScopes(in.scopes...),
+		tfplan.WithMetadata(in.metadataA, in.metadataB),
+		tfplan.WithCompareOptions(in.options),
+	)
 	if err != nil {
 		return err
 	}
 
-	out := plan.CompareInspects(aOut, bOut)
-
 	if in.prettyPrint {
-		for _, line := range out.Pretty() {
+		lines, err := out.PrettyWithOptions(in.prettyOptions)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
 			fmt.Print(line)
 		}
 	} else {
 		bytes, err := json.Marshal(out)
 		if err != nil {
-			return fmt.Errorf("failed to json marshal inspection output caused by: %v", err)
+			return fmt.Errorf("failed to json marshal inspection output caused by: %w", err)
 		}
 		fmt.Println(string(bytes))
 	}
@@ -74,6 +126,23 @@ differences between the two plans.
 Comparing plans programmatically is particularly useful when they are large and/or you have to do
 it often. Applying the optional filter can be useful to rule out changes you don't care about.
 
+To compare N plans, e.g. one per environment, use --plan (-p) once for each plan with a label. Each
+diverging attribute is reported against each plan and plans differing from the majority are
+marked as outliers. Without a majority, e.g. when two plans differ, every plan is an outlier.
+
+Use --scope to also compare the un-filtered changes (raw), variables, provider configuration
+(providers) and plan metadata such as the Terraform version (metadata) of --plan-a and --plan-b.
+
+Use --unknown-as-wildcard, --typed-values, --unordered and --ignore-path to tolerate equivalent
+values when comparing --plan-a and --plan-b. Every suppressed difference is reported.
+
+Use --view side-by-side or --view unified with --pretty to print each diverging attribute once with
+the Plan A and Plan B changes next to each other. Use --no-color for plain text.
+
+Use --mode after to only compare the values each plan converges to, e.g. when the environments
+had different prior state. --mode before only compares the prior values and --mode actions only
+compares the planned actions (create, update, delete...) of each resource.
+
 Example usage:
 $ tfplan compare \
 --plan-a "$(terraform show --json a.plan)" \
@@ -81,6 +150,12 @@ $ tfplan compare \
 --detailed-exitcode \
 --filter "$(cat filter.json)" \
 --pretty
+
+$ tfplan compare \
+--plan dev="$(terraform show --json dev.plan)" \
+--plan staging="$(terraform show --json staging.plan)" \
+--plan prod="$(terraform show --json prod.plan)" \
+--detailed-exitcode
 `,
 	PreRunE: nil,
 	// RunE:    compareRunner,
@@ -88,48 +163,168 @@ $ tfplan compare \
 
 		planAFlg, err := cmd.Flags().GetString("plan-a")
 		if err != nil {
-			return fmt.Errorf("failed to get plan-a flag caused by: %v", err)
+			return fmt.Errorf("failed to get plan-a flag caused by: %w", err)
 		}
 
 		planBFlg, err := cmd.Flags().GetString("plan-b")
 		if err != nil {
-			return fmt.Errorf("failed to get plan-b flag caused by: %v", err)
+			return fmt.Errorf("failed to get plan-b flag caused by: %w", err)
 		}
 
-		tfplanA, err := plan.ParsePlan([]byte(planAFlg))
+		planFlgs, err := cmd.Flags().GetStringArray("plan")
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		var tfplanA, tfplanB *tfplan.Plan
+		var metadataA, metadataB *tfplan.PlanMetadata
+		plans := []tfplan.LabelledPlan{}
+		if len(planFlgs) > 0 {
+			if planAFlg != "" || planBFlg != "" {
+				return fmt.Errorf("plan cannot be used with plan-a or plan-b")
+			}
+
+			labels := map[string]bool{}
+			for _, planFlg := range planFlgs {
+				label, planJson, ok := strings.Cut(planFlg, "=")
+				if !ok || label == "" {
+					return fmt.Errorf("plan must be in the format label=plan")
+				}
+				if labels[label] {
+					return fmt.Errorf("plan label %s is used more than once", label)
+				}
+				labels[label] = true
+
+				parsed, err := tfplan.ParsePlan([]byte(planJson))
+				if err != nil {
+					return fmt.Errorf("failed to parse plan %s caused by: %w", label, err)
+				}
+				plans = append(plans, tfplan.LabelledPlan{Label: label, Plan: parsed})
+			}
+		} else {
+			if planAFlg == "" || planBFlg == "" {
+				return fmt.Errorf("plan-a and plan-b are required when plan is not used")
+			}
+
+			tfplanA, err = tfplan.ParsePlan([]byte(planAFlg))
+			if err != nil {
+				return err
+			}
+
+			tfplanB, err = tfplan.ParsePlan([]byte(planBFlg))
+			if err != nil {
+				return err
+			}
+
+			metadataA, err = tfplan.ParsePlanMetadata([]byte(planAFlg))
+			if err != nil {
+				return err
+			}
+
+			metadataB, err = tfplan.ParsePlanMetadata([]byte(planBFlg))
+			if err != nil {
+				return err
+			}
+		}
+
+		filterFlg, err := cmd.Flags().GetString("filter")
+		if err != nil {
+			return fmt.Errorf("failed to get filter flag caused by: %w", err)
 		}
 
-		tfplanB, err := plan.ParsePlan([]byte(planBFlg))
+		filter, err := tfplan.ParseFilter([]byte(filterFlg))
 		if err != nil {
 			return err
 		}
 
-		filterFlg, err := cmd.Flags().GetString("filter")
+		rewriteFlg, err := cmd.Flags().GetString("rewrite")
 		if err != nil {
-			return fmt.Errorf("failed to get filter flag caused by: %v", err)
+			return fmt.Errorf("failed to get rewrite flag caused by: %w", err)
 		}
 
-		filter, err := plan.ParseInspectFilter([]byte(filterFlg))
+		rewrites, err := tfplan.ParseRewriteRules([]byte(rewriteFlg))
 		if err != nil {
 			return err
 		}
 
+		scopeFlg, err := cmd.Flags().GetStringSlice("scope")
+		if err != nil {
+			return fmt.Errorf("failed to get scope flag caused by: %w", err)
+		}
+
+		unknownFlg, err := cmd.Flags().GetBool("unknown-as-wildcard")
+		if err != nil {
+			return fmt.Errorf("failed to get unknown-as-wildcard flag caused by: %w", err)
+		}
+
+		typedFlg, err := cmd.Flags().GetBool("typed-values")
+		if err != nil {
+			return fmt.Errorf("failed to get typed-values flag caused by: %w", err)
+		}
+
+		unorderedFlg, err := cmd.Flags().GetStringSlice("unordered")
+		if err != nil {
+			return fmt.Errorf("failed to get unordered flag caused by: %w", err)
+		}
+
+		ignoreFlg, err := cmd.Flags().GetStringSlice("ignore-path")
+		if err != nil {
+			return fmt.Errorf("failed to get ignore-path flag caused by: %w", err)
+		}
+
+		modeFlg, err := cmd.Flags().GetString("mode")
+		if err != nil {
+			return fmt.Errorf("failed to get mode flag caused by: %w", err)
+		}
+
+		var options *tfplan.CompareOptions
+		if unknownFlg || typedFlg || len(unorderedFlg) > 0 || len(ignoreFlg) > 0 || modeFlg != tfplan.CompareModeFull {
+			options = &tfplan.CompareOptions{
+				Mode:              modeFlg,
+				UnknownAsWildcard: unknownFlg,
+				TypedValues:       typedFlg,
+				UnorderedLists:    unorderedFlg,
+				IgnorePaths:       ignoreFlg,
+			}
+		}
+
+		setFlg, err := cmd.Flags().GetStringToString("set")
+		if err != nil {
+			return fmt.Errorf("failed to get set flag caused by: %w", err)
+		}
+
 		detailedFlg, err := cmd.Flags().GetBool("detailed-exitcode")
 		if err != nil {
-			return fmt.Errorf("failed to get detailed-exitcode flag caused by: %v", err)
+			return fmt.Errorf("failed to get detailed-exitcode flag caused by: %w", err)
 		}
 
 		prettyFlg, err := cmd.Flags().GetBool("pretty")
 		if err != nil {
-			return fmt.Errorf("failed to get pretty flag caused by: %v", err)
+			return fmt.Errorf("failed to get pretty flag caused by: %w", err)
 		}
 
-		return comparePlans(&comparePlanInput{
+		viewFlg, err := cmd.Flags().GetString("view")
+		if err != nil {
+			return fmt.Errorf("failed to get view flag caused by: %w", err)
+		}
+
+		noColorFlg, err := cmd.Flags().GetBool("no-color")
+		if err != nil {
+			return fmt.Errorf("failed to get no-color flag caused by: %w", err)
+		}
+
+		return comparePlans(cmd.Context(), &comparePlanInput{
 			planA:            tfplanA,
 			planB:            tfplanB,
+			plans:            plans,
 			filter:           filter,
+			set:              setFlg,
+			rewrites:         rewrites,
+			scopes:           scopeFlg,
+			metadataA:        metadataA,
+			metadataB:        metadataB,
+			options:          options,
+			prettyOptions:    &tfplan.PrettyOptions{View: viewFlg, NoColor: noColorFlg},
 			prettyPrint:      prettyFlg,
 			detailedExitCode: detailedFlg,
 		})
@@ -141,11 +336,18 @@ func init() {
 
 	compareCmd.PersistentFlags().StringP("plan-a", "a", "", "plan (json format) to compare against --plan-b (-b)")
 	compareCmd.PersistentFlags().StringP("plan-b", "b", "", "plan (json format) to compare against --plan-a (-a)")
+	compareCmd.PersistentFlags().StringArrayP("plan", "p", []string{}, "labelled plan (label=json format) to compare against every other --plan (-p). Repeat for N plans")
 	compareCmd.PersistentFlags().StringP("filter", "f", "{}", "filter (json format) to filter out changes")
+	compareCmd.PersistentFlags().StringP("rewrite", "r", "{}", "rewrite rules (json format) normalising addresses and values of both plans before comparing")
+	compareCmd.PersistentFlags().StringSlice("scope", []string{}, "additional scopes (raw, variables, providers, metadata) to compare")
+	compareCmd.PersistentFlags().String("mode", tfplan.CompareModeFull, "which side of each change to compare (full, after, before, actions)")
+	compareCmd.PersistentFlags().Bool("unknown-as-wildcard", false, "treat (known after apply) as equal to any value")
+	compareCmd.PersistentFlags().Bool("typed-values", false, "compare values as numbers or booleans when both sides parse as one")
+	compareCmd.PersistentFlags().StringSlice("unordered", []string{}, "patterns of list paths whose elements are compared regardless of order")
+	compareCmd.PersistentFlags().StringSlice("ignore-path", []string{}, "patterns of paths to leave out of the compare")
+	compareCmd.PersistentFlags().StringToStringP("set", "s", map[string]string{}, "values (key=value) for ${set:key} placeholders in the filter")
 	compareCmd.PersistentFlags().BoolP("detailed-exitcode", "d", false, "when used, exit code 2 will return when there are unfiltered changes")
 	compareCmd.PersistentFlags().BoolP("pretty", "P", false, "print the results in a human readable format")
-
-	// Required flags
-	compareCmd.MarkPersistentFlagRequired("plan-a")
-	compareCmd.MarkPersistentFlagRequired("plan-b")
+	compareCmd.PersistentFlags().String("view", tfplan.CompareViewBlocks, "how --pretty prints diverging changes of plan-a and plan-b (blocks, side-by-side, unified)")
+	compareCmd.PersistentFlags().Bool("no-color", false, "print --pretty results as plain text without colour")
 }
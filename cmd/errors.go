@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+)
+
+// Value of the output flag printing errors as structured JSON.
+const outputJSON = "json"
+
+// Set once the command has parsed its flags and --output json is set.
+var jsonErrors bool
+
+/*
+Checks the command line for --output json ahead of cobra, so errors parsing
+the flags, which cobra raises before PersistentPreRunE, are printed as JSON
+too.
+*/
+func outputJSONRequested(args []string) bool {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return false
+		case arg == "--output=json":
+			return true
+		case arg == "--output" && i+1 < len(args) && args[i+1] == outputJSON:
+			return true
+		}
+	}
+	return false
+}
+
+type errorDetail struct {
+	// The full error message, including its causes.
+	Message string `json:"message"`
+	// The kind of the error, e.g. "invalid filter pattern".
+	Kind string `json:"kind,omitempty"`
+	// The input which could not be parsed, e.g. "plan" or "filter".
+	Input string `json:"input,omitempty"`
+	// The operation which failed, e.g. "inspect" or "compare".
+	Operation string `json:"operation,omitempty"`
+	Address   string `json:"address,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Filter    string `json:"filter,omitempty"`
+	Rule      *int   `json:"rule,omitempty"`
+}
+
+type errorOutput struct {
+	Error errorDetail `json:"error"`
+}
+
+/*
+Prints the error to stderr as {"error": {...}}, located in the plan and
+filter when the chain holds a tfplan.Error.
+*/
+func printJSONError(err error) {
+	out := errorOutput{Error: errorDetail{Message: err.Error()}}
+
+	var planErr *tfplan.Error
+	if errors.As(err, &planErr) {
+		if planErr.Kind != nil {
+			out.Error.Kind = planErr.Kind.Error()
+		}
+		out.Error.Address = planErr.Address
+		out.Error.Path = planErr.Path
+		out.Error.Filter = planErr.Filter
+		if planErr.Filter != "" {
+			out.Error.Rule = &planErr.Rule
+		}
+	}
+	var parseErr *tfplan.ParseError
+	if errors.As(err, &parseErr) {
+		out.Error.Input = parseErr.Input
+	}
+	var opErr *tfplan.OperationError
+	if errors.As(err, &opErr) {
+		out.Error.Operation = opErr.Op
+	}
+
+	// Messages hold version constraints such as >= 0.1 which are left as is
+	enc := json.NewEncoder(os.Stderr)
+	enc.SetEscapeHTML(false)
+	if encErr := enc.Encode(out); encErr != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+}
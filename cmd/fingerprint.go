@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	fingerprintOnlyAll       = "all"
+	fingerprintOnlyRemaining = "remaining"
+)
+
+type fingerprintPlanInput struct {
+	tfplan         *tfplan.Plan
+	filter         *tfplan.InspectFilter
+	set            map[string]string
+	ignorePaths    []string
+	unorderedLists []string
+	only           string
+}
+
+func fingerprintPlan(ctx context.Context, in *fingerprintPlanInput) error {
+
+	out, err := tfplan.Fingerprint(ctx, in.tfplan,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+		tfplan.WithCompareOptions(&tfplan.CompareOptions{
+			IgnorePaths:    in.ignorePaths,
+			UnorderedLists: in.unorderedLists,
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	switch in.only {
+	case "":
+		bytes, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("failed to json marshal fingerprint output caused by: %w", err)
+		}
+		fmt.Println(string(bytes))
+	case fingerprintOnlyAll:
+		fmt.Println(out.All)
+	case fingerprintOnlyRemaining:
+		fmt.Println(out.Remaining)
+	default:
+		return fmt.Errorf("unknown fingerprint %s", in.only)
+	}
+
+	return nil
+}
+
+// fingerprintCmd represents the fingerprint command
+var fingerprintCmd = &cobra.Command{
+	Use:   "fingerprint",
+	Short: "Compute a stable hash of the changes of a plan",
+	Long: `
+Computes stable sha256 fingerprints of the semantic change set of a JSON Terraform plan: the
+resource changes, their planned actions and the output changes. The all fingerprint covers every
+change and the remaining fingerprint the changes left after applying your provided filter
+criteria. Use them as cache keys so CI can recognise a plan it has already reviewed.
+
+The fingerprints ignore the order of the plan JSON and resource drift. Use --ignore-path to leave
+volatile paths such as timestamps out, and --unordered for lists whose elements are planned in
+no particular order. Use --only to print just one of the fingerprints.
+
+Example usage:
+$ tfplan fingerprint \
+--plan "$(terraform show --json .plan)" \
+--filter "$(cat filter.json)" \
+--ignore-path '.tags.LastModified' \
+--only remaining
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		filterFlg, err := cmd.Flags().GetString("filter")
+		if err != nil {
+			return fmt.Errorf("failed to get filter flag caused by: %w", err)
+		}
+
+		filter := &tfplan.InspectFilter{}
+		if filterFlg != "" && filterFlg != "{}" {
+			filter, err = tfplan.ParseFilter([]byte(filterFlg))
+			if err != nil {
+				return err
+			}
+		}
+
+		setFlg, err := cmd.Flags().GetStringToString("set")
+		if err != nil {
+			return fmt.Errorf("failed to get set flag caused by: %w", err)
+		}
+
+		unorderedFlg, err := cmd.Flags().GetStringSlice("unordered")
+		if err != nil {
+			return fmt.Errorf("failed to get unordered flag caused by: %w", err)
+		}
+
+		ignoreFlg, err := cmd.Flags().GetStringSlice("ignore-path")
+		if err != nil {
+			return fmt.Errorf("failed to get ignore-path flag caused by: %w", err)
+		}
+
+		onlyFlg, err := cmd.Flags().GetString("only")
+		if err != nil {
+			return fmt.Errorf("failed to get only flag caused by: %w", err)
+		}
+
+		return fingerprintPlan(cmd.Context(), &fingerprintPlanInput{
+			tfplan:         parsed,
+			filter:         filter,
+			set:            setFlg,
+			ignorePaths:    ignoreFlg,
+			unorderedLists: unorderedFlg,
+			only:           onlyFlg,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fingerprintCmd)
+	fingerprintCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to fingerprint")
+	fingerprintCmd.PersistentFlags().StringP("filter", "f", "{}", "filter (json format) to filter out changes for the remaining fingerprint")
+	fingerprintCmd.PersistentFlags().StringToStringP("set", "s", map[string]string{}, "values (key=value) for ${set:key} placeholders in the filter")
+	fingerprintCmd.PersistentFlags().StringSlice("unordered", []string{}, "patterns of list paths whose elements are fingerprinted regardless of order")
+	fingerprintCmd.PersistentFlags().StringSlice("ignore-path", []string{}, "patterns of volatile paths to leave out of the fingerprints")
+	fingerprintCmd.PersistentFlags().String("only", "", "print only the all or remaining fingerprint")
+
+	// Required flags
+	fingerprintCmd.MarkPersistentFlagRequired("plan")
+}
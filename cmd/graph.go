@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+
+	"github.com/spf13/cobra"
+)
+
+type graphPlanInput struct {
+	tfplan         *tfplan.Plan
+	filter         *tfplan.InspectFilter
+	set            map[string]string
+	format         string
+	unfilteredOnly bool
+}
+
+func graphPlan(ctx context.Context, in *graphPlanInput) error {
+
+	opts := []tfplan.Option{tfplan.WithFilter(in.filter), tfplan.WithSet(in.set)}
+	if in.unfilteredOnly {
+		opts = append(opts, tfplan.WithUnfilteredOnly())
+	}
+
+	out, err := tfplan.Graph(ctx, in.tfplan, opts...)
+	if err != nil {
+		return err
+	}
+
+	switch in.format {
+	case tfplan.GraphFormatDOT:
+		for _, line := range out.DOT() {
+			fmt.Print(line)
+		}
+	case tfplan.GraphFormatMermaid:
+		for _, line := range out.Mermaid() {
+			fmt.Print(line)
+		}
+	case tfplan.GraphFormatJSON:
+		bytes, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("failed to json marshal graph output caused by: %w", err)
+		}
+		fmt.Println(string(bytes))
+	default:
+		return fmt.Errorf("unknown graph format %s", in.format)
+	}
+
+	return nil
+}
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Render the changes of a plan as a graph",
+	Long: `
+Renders the planned changes of a JSON Terraform plan as a graph. Nodes are coloured by action
+(create, update, replace, delete or move) and grouped by module. Edges are taken from the
+references and depends_on of the configuration in the plan. Changes depending on each other
+through values that are not changed, e.g. module variables, are connected directly.
+
+Use --unfiltered-only to only show the changes not filtered out by your provided filter criteria.
+
+The graph is printed in the Graphviz DOT language (default), as a Mermaid flowchart or as JSON.
+
+Example usage:
+$ tfplan graph \
+--plan "$(terraform show --json .plan)" \
+--filter "$(cat filter.json)" \
+--unfiltered-only | dot -Tsvg > tfplan.svg
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		filterFlg, err := cmd.Flags().GetString("filter")
+		if err != nil {
+			return fmt.Errorf("failed to get filter flag caused by: %w", err)
+		}
+
+		filter := &tfplan.InspectFilter{}
+		if filterFlg != "" && filterFlg != "{}" {
+			filter, err = tfplan.ParseFilter([]byte(filterFlg))
+			if err != nil {
+				return err
+			}
+		}
+
+		setFlg, err := cmd.Flags().GetStringToString("set")
+		if err != nil {
+			return fmt.Errorf("failed to get set flag caused by: %w", err)
+		}
+
+		formatFlg, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed to get format flag caused by: %w", err)
+		}
+
+		unfilteredOnlyFlg, err := cmd.Flags().GetBool("unfiltered-only")
+		if err != nil {
+			return fmt.Errorf("failed to get unfiltered-only flag caused by: %w", err)
+		}
+
+		return graphPlan(cmd.Context(), &graphPlanInput{
+			tfplan:         parsed,
+			filter:         filter,
+			set:            setFlg,
+			format:         formatFlg,
+			unfilteredOnly: unfilteredOnlyFlg,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to render")
+	graphCmd.PersistentFlags().StringP("filter", "f", "{}", "filter (json format) to filter out changes with --unfiltered-only")
+	graphCmd.PersistentFlags().StringToStringP("set", "s", map[string]string{}, "values (key=value) for ${set:key} placeholders in the filter")
+	graphCmd.PersistentFlags().StringP("format", "F", tfplan.GraphFormatDOT, "format of the graph (dot, mermaid, json)")
+	graphCmd.PersistentFlags().BoolP("unfiltered-only", "u", false, "only show the changes not filtered out by the filter")
+
+	// Required flags
+	graphCmd.MarkPersistentFlagRequired("plan")
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+
+	"github.com/spf13/cobra"
+)
+
+type impactPlanInput struct {
+	tfplan           *tfplan.Plan
+	filter           *tfplan.InspectFilter
+	set              map[string]string
+	addresses        []string
+	prettyPrint      bool
+	detailedExitCode bool
+}
+
+func impactPlan(ctx context.Context, in *impactPlanInput) error {
+
+	out, err := tfplan.Impact(ctx, in.tfplan, in.addresses,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+	)
+	if err != nil {
+		return err
+	}
+
+	if in.prettyPrint {
+		for _, line := range out.Pretty() {
+			fmt.Print(line)
+		}
+	} else {
+		bytes, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("failed to json marshal impact output caused by: %w", err)
+		}
+		fmt.Println(string(bytes))
+	}
+
+	if !out.IsEmpty() && in.detailedExitCode {
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+// impactCmd represents the impact command
+var impactCmd = &cobra.Command{
+	Use:   "impact [address...]",
+	Short: "Show which planned changes depend on each change",
+	Long: `
+Analyses the blast radius of the changes in a JSON Terraform plan. Walks the references and
+depends_on of the configuration in the plan to find which other planned changes depend on each
+change not filtered out by your provided filter criteria, directly or through other resources,
+module variables and outputs. Changes are ranked by how many downstream changes they cause.
+
+Pass wildcard-supported address patterns as arguments to only analyse matching changes.
+
+References through locals are not recorded in the plan configuration so can't be followed.
+
+Example usage:
+$ tfplan impact aws_vpc.main \
+--plan "$(terraform show --json .plan)" \
+--filter "$(cat filter.json)" \
+--pretty
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		filterFlg, err := cmd.Flags().GetString("filter")
+		if err != nil {
+			return fmt.Errorf("failed to get filter flag caused by: %w", err)
+		}
+
+		filter := &tfplan.InspectFilter{}
+		if filterFlg != "" && filterFlg != "{}" {
+			filter, err = tfplan.ParseFilter([]byte(filterFlg))
+			if err != nil {
+				return err
+			}
+		}
+
+		setFlg, err := cmd.Flags().GetStringToString("set")
+		if err != nil {
+			return fmt.Errorf("failed to get set flag caused by: %w", err)
+		}
+
+		detailedFlg, err := cmd.Flags().GetBool("detailed-exitcode")
+		if err != nil {
+			return fmt.Errorf("failed to get detailed-exitcode flag caused by: %w", err)
+		}
+
+		prettyFlg, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			return fmt.Errorf("failed to get pretty flag caused by: %w", err)
+		}
+
+		return impactPlan(cmd.Context(), &impactPlanInput{
+			tfplan:           parsed,
+			filter:           filter,
+			set:              setFlg,
+			addresses:        args,
+			prettyPrint:      prettyFlg,
+			detailedExitCode: detailedFlg,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(impactCmd)
+	impactCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to analyse")
+	impactCmd.PersistentFlags().StringP("filter", "f", "{}", "filter (json format) to filter out changes from the analysis")
+	impactCmd.PersistentFlags().StringToStringP("set", "s", map[string]string{}, "values (key=value) for ${set:key} placeholders in the filter")
+	impactCmd.PersistentFlags().BoolP("detailed-exitcode", "d", false, "when used, exit code 2 will return when any change has dependents")
+	impactCmd.PersistentFlags().BoolP("pretty", "P", false, "print the results in a human readable format")
+
+	// Required flags
+	impactCmd.MarkPersistentFlagRequired("plan")
+}
@@ -1,43 +1,67 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/orange-car/tfplan/internal/plan"
+	"github.com/orange-car/tfplan/internal/tui"
+	"github.com/orange-car/tfplan/pkg/tfplan"
 
 	"github.com/spf13/cobra"
 )
 
 type inspectPlanInput struct {
-	tfplan           *plan.Plan
-	filter           *plan.InspectFilter
+	tfplan           *tfplan.Plan
+	filter           *tfplan.InspectFilter
+	set              map[string]string
+	filterFile       string
+	configDir        string
+	annotate         bool
+	output           string
+	interactive      bool
 	prettyPrint      bool
 	detailedExitCode bool
 }
 
-func inspectPlan(in *inspectPlanInput) error {
+func inspectPlan(ctx context.Context, in *inspectPlanInput) error {
 
 	if in.tfplan == nil {
-		return fmt.Errorf("plan cannot be empty")
+		return tfplan.ErrNilPlan
 	}
 
-	out, err := in.tfplan.Inspect(&plan.InspectInput{
-		Filter: in.filter,
-	})
+	if in.interactive {
+		return browsePlan(in)
+	}
+
+	if in.output != "" && in.output != outputJSON {
+		return reportPlan(ctx, in)
+	}
+
+	out, err := tfplan.Inspect(ctx, in.tfplan,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+		tfplan.WithConfigDir(in.configDir),
+	)
 	if err != nil {
 		return err
 	}
 
-	if in.prettyPrint {
+	if in.annotate {
+		for _, line := range out.Annotations() {
+			fmt.Print(line)
+		}
+	} else if in.prettyPrint {
 		for _, line := range out.Pretty() {
 			fmt.Print(line)
 		}
 	} else {
 		bytes, err := json.Marshal(out)
 		if err != nil {
-			return fmt.Errorf("failed to json marshal inspection output caused by: %v", err)
+			return fmt.Errorf("failed to json marshal inspection output caused by: %w", err)
 		}
 		fmt.Println(string(bytes))
 	}
@@ -49,6 +73,90 @@ func inspectPlan(in *inspectPlanInput) error {
 	return nil
 }
 
+/*
+Appends the lines to the file at the path held by the environment variable,
+if set.
+*/
+func appendToEnvFile(name string, lines []string) error {
+	path := os.Getenv(name)
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s file caused by: %w", name, err)
+	}
+	defer f.Close()
+
+	for _, line := range lines {
+		if _, err := f.WriteString(line); err != nil {
+			return fmt.Errorf("failed to write %s file caused by: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func reportPlan(ctx context.Context, in *inspectPlanInput) error {
+	report, err := tfplan.Report(ctx, in.tfplan,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+		tfplan.WithConfigDir(in.configDir),
+	)
+	if err != nil {
+		return err
+	}
+
+	switch in.output {
+	case tfplan.CIOutputGitHub:
+		for _, line := range report.GitHubCommands() {
+			fmt.Print(line)
+		}
+		if err := appendToEnvFile("GITHUB_STEP_SUMMARY", report.GitHubSummary()); err != nil {
+			return err
+		}
+		if err := appendToEnvFile("GITHUB_OUTPUT", report.GitHubOutputs()); err != nil {
+			return err
+		}
+	case tfplan.CIOutputGitLab:
+		bytes, err := json.Marshal(report.GitLabCodeQuality())
+		if err != nil {
+			return fmt.Errorf("failed to json marshal code quality report caused by: %w", err)
+		}
+		fmt.Println(string(bytes))
+	default:
+		return fmt.Errorf("unknown output %s", in.output)
+	}
+
+	if !report.Blocked.IsEmpty() && in.detailedExitCode {
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+func browsePlan(in *inspectPlanInput) error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return fmt.Errorf("failed to create terminal screen caused by: %w", err)
+	}
+	if err := screen.Init(); err != nil {
+		return fmt.Errorf("failed to initialise terminal screen caused by: %w", err)
+	}
+	defer screen.Fini()
+
+	app, err := tui.New(screen, &tui.Input{
+		Plan:       (*plan.Plan)(in.tfplan),
+		Filter:     in.filter,
+		Set:        in.set,
+		FilterFile: in.filterFile,
+	})
+	if err != nil {
+		return err
+	}
+	return app.Run()
+}
+
 // inspectCmd represents the inspect command
 var inspectCmd = &cobra.Command{
 	Use:   "inspect",
@@ -72,46 +180,129 @@ $ tfplan inspect \
 --detailed-exitcode \
 --filter "$(cat filter.json)" \
 --pretty
+
+Use --tui to browse the changes as a tree of modules, resources and attributes. Search with /,
+collapse with the arrow keys, toggle filtered changes with f and press a on an attribute to add
+a rule allowing its change to the --filter-file.
+
+$ tfplan inspect \
+--plan "$(terraform show --json .plan)" \
+--filter-file filter.json \
+--tui
+
+Use --config-dir to parse the Terraform configuration and attach the file and line declaring
+each change to the results. Local modules are found from their source and remote modules from
+the .terraform/modules manifest written by terraform init. Use --annotate to print GitHub
+workflow annotations pointing at those lines instead of the results.
+
+$ tfplan inspect \
+--plan "$(terraform show --json .plan)" \
+--filter "$(cat filter.json)" \
+--config-dir . \
+--annotate
+
+Use --output github in GitHub Actions to print an error annotation per un-filtered change,
+append a summary to $GITHUB_STEP_SUMMARY and write the has_changes and blocked_count step
+outputs to $GITHUB_OUTPUT. Use --output gitlab in GitLab CI to print a Code Quality report.
+Use --output json to print the results as json and any error as {"error": {...}} on stderr.
+
+$ tfplan inspect \
+--plan "$(terraform show --json .plan)" \
+--filter "$(cat filter.json)" \
+--config-dir . \
+--output gitlab > gl-code-quality-report.json
 `,
 	PreRunE: nil,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		planFlg, err := cmd.Flags().GetString("plan")
 		if err != nil {
-			return fmt.Errorf("failed to get plan flag caused by: %v", err)
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
 		}
 
-		tfplan, err := plan.ParsePlan([]byte(planFlg))
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
 		if err != nil {
 			return err
 		}
 
 		filterFlg, err := cmd.Flags().GetString("filter")
 		if err != nil {
-			return fmt.Errorf("failed to get filter flag caused by: %v", err)
+			return fmt.Errorf("failed to get filter flag caused by: %w", err)
 		}
 
-		filter := &plan.InspectFilter{}
+		filter := &tfplan.InspectFilter{}
 		if filterFlg != "" && filterFlg != "{}" {
-			filter, err = plan.ParseInspectFilter([]byte(filterFlg))
+			filter, err = tfplan.ParseFilter([]byte(filterFlg))
 			if err != nil {
 				return err
 			}
 		}
 
+		filterFileFlg, err := cmd.Flags().GetString("filter-file")
+		if err != nil {
+			return fmt.Errorf("failed to get filter-file flag caused by: %w", err)
+		}
+
+		if filterFileFlg != "" {
+			if cmd.Flags().Changed("filter") {
+				return fmt.Errorf("filter cannot be used with filter-file")
+			}
+
+			bytes, err := os.ReadFile(filterFileFlg)
+			if err == nil {
+				filter, err = tfplan.ParseFilter(bytes)
+				if err != nil {
+					return err
+				}
+			} else if !os.IsNotExist(err) {
+				return fmt.Errorf("failed to read filter file caused by: %w", err)
+			}
+		}
+
+		configDirFlg, err := cmd.Flags().GetString("config-dir")
+		if err != nil {
+			return fmt.Errorf("failed to get config-dir flag caused by: %w", err)
+		}
+
+		annotateFlg, err := cmd.Flags().GetBool("annotate")
+		if err != nil {
+			return fmt.Errorf("failed to get annotate flag caused by: %w", err)
+		}
+
+		outputFlg, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return fmt.Errorf("failed to get output flag caused by: %w", err)
+		}
+
+		tuiFlg, err := cmd.Flags().GetBool("tui")
+		if err != nil {
+			return fmt.Errorf("failed to get tui flag caused by: %w", err)
+		}
+
+		setFlg, err := cmd.Flags().GetStringToString("set")
+		if err != nil {
+			return fmt.Errorf("failed to get set flag caused by: %w", err)
+		}
+
 		detailedFlg, err := cmd.Flags().GetBool("detailed-exitcode")
 		if err != nil {
-			return fmt.Errorf("failed to get detailed-exitcode flag caused by: %v", err)
+			return fmt.Errorf("failed to get detailed-exitcode flag caused by: %w", err)
 		}
 
 		prettyFlg, err := cmd.Flags().GetBool("pretty")
 		if err != nil {
-			return fmt.Errorf("failed to get pretty flag caused by: %v", err)
+			return fmt.Errorf("failed to get pretty flag caused by: %w", err)
 		}
 
-		return inspectPlan(&inspectPlanInput{
-			tfplan:           tfplan,
+		return inspectPlan(cmd.Context(), &inspectPlanInput{
+			tfplan:           parsed,
 			filter:           filter,
+			set:              setFlg,
+			filterFile:       filterFileFlg,
+			configDir:        configDirFlg,
+			annotate:         annotateFlg,
+			output:           outputFlg,
+			interactive:      tuiFlg,
 			prettyPrint:      prettyFlg,
 			detailedExitCode: detailedFlg,
 		})
@@ -122,6 +313,12 @@ func init() {
 	rootCmd.AddCommand(inspectCmd)
 	inspectCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to inspect")
 	inspectCmd.PersistentFlags().StringP("filter", "f", "{}", "filter (json format) to filter out changes")
+	inspectCmd.PersistentFlags().String("filter-file", "", "filter file (json format) to filter out changes, cannot be used with --filter (-f). Rules added in --tui are written to it")
+	inspectCmd.PersistentFlags().String("config-dir", "", "directory of the Terraform configuration to locate the file and line of each change in")
+	inspectCmd.PersistentFlags().Bool("annotate", false, "print GitHub workflow annotations for the un-filtered changes instead of the results")
+	inspectCmd.PersistentFlags().String("output", "", "write the results for a CI system (github, gitlab) instead of json or pretty printed, or json to also print errors as structured JSON")
+	inspectCmd.PersistentFlags().Bool("tui", false, "browse the changes in an interactive terminal UI")
+	inspectCmd.PersistentFlags().StringToStringP("set", "s", map[string]string{}, "values (key=value) for ${set:key} placeholders in the filter")
 	inspectCmd.PersistentFlags().BoolP("detailed-exitcode", "d", false, "when used, exit code 2 will return when there are unfiltered changes")
 	inspectCmd.PersistentFlags().BoolP("pretty", "P", false, "print the results in a human readable format")
 
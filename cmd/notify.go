@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/orange-car/tfplan/internal/notify"
+	"github.com/orange-car/tfplan/pkg/tfplan"
+
+	"github.com/spf13/cobra"
+)
+
+type notifyPlanInput struct {
+	inspect    *tfplan.InspectOutput
+	compare    *tfplan.CompareInspectsOutput
+	target     string
+	repository string
+	number     int
+	token      string
+	apiURL     string
+	webhookURL string
+	key        string
+	client     notify.Doer
+}
+
+func newNotifier(in *notifyPlanInput) (notify.Notifier, error) {
+	switch in.target {
+	case notify.TargetGitHub, notify.TargetGitLab, notify.TargetBitbucket:
+		if in.repository == "" || in.number <= 0 {
+			return nil, fmt.Errorf("repository and number are required to notify %s", in.target)
+		}
+	case notify.TargetWebhook, notify.TargetSlack:
+		if in.webhookURL == "" {
+			return nil, fmt.Errorf("webhook-url is required to notify %s", in.target)
+		}
+	}
+
+	switch in.target {
+	case notify.TargetGitHub:
+		return &notify.GitHub{Client: in.client, BaseURL: in.apiURL, Token: in.token, Repository: in.repository, Number: in.number, Key: in.key}, nil
+	case notify.TargetGitLab:
+		return &notify.GitLab{Client: in.client, BaseURL: in.apiURL, Token: in.token, Repository: in.repository, Number: in.number, Key: in.key}, nil
+	case notify.TargetBitbucket:
+		return &notify.Bitbucket{Client: in.client, BaseURL: in.apiURL, Token: in.token, Repository: in.repository, Number: in.number, Key: in.key}, nil
+	case notify.TargetWebhook, notify.TargetSlack:
+		headers := map[string]string{}
+		if in.token != "" {
+			headers["Authorization"] = "Bearer " + in.token
+		}
+		return &notify.Webhook{Client: in.client, URL: in.webhookURL, Slack: in.target == notify.TargetSlack, Headers: headers}, nil
+	}
+	return nil, fmt.Errorf("unknown notify target %s", in.target)
+}
+
+func notifyPlan(ctx context.Context, in *notifyPlanInput) error {
+
+	var lines []string
+	msg := &notify.Message{}
+	switch {
+	case in.inspect != nil && in.compare != nil:
+		return fmt.Errorf("only one of inspect and compare can be notified")
+	case in.inspect != nil:
+		lines = in.inspect.Markdown()
+		msg.HasChanges = !in.inspect.IsEmpty()
+	case in.compare != nil:
+		lines = in.compare.Markdown()
+		msg.HasChanges = !in.compare.IsEmpty()
+	default:
+		return fmt.Errorf("inspect or compare output is required")
+	}
+	msg.Body = strings.Join(lines, "")
+
+	notifier, err := newNotifier(in)
+	if err != nil {
+		return err
+	}
+
+	if err := notifier.Notify(ctx, msg); err != nil {
+		return fmt.Errorf("failed to notify %s caused by: %w", in.target, err)
+	}
+	return nil
+}
+
+// notifyCmd represents the notify command
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Post inspect or compare results to a pull request or webhook",
+	Long: `
+Renders the output of inspect or compare (json format) as Markdown and posts it.
+
+For GitHub, GitLab and Bitbucket Cloud a single sticky comment is kept on the pull request or
+merge request: it is created on the first run and updated on every later run. Pass --key to keep
+several sticky comments, e.g. one per workspace. --repository is owner/name on GitHub, the
+project path or ID on GitLab and workspace/slug on Bitbucket. --number is the pull request
+number, or the merge request IID on GitLab. Use --api-url for GitHub Enterprise Server or a
+self-managed GitLab.
+
+For webhooks the message is posted as {"body": ..., "hasChanges": ...}, or as a Slack-compatible
+{"text": ...} payload with --target slack. A --token is sent as a bearer token.
+
+Example usage:
+$ tfplan notify \
+--inspect "$(tfplan inspect --plan "$(terraform show --json .plan)" --filter "$(cat filter.json)")" \
+--target github \
+--repository "$GITHUB_REPOSITORY" \
+--number "$PR_NUMBER" \
+--token "$GITHUB_TOKEN"
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		inspectFlg, err := cmd.Flags().GetString("inspect")
+		if err != nil {
+			return fmt.Errorf("failed to get inspect flag caused by: %w", err)
+		}
+
+		var inspect *tfplan.InspectOutput
+		if inspectFlg != "" {
+			inspect, err = tfplan.ParseInspectOutput([]byte(inspectFlg))
+			if err != nil {
+				return err
+			}
+		}
+
+		compareFlg, err := cmd.Flags().GetString("compare")
+		if err != nil {
+			return fmt.Errorf("failed to get compare flag caused by: %w", err)
+		}
+
+		var compare *tfplan.CompareInspectsOutput
+		if compareFlg != "" {
+			compare, err = tfplan.ParseCompareOutput([]byte(compareFlg))
+			if err != nil {
+				return err
+			}
+		}
+
+		targetFlg, err := cmd.Flags().GetString("target")
+		if err != nil {
+			return fmt.Errorf("failed to get target flag caused by: %w", err)
+		}
+
+		repositoryFlg, err := cmd.Flags().GetString("repository")
+		if err != nil {
+			return fmt.Errorf("failed to get repository flag caused by: %w", err)
+		}
+
+		numberFlg, err := cmd.Flags().GetInt("number")
+		if err != nil {
+			return fmt.Errorf("failed to get number flag caused by: %w", err)
+		}
+
+		tokenFlg, err := cmd.Flags().GetString("token")
+		if err != nil {
+			return fmt.Errorf("failed to get token flag caused by: %w", err)
+		}
+
+		apiUrlFlg, err := cmd.Flags().GetString("api-url")
+		if err != nil {
+			return fmt.Errorf("failed to get api-url flag caused by: %w", err)
+		}
+
+		webhookUrlFlg, err := cmd.Flags().GetString("webhook-url")
+		if err != nil {
+			return fmt.Errorf("failed to get webhook-url flag caused by: %w", err)
+		}
+
+		keyFlg, err := cmd.Flags().GetString("key")
+		if err != nil {
+			return fmt.Errorf("failed to get key flag caused by: %w", err)
+		}
+
+		timeoutFlg, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			return fmt.Errorf("failed to get timeout flag caused by: %w", err)
+		}
+
+		return notifyPlan(cmd.Context(), &notifyPlanInput{
+			inspect:    inspect,
+			compare:    compare,
+			target:     targetFlg,
+			repository: repositoryFlg,
+			number:     numberFlg,
+			token:      tokenFlg,
+			apiURL:     apiUrlFlg,
+			webhookURL: webhookUrlFlg,
+			key:        keyFlg,
+			client:     &http.Client{Timeout: timeoutFlg},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+	notifyCmd.PersistentFlags().StringP("inspect", "i", "", "inspect output (json format) to notify")
+	notifyCmd.PersistentFlags().StringP("compare", "c", "", "compare output (json format) to notify")
+	notifyCmd.PersistentFlags().StringP("target", "T", "", "where to notify (github, gitlab, bitbucket, webhook, slack)")
+	notifyCmd.PersistentFlags().StringP("repository", "r", "", "repository of the pull request (owner/name, group/project or workspace/slug)")
+	notifyCmd.PersistentFlags().IntP("number", "n", 0, "number of the pull request, or IID of the merge request")
+	notifyCmd.PersistentFlags().StringP("token", "t", "", "token to authenticate with")
+	notifyCmd.PersistentFlags().String("api-url", "", "base url of the REST API, defaults to the public API of the target")
+	notifyCmd.PersistentFlags().String("webhook-url", "", "url of the webhook")
+	notifyCmd.PersistentFlags().String("key", "", "key of the sticky comment, to keep several on a pull request")
+	notifyCmd.PersistentFlags().Duration("timeout", 30*time.Second, "timeout of each request")
+
+	// Required flags
+	notifyCmd.MarkPersistentFlagRequired("target")
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+
+	"github.com/spf13/cobra"
+)
+
+type policyPlanInput struct {
+	tfplan           *tfplan.Plan
+	policies         *tfplan.PolicySet
+	prettyPrint      bool
+	detailedExitCode bool
+}
+
+func policyPlan(ctx context.Context, in *policyPlanInput) error {
+
+	out, err := tfplan.EvaluatePolicies(ctx, in.tfplan, in.policies)
+	if err != nil {
+		return err
+	}
+
+	if in.prettyPrint {
+		for _, line := range out.Pretty() {
+			fmt.Print(line)
+		}
+	} else {
+		bytes, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("failed to json marshal policy output caused by: %w", err)
+		}
+		fmt.Println(string(bytes))
+	}
+
+	if !out.IsEmpty() && in.detailedExitCode {
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+// policyCmd represents the policy command
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Evaluate policies against a plan",
+	Long: `
+Evaluates CEL policies against each resource change in a JSON Terraform plan. Each policy
+is an expression that must evaluate to true for the resource to pass. Resources failing a
+policy are reported as a warning or failure depending on the policy severity.
+
+Policies are evaluated in-process. The variables address, resource (the resource change
+from the plan) and diff (the flattened changes keyed by path) are available to expressions.
+
+Example usage:
+$ tfplan policy \
+--plan "$(terraform show --json .plan)" \
+--policies "$(cat policies.json)" \
+--detailed-exitcode \
+--pretty
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		policiesFlg, err := cmd.Flags().GetString("policies")
+		if err != nil {
+			return fmt.Errorf("failed to get policies flag caused by: %w", err)
+		}
+
+		policies, err := tfplan.ParsePolicySet([]byte(policiesFlg))
+		if err != nil {
+			return err
+		}
+
+		detailedFlg, err := cmd.Flags().GetBool("detailed-exitcode")
+		if err != nil {
+			return fmt.Errorf("failed to get detailed-exitcode flag caused by: %w", err)
+		}
+
+		prettyFlg, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			return fmt.Errorf("failed to get pretty flag caused by: %w", err)
+		}
+
+		return policyPlan(cmd.Context(), &policyPlanInput{
+			tfplan:           parsed,
+			policies:         policies,
+			prettyPrint:      prettyFlg,
+			detailedExitCode: detailedFlg,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+	policyCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to evaluate")
+	policyCmd.PersistentFlags().StringP("policies", "l", "", "policies (json format) to evaluate against the plan")
+	policyCmd.PersistentFlags().BoolP("detailed-exitcode", "d", false, "when used, exit code 2 will return when there are policy failures")
+	policyCmd.PersistentFlags().BoolP("pretty", "P", false, "print the results in a human readable format")
+
+	// Required flags
+	policyCmd.MarkPersistentFlagRequired("plan")
+	policyCmd.MarkPersistentFlagRequired("policies")
+}
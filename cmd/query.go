@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+
+	"github.com/spf13/cobra"
+)
+
+type queryPlanInput struct {
+	tfplan           *tfplan.Plan
+	expression       string
+	filter           *tfplan.InspectFilter
+	set              map[string]string
+	format           string
+	detailedExitCode bool
+}
+
+func queryPlan(ctx context.Context, in *queryPlanInput) error {
+
+	out, err := tfplan.Query(ctx, in.tfplan, in.expression,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+	)
+	if err != nil {
+		return err
+	}
+
+	switch in.format {
+	case tfplan.QueryFormatText:
+		for _, line := range out.Text() {
+			fmt.Print(line)
+		}
+	case tfplan.QueryFormatMarkdown:
+		for _, line := range out.Markdown() {
+			fmt.Print(line)
+		}
+	case tfplan.QueryFormatJSON:
+		bytes, err := json.Marshal(out.Result)
+		if err != nil {
+			return fmt.Errorf("failed to json marshal query output caused by: %w", err)
+		}
+		fmt.Println(string(bytes))
+	default:
+		return fmt.Errorf("unknown query format %s", in.format)
+	}
+
+	if !out.IsEmpty() && in.detailedExitCode {
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Query a plan with a JMESPath expression",
+	Long: `
+Evaluates a JMESPath expression (https://jmespath.org) against a JSON Terraform plan. The
+expression is evaluated against an object holding the plan JSON as plan and the changes not
+filtered out by your provided filter criteria as changes. Each change has the fields kind
+(resource, drift or output), address, type, module, provider, actions, attributes (a list of
+path, before and after) and diff (the same attributes keyed by path). Sensitive values of the
+plan are replaced with "(sensitive value)" as in inspect.
+
+Example usage, listing every aws_security_group_rule opened to 0.0.0.0/0:
+$ tfplan query \
+--plan "$(terraform show --json .plan)" \
+--expression "changes[?type=='aws_security_group_rule' && attributes[?starts_with(path, '.cidr_blocks') && after=='0.0.0.0/0']].address" \
+--format text
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		expressionFlg, err := cmd.Flags().GetString("expression")
+		if err != nil {
+			return fmt.Errorf("failed to get expression flag caused by: %w", err)
+		}
+
+		filterFlg, err := cmd.Flags().GetString("filter")
+		if err != nil {
+			return fmt.Errorf("failed to get filter flag caused by: %w", err)
+		}
+
+		filter := &tfplan.InspectFilter{}
+		if filterFlg != "" && filterFlg != "{}" {
+			filter, err = tfplan.ParseFilter([]byte(filterFlg))
+			if err != nil {
+				return err
+			}
+		}
+
+		setFlg, err := cmd.Flags().GetStringToString("set")
+		if err != nil {
+			return fmt.Errorf("failed to get set flag caused by: %w", err)
+		}
+
+		formatFlg, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed to get format flag caused by: %w", err)
+		}
+
+		detailedFlg, err := cmd.Flags().GetBool("detailed-exitcode")
+		if err != nil {
+			return fmt.Errorf("failed to get detailed-exitcode flag caused by: %w", err)
+		}
+
+		return queryPlan(cmd.Context(), &queryPlanInput{
+			tfplan:           parsed,
+			expression:       expressionFlg,
+			filter:           filter,
+			set:              setFlg,
+			format:           formatFlg,
+			detailedExitCode: detailedFlg,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+	queryCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to query")
+	queryCmd.PersistentFlags().StringP("expression", "e", "", "JMESPath expression to evaluate against the plan and its changes")
+	queryCmd.PersistentFlags().StringP("filter", "f", "{}", "filter (json format) to filter out changes before querying")
+	queryCmd.PersistentFlags().StringToStringP("set", "s", map[string]string{}, "values (key=value) for ${set:key} placeholders in the filter")
+	queryCmd.PersistentFlags().StringP("format", "F", tfplan.QueryFormatJSON, "format of the result (json, text, markdown)")
+	queryCmd.PersistentFlags().BoolP("detailed-exitcode", "d", false, "when used, exit code 2 will return when the result is not empty, false or null")
+
+	// Required flags
+	queryCmd.MarkPersistentFlagRequired("plan")
+	queryCmd.MarkPersistentFlagRequired("expression")
+}
@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -11,15 +15,42 @@ var rootCmd = &cobra.Command{
 	Use:   "tfplan",
 	Short: "Parse and work with Terraform plans",
 	Long:  `A utility to parse Terraform plans and perform useful actions to automate your processes.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+
+		outputFlg, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return fmt.Errorf("failed to get output flag caused by: %w", err)
+		}
+
+		if outputFlg == outputJSON {
+			// Errors are printed by Execute instead
+			jsonErrors = true
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+		}
+		return nil
+	},
 }
 
 func Execute() {
-	err := rootCmd.Execute()
+	if outputJSONRequested(os.Args[1:]) {
+		jsonErrors = true
+		rootCmd.SilenceErrors = true
+		rootCmd.SilenceUsage = true
+	}
+
+	// Cancelled on interrupt so long running commands such as serve can stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
+		if jsonErrors {
+			printJSONError(err)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
-
+	rootCmd.PersistentFlags().String("output", "", "set to json to print errors as structured JSON")
 }
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/orange-car/tfplan/internal/server"
+
+	"github.com/spf13/cobra"
+)
+
+type servePlansInput struct {
+	address       string
+	maxBodyBytes  int64
+	timeout       time.Duration
+	maxConcurrent int
+}
+
+func servePlans(ctx context.Context, in *servePlansInput) error {
+
+	srv := &http.Server{
+		Addr:              in.address,
+		Handler:           server.New(&server.Options{MaxBodyBytes: in.maxBodyBytes, Timeout: in.timeout, MaxConcurrent: in.maxConcurrent}),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       in.timeout,
+		// Leave the handler timeout room to write its response
+		WriteTimeout: in.timeout + 5*time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		fmt.Printf("listening on %s\n", in.address)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("failed to serve caused by: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), in.timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down caused by: %w", err)
+	}
+	return nil
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve inspect and compare as a JSON API",
+	Long: `
+Serves tfplan over HTTP so other tools can inspect and compare plans without shelling out. Each
+endpoint takes a JSON body holding the plans and filter and returns the same JSON as the CLI.
+
+	POST /inspect      {"plan": {...}, "filter": {...}, "set": {...}}
+	POST /compare      {"planA": {...}, "planB": {...}, "filter": {...}, "set": {...}, "options": {...}}
+	POST /summary      {"plan": {...}, "filter": {...}, "set": {...}}
+	POST /filter/lint  {"filter": {...}}
+	GET  /healthz
+	GET  /metrics      Prometheus request counts and latencies
+
+Errors are returned as {"error": "..."}. Bodies larger than --max-body-bytes are rejected with 413
+and requests taking longer than --timeout with 503. At most --max-concurrent requests are handled
+at once, others wait for their turn within the timeout. The server shuts down gracefully on SIGINT
+or SIGTERM.
+
+Example usage:
+$ tfplan serve --address :8080 --timeout 30s
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		addressFlg, err := cmd.Flags().GetString("address")
+		if err != nil {
+			return fmt.Errorf("failed to get address flag caused by: %w", err)
+		}
+
+		maxBodyBytesFlg, err := cmd.Flags().GetInt64("max-body-bytes")
+		if err != nil {
+			return fmt.Errorf("failed to get max-body-bytes flag caused by: %w", err)
+		}
+
+		timeoutFlg, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			return fmt.Errorf("failed to get timeout flag caused by: %w", err)
+		}
+
+		maxConcurrentFlg, err := cmd.Flags().GetInt("max-concurrent")
+		if err != nil {
+			return fmt.Errorf("failed to get max-concurrent flag caused by: %w", err)
+		}
+
+		return servePlans(cmd.Context(), &servePlansInput{
+			address:       addressFlg,
+			maxBodyBytes:  maxBodyBytesFlg,
+			timeout:       timeoutFlg,
+			maxConcurrent: maxConcurrentFlg,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.PersistentFlags().StringP("address", "a", ":8080", "address to listen on")
+	serveCmd.PersistentFlags().Int64("max-body-bytes", server.DefaultMaxBodyBytes, "maximum size of a request body in bytes")
+	serveCmd.PersistentFlags().Duration("timeout", server.DefaultTimeout, "maximum time to handle a request")
+	serveCmd.PersistentFlags().Int("max-concurrent", 0, "maximum number of requests handled at once, defaults to the number of CPUs")
+}
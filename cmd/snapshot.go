@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+
+	"github.com/spf13/cobra"
+)
+
+type saveSnapshotInput struct {
+	tfplan   *tfplan.Plan
+	filter   *tfplan.InspectFilter
+	set      map[string]string
+	rewrites *tfplan.RewriteRules
+}
+
+func saveSnapshot(ctx context.Context, in *saveSnapshotInput) error {
+
+	out, err := tfplan.SaveSnapshot(ctx, in.tfplan,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+		tfplan.WithRewrites(in.rewrites),
+	)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("failed to json marshal snapshot caused by: %w", err)
+	}
+	fmt.Println(string(bytes))
+
+	return nil
+}
+
+type verifySnapshotInput struct {
+	tfplan      *tfplan.Plan
+	snapshot    *tfplan.Snapshot
+	prettyPrint bool
+}
+
+func verifySnapshot(ctx context.Context, in *verifySnapshotInput) error {
+
+	out, err := tfplan.VerifySnapshot(ctx, in.snapshot, in.tfplan)
+	if err != nil {
+		return err
+	}
+
+	if in.prettyPrint {
+		for _, line := range out.Pretty() {
+			fmt.Print(line)
+		}
+	} else {
+		bytes, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("failed to json marshal verify output caused by: %w", err)
+		}
+		fmt.Println(string(bytes))
+	}
+
+	if !out.IsEmpty() {
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and verify approved plan snapshots",
+	Long: `
+Saves an inspected plan as a snapshot and verifies later plans against it.
+
+A snapshot is the canonical JSON of the inspected and redacted plan along with the filter it
+was inspected with and a content hash. When a plan is approved, save a snapshot of it. At apply
+time, re-plan and verify the new plan against the snapshot so apply cannot drift away from what
+was reviewed.
+`,
+}
+
+// snapshotSaveCmd represents the snapshot save command
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save",
+	Short: "Save a snapshot of a plan",
+	Long: `
+Inspects a JSON Terraform plan with the optional filter, redacts it with the optional rewrite
+rules and prints a snapshot of it.
+
+Example usage:
+$ tfplan snapshot save \
+--plan "$(terraform show --json .plan)" \
+--filter "$(cat filter.json)" \
+--rewrite "$(cat redact.json)" > snapshot.json
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		filterFlg, err := cmd.Flags().GetString("filter")
+		if err != nil {
+			return fmt.Errorf("failed to get filter flag caused by: %w", err)
+		}
+
+		filter, err := tfplan.ParseFilter([]byte(filterFlg))
+		if err != nil {
+			return err
+		}
+
+		rewriteFlg, err := cmd.Flags().GetString("rewrite")
+		if err != nil {
+			return fmt.Errorf("failed to get rewrite flag caused by: %w", err)
+		}
+
+		rewrites, err := tfplan.ParseRewriteRules([]byte(rewriteFlg))
+		if err != nil {
+			return err
+		}
+
+		setFlg, err := cmd.Flags().GetStringToString("set")
+		if err != nil {
+			return fmt.Errorf("failed to get set flag caused by: %w", err)
+		}
+
+		return saveSnapshot(cmd.Context(), &saveSnapshotInput{
+			tfplan:   parsed,
+			filter:   filter,
+			set:      setFlg,
+			rewrites: rewrites,
+		})
+	},
+}
+
+// snapshotVerifyCmd represents the snapshot verify command
+var snapshotVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify a plan against a snapshot",
+	Long: `
+Inspects a JSON Terraform plan with the filter and rewrite rules of the snapshot and compares
+it with the snapshot. Differences are reported with plan A being the snapshot and plan B the
+plan. Exit code 2 is returned when the plan diverges from the snapshot.
+
+Example usage:
+$ tfplan snapshot verify \
+--plan "$(terraform show --json .plan)" \
+--snapshot "$(cat snapshot.json)" \
+--pretty
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		snapshotFlg, err := cmd.Flags().GetString("snapshot")
+		if err != nil {
+			return fmt.Errorf("failed to get snapshot flag caused by: %w", err)
+		}
+
+		snapshot, err := tfplan.ParseSnapshot([]byte(snapshotFlg))
+		if err != nil {
+			return err
+		}
+
+		prettyFlg, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			return fmt.Errorf("failed to get pretty flag caused by: %w", err)
+		}
+
+		return verifySnapshot(cmd.Context(), &verifySnapshotInput{
+			tfplan:      parsed,
+			snapshot:    snapshot,
+			prettyPrint: prettyFlg,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+	snapshotCmd.AddCommand(snapshotVerifyCmd)
+
+	snapshotSaveCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to snapshot")
+	snapshotSaveCmd.PersistentFlags().StringP("filter", "f", "{}", "filter (json format) to filter out changes")
+	snapshotSaveCmd.PersistentFlags().StringP("rewrite", "r", "{}", "rewrite rules (json format) redacting addresses and values of the plan")
+	snapshotSaveCmd.PersistentFlags().StringToStringP("set", "s", map[string]string{}, "values (key=value) for ${set:key} placeholders in the filter")
+
+	snapshotVerifyCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to verify")
+	snapshotVerifyCmd.PersistentFlags().StringP("snapshot", "S", "", "snapshot (json format) to verify the plan against")
+	snapshotVerifyCmd.PersistentFlags().BoolP("pretty", "P", false, "print the results in a human readable format")
+
+	// Required flags
+	snapshotSaveCmd.MarkPersistentFlagRequired("plan")
+	snapshotVerifyCmd.MarkPersistentFlagRequired("plan")
+	snapshotVerifyCmd.MarkPersistentFlagRequired("snapshot")
+}
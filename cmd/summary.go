@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+
+	"github.com/spf13/cobra"
+)
+
+type summaryPlanInput struct {
+	tfplan *tfplan.Plan
+	filter *tfplan.InspectFilter
+	set    map[string]string
+	format string
+}
+
+func summaryPlan(ctx context.Context, in *summaryPlanInput) error {
+
+	out, err := tfplan.Summary(ctx, in.tfplan,
+		tfplan.WithFilter(in.filter),
+		tfplan.WithSet(in.set),
+	)
+	if err != nil {
+		return err
+	}
+
+	switch in.format {
+	case tfplan.SummaryFormatTable:
+		for _, line := range out.Table() {
+			fmt.Print(line)
+		}
+	case tfplan.SummaryFormatMarkdown:
+		for _, line := range out.Markdown() {
+			fmt.Print(line)
+		}
+	case tfplan.SummaryFormatJSON:
+		bytes, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("failed to json marshal summary output caused by: %w", err)
+		}
+		fmt.Println(string(bytes))
+	default:
+		return fmt.Errorf("unknown summary format %s", in.format)
+	}
+
+	return nil
+}
+
+// summaryCmd represents the summary command
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Count the changes of a plan",
+	Long: `
+Counts the planned resource changes of a JSON Terraform plan by action, resource type, module
+and provider. Each count is reported for every change (all) and for the changes remaining
+after applying your provided filter criteria (remaining). Resources whose changes are all
+filtered out are not counted as remaining. Resources only moved to a new address are counted
+with the move action, resources without changes are counted as unchanged and data sources are
+not counted.
+
+The summary is printed as a table (default), JSON or Markdown, e.g. for a pull request comment.
+
+Example usage:
+$ tfplan summary \
+--plan "$(terraform show --json .plan)" \
+--filter "$(cat filter.json)" \
+--format markdown
+`,
+	PreRunE: nil,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		planFlg, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			return fmt.Errorf("failed to get plan flag caused by: %w", err)
+		}
+
+		parsed, err := tfplan.ParsePlan([]byte(planFlg))
+		if err != nil {
+			return err
+		}
+
+		filterFlg, err := cmd.Flags().GetString("filter")
+		if err != nil {
+			return fmt.Errorf("failed to get filter flag caused by: %w", err)
+		}
+
+		filter := &tfplan.InspectFilter{}
+		if filterFlg != "" && filterFlg != "{}" {
+			filter, err = tfplan.ParseFilter([]byte(filterFlg))
+			if err != nil {
+				return err
+			}
+		}
+
+		setFlg, err := cmd.Flags().GetStringToString("set")
+		if err != nil {
+			return fmt.Errorf("failed to get set flag caused by: %w", err)
+		}
+
+		formatFlg, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed to get format flag caused by: %w", err)
+		}
+
+		return summaryPlan(cmd.Context(), &summaryPlanInput{
+			tfplan: parsed,
+			filter: filter,
+			set:    setFlg,
+			format: formatFlg,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(summaryCmd)
+	summaryCmd.PersistentFlags().StringP("plan", "p", "", "plan (json format) to summarise")
+	summaryCmd.PersistentFlags().StringP("filter", "f", "{}", "filter (json format) to filter out changes for the filtered counts")
+	summaryCmd.PersistentFlags().StringToStringP("set", "s", map[string]string{}, "values (key=value) for ${set:key} placeholders in the filter")
+	summaryCmd.PersistentFlags().StringP("format", "F", tfplan.SummaryFormatTable, "format of the summary (table, json, markdown)")
+
+	// Required flags
+	summaryCmd.MarkPersistentFlagRequired("plan")
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The default base URL of the Bitbucket Cloud REST API.
+const BitbucketAPIURL = "https://api.bitbucket.org/2.0"
+
+// Keeps a sticky comment on a Bitbucket Cloud pull request up to date.
+type Bitbucket struct {
+	// Sends the requests.
+	Client Doer
+	// Base URL of the REST API. Defaults to BitbucketAPIURL.
+	BaseURL string
+	// Access token with permission to write pull request comments.
+	Token string
+	// The repository as workspace/slug.
+	Repository string
+	// The ID of the pull request.
+	Number int
+	// Optional key to keep several sticky comments on the pull request.
+	Key string
+}
+
+type bitbucketContent struct {
+	Raw string `json:"raw"`
+}
+
+type bitbucketComment struct {
+	ID      int64            `json:"id,omitempty"`
+	Content bitbucketContent `json:"content"`
+}
+
+type bitbucketComments struct {
+	Values []bitbucketComment `json:"values"`
+	Next   string             `json:"next"`
+}
+
+func (b *Bitbucket) url(path string) string {
+	base := b.BaseURL
+	if base == "" {
+		base = BitbucketAPIURL
+	}
+	return fmt.Sprintf("%s/repositories/%s/pullrequests/%v/comments%s", strings.TrimSuffix(base, "/"), b.Repository, b.Number, path)
+}
+
+func (b *Bitbucket) headers() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + b.Token}
+}
+
+func (b *Bitbucket) list(ctx context.Context) ([]comment, error) {
+	out := []comment{}
+	url := b.url("?pagelen=100")
+	for url != "" {
+		page := &bitbucketComments{}
+		if err := doJSON(ctx, b.Client, http.MethodGet, url, b.headers(), nil, page); err != nil {
+			return nil, err
+		}
+		for _, c := range page.Values {
+			out = append(out, comment{id: fmt.Sprintf("%v", c.ID), body: c.Content.Raw})
+		}
+		url = page.Next
+	}
+	return out, nil
+}
+
+func (b *Bitbucket) create(ctx context.Context, body string) error {
+	return doJSON(ctx, b.Client, http.MethodPost, b.url(""), b.headers(), &bitbucketComment{Content: bitbucketContent{Raw: body}}, nil)
+}
+
+func (b *Bitbucket) update(ctx context.Context, id, body string) error {
+	return doJSON(ctx, b.Client, http.MethodPut, b.url("/"+id), b.headers(), &bitbucketComment{Content: bitbucketContent{Raw: body}}, nil)
+}
+
+/*
+Creates or updates the sticky comment of the pull request.
+*/
+func (b *Bitbucket) Notify(ctx context.Context, msg *Message) error {
+	return upsertSticky(ctx, b, b.Key, msg)
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Bitbucket(t *testing.T) {
+	responses := map[string]string{
+		"GET /repositories/team/infra/pullrequests/5/comments?pagelen=100&page=2": `{"values": [{"id": 2, "content": {"raw": "<!-- tfplan:prod -->\nold report"}}]}`,
+		"PUT /repositories/team/infra/pullrequests/5/comments/2":                  `{"id": 2}`,
+	}
+	server, received := standIn(t, "Authorization", responses)
+	// The next page is linked by its absolute URL
+	responses["GET /repositories/team/infra/pullrequests/5/comments?pagelen=100"] = `{"values": [{"id": 1, "content": {"raw": "LGTM"}}], "next": "` + server.URL + `/repositories/team/infra/pullrequests/5/comments?pagelen=100&page=2"}`
+
+	b := &Bitbucket{Client: server.Client(), BaseURL: server.URL, Token: "secret", Repository: "team/infra", Number: 5, Key: "prod"}
+	assert.Nil(t, b.Notify(context.Background(), &Message{Body: "report"}))
+
+	diff.Check(t, []request{
+		{Method: "GET", URI: "/repositories/team/infra/pullrequests/5/comments?pagelen=100", Header: "Bearer secret"},
+		{Method: "GET", URI: "/repositories/team/infra/pullrequests/5/comments?pagelen=100&page=2", Header: "Bearer secret"},
+		{Method: "PUT", URI: "/repositories/team/infra/pullrequests/5/comments/2", Header: "Bearer secret", Body: `{"content":{"raw":"\u003c!-- tfplan:prod --\u003e\nreport"}}`},
+	}, received())
+
+	t.Run("create", func(t *testing.T) {
+		server, received := standIn(t, "Authorization", map[string]string{
+			"GET /repositories/team/infra/pullrequests/6/comments?pagelen=100": `{"values": []}`,
+			"POST /repositories/team/infra/pullrequests/6/comments":            `{"id": 1}`,
+		})
+
+		b := &Bitbucket{Client: server.Client(), BaseURL: server.URL, Token: "secret", Repository: "team/infra", Number: 6}
+		assert.Nil(t, b.Notify(context.Background(), &Message{Body: "report"}))
+
+		diff.Check(t, request{Method: "POST", URI: "/repositories/team/infra/pullrequests/6/comments", Header: "Bearer secret", Body: `{"content":{"raw":"\u003c!-- tfplan --\u003e\nreport"}}`}, received()[1])
+	})
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The default base URL of the GitHub REST API.
+const GitHubAPIURL = "https://api.github.com"
+
+// Keeps a sticky comment on a GitHub pull request up to date.
+type GitHub struct {
+	// Sends the requests.
+	Client Doer
+	// Base URL of the REST API, e.g. https://github.example.com/api/v3 for
+	// GitHub Enterprise Server. Defaults to GitHubAPIURL.
+	BaseURL string
+	// Token with permission to write pull request comments.
+	Token string
+	// The repository as owner/name.
+	Repository string
+	// The number of the pull request.
+	Number int
+	// Optional key to keep several sticky comments on the pull request.
+	Key string
+}
+
+type githubComment struct {
+	ID   int64  `json:"id,omitempty"`
+	Body string `json:"body"`
+}
+
+func (g *GitHub) url(path string) string {
+	base := g.BaseURL
+	if base == "" {
+		base = GitHubAPIURL
+	}
+	return strings.TrimSuffix(base, "/") + "/repos/" + g.Repository + path
+}
+
+func (g *GitHub) headers() map[string]string {
+	return map[string]string{
+		"Accept":               "application/vnd.github+json",
+		"Authorization":        "Bearer " + g.Token,
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+}
+
+func (g *GitHub) list(ctx context.Context) ([]comment, error) {
+	out := []comment{}
+	for page := 1; ; page++ {
+		comments := []githubComment{}
+		url := g.url(fmt.Sprintf("/issues/%v/comments?per_page=100&page=%v", g.Number, page))
+		if err := doJSON(ctx, g.Client, http.MethodGet, url, g.headers(), nil, &comments); err != nil {
+			return nil, err
+		}
+		for _, c := range comments {
+			out = append(out, comment{id: fmt.Sprintf("%v", c.ID), body: c.Body})
+		}
+		if len(comments) < 100 {
+			return out, nil
+		}
+	}
+}
+
+func (g *GitHub) create(ctx context.Context, body string) error {
+	url := g.url(fmt.Sprintf("/issues/%v/comments", g.Number))
+	return doJSON(ctx, g.Client, http.MethodPost, url, g.headers(), &githubComment{Body: body}, nil)
+}
+
+func (g *GitHub) update(ctx context.Context, id, body string) error {
+	url := g.url("/issues/comments/" + id)
+	return doJSON(ctx, g.Client, http.MethodPatch, url, g.headers(), &githubComment{Body: body}, nil)
+}
+
+/*
+Creates or updates the sticky comment of the pull request.
+*/
+func (g *GitHub) Notify(ctx context.Context, msg *Message) error {
+	return upsertSticky(ctx, g, g.Key, msg)
+}
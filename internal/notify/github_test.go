@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GitHub(t *testing.T) {
+	// A full first page of someone else's comments then the sticky comment
+	page1 := []string{}
+	for i := 0; i < 100; i++ {
+		page1 = append(page1, fmt.Sprintf(`{"id": %v, "body": "LGTM"}`, i))
+	}
+
+	server, received := standIn(t, "Authorization", map[string]string{
+		"GET /repos/orange-car/infra/issues/7/comments?per_page=100&page=1": "[" + strings.Join(page1, ",") + "]",
+		"GET /repos/orange-car/infra/issues/7/comments?per_page=100&page=2": `[{"id": 1234, "body": "<!-- tfplan -->\nold report"}]`,
+		"PATCH /repos/orange-car/infra/issues/comments/1234":                `{"id": 1234}`,
+	})
+
+	g := &GitHub{Client: server.Client(), BaseURL: server.URL, Token: "secret", Repository: "orange-car/infra", Number: 7}
+	assert.Nil(t, g.Notify(context.Background(), &Message{Body: "report"}))
+
+	diff.Check(t, []request{
+		{Method: "GET", URI: "/repos/orange-car/infra/issues/7/comments?per_page=100&page=1", Header: "Bearer secret"},
+		{Method: "GET", URI: "/repos/orange-car/infra/issues/7/comments?per_page=100&page=2", Header: "Bearer secret"},
+		{Method: "PATCH", URI: "/repos/orange-car/infra/issues/comments/1234", Header: "Bearer secret", Body: `{"body":"\u003c!-- tfplan --\u003e\nreport"}`},
+	}, received())
+
+	t.Run("create", func(t *testing.T) {
+		server, received := standIn(t, "Authorization", map[string]string{
+			"GET /repos/orange-car/infra/issues/8/comments?per_page=100&page=1": `[]`,
+			"POST /repos/orange-car/infra/issues/8/comments":                    `{"id": 1}`,
+		})
+
+		g := &GitHub{Client: server.Client(), BaseURL: server.URL, Token: "secret", Repository: "orange-car/infra", Number: 8, Key: "prod"}
+		assert.Nil(t, g.Notify(context.Background(), &Message{Body: "report"}))
+
+		diff.Check(t, request{Method: "POST", URI: "/repos/orange-car/infra/issues/8/comments", Header: "Bearer secret", Body: `{"body":"\u003c!-- tfplan:prod --\u003e\nreport"}`}, received()[1])
+	})
+
+	t.Run("error", func(t *testing.T) {
+		server, _ := standIn(t, "", map[string]string{})
+
+		g := &GitHub{Client: server.Client(), BaseURL: server.URL, Repository: "orange-car/infra", Number: 9}
+		err := g.Notify(context.Background(), &Message{Body: "report"})
+		assert.ErrorContains(t, err, "unable to list comments caused by: unexpected status 404")
+	})
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The default base URL of the GitLab REST API.
+const GitLabAPIURL = "https://gitlab.com/api/v4"
+
+// Keeps a sticky note on a GitLab merge request up to date.
+type GitLab struct {
+	// Sends the requests.
+	Client Doer
+	// Base URL of the REST API, e.g. https://gitlab.example.com/api/v4 for
+	// a self-managed instance. Defaults to GitLabAPIURL.
+	BaseURL string
+	// Token with the api scope, e.g. a project access token.
+	Token string
+	// The project as its path, e.g. group/project, or ID.
+	Repository string
+	// The IID of the merge request.
+	Number int
+	// Optional key to keep several sticky notes on the merge request.
+	Key string
+}
+
+type gitlabNote struct {
+	ID   int64  `json:"id,omitempty"`
+	Body string `json:"body"`
+}
+
+func (g *GitLab) url(path string) string {
+	base := g.BaseURL
+	if base == "" {
+		base = GitLabAPIURL
+	}
+	return fmt.Sprintf("%s/projects/%s/merge_requests/%v/notes%s", strings.TrimSuffix(base, "/"), url.PathEscape(g.Repository), g.Number, path)
+}
+
+func (g *GitLab) headers() map[string]string {
+	return map[string]string{"PRIVATE-TOKEN": g.Token}
+}
+
+func (g *GitLab) list(ctx context.Context) ([]comment, error) {
+	out := []comment{}
+	for page := 1; ; page++ {
+		notes := []gitlabNote{}
+		if err := doJSON(ctx, g.Client, http.MethodGet, g.url(fmt.Sprintf("?per_page=100&page=%v", page)), g.headers(), nil, &notes); err != nil {
+			return nil, err
+		}
+		for _, n := range notes {
+			out = append(out, comment{id: fmt.Sprintf("%v", n.ID), body: n.Body})
+		}
+		if len(notes) < 100 {
+			return out, nil
+		}
+	}
+}
+
+func (g *GitLab) create(ctx context.Context, body string) error {
+	return doJSON(ctx, g.Client, http.MethodPost, g.url(""), g.headers(), &gitlabNote{Body: body}, nil)
+}
+
+func (g *GitLab) update(ctx context.Context, id, body string) error {
+	return doJSON(ctx, g.Client, http.MethodPut, g.url("/"+id), g.headers(), &gitlabNote{Body: body}, nil)
+}
+
+/*
+Creates or updates the sticky note of the merge request.
+*/
+func (g *GitLab) Notify(ctx context.Context, msg *Message) error {
+	return upsertSticky(ctx, g, g.Key, msg)
+}
@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GitLab(t *testing.T) {
+	server, received := standIn(t, "PRIVATE-TOKEN", map[string]string{
+		"GET /projects/group%2Finfra/merge_requests/3/notes?per_page=100&page=1": `[{"id": 1, "body": "LGTM"}, {"id": 2, "body": "<!-- tfplan -->\nold report"}]`,
+		"PUT /projects/group%2Finfra/merge_requests/3/notes/2":                   `{"id": 2}`,
+	})
+
+	g := &GitLab{Client: server.Client(), BaseURL: server.URL, Token: "secret", Repository: "group/infra", Number: 3}
+	assert.Nil(t, g.Notify(context.Background(), &Message{Body: "report"}))
+
+	diff.Check(t, []request{
+		{Method: "GET", URI: "/projects/group%2Finfra/merge_requests/3/notes?per_page=100&page=1", Header: "secret"},
+		{Method: "PUT", URI: "/projects/group%2Finfra/merge_requests/3/notes/2", Header: "secret", Body: `{"body":"\u003c!-- tfplan --\u003e\nreport"}`},
+	}, received())
+
+	t.Run("create", func(t *testing.T) {
+		server, received := standIn(t, "PRIVATE-TOKEN", map[string]string{
+			"GET /projects/group%2Finfra/merge_requests/4/notes?per_page=100&page=1": `[]`,
+			"POST /projects/group%2Finfra/merge_requests/4/notes":                    `{"id": 1}`,
+		})
+
+		g := &GitLab{Client: server.Client(), BaseURL: server.URL, Token: "secret", Repository: "group/infra", Number: 4}
+		assert.Nil(t, g.Notify(context.Background(), &Message{Body: "report"}))
+
+		diff.Check(t, request{Method: "POST", URI: "/projects/group%2Finfra/merge_requests/4/notes", Header: "secret", Body: `{"body":"\u003c!-- tfplan --\u003e\nreport"}`}, received()[1])
+	})
+}
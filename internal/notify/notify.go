@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// Create or update a sticky comment on a GitHub pull request.
+	TargetGitHub = "github"
+	// Create or update a sticky note on a GitLab merge request.
+	TargetGitLab = "gitlab"
+	// Create or update a sticky comment on a Bitbucket Cloud pull request.
+	TargetBitbucket = "bitbucket"
+	// Post a generic JSON payload to a webhook.
+	TargetWebhook = "webhook"
+	// Post a Slack-compatible JSON payload to a webhook.
+	TargetSlack = "slack"
+)
+
+// Sends HTTP requests. Satisfied by *http.Client so tests can swap in a
+// client pointing at a stand-in server.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// A message to notify about, e.g. an inspect rendered as Markdown.
+type Message struct {
+	// The Markdown body of the message.
+	Body string `json:"body"`
+	// Whether the message reports changes needing attention.
+	HasChanges bool `json:"hasChanges"`
+}
+
+// Delivers a Message to a pull request, merge request or webhook.
+type Notifier interface {
+	Notify(ctx context.Context, msg *Message) error
+}
+
+// A comment of a pull request or merge request.
+type comment struct {
+	id   string
+	body string
+}
+
+// The comments API of a pull request or merge request.
+type commentAPI interface {
+	list(ctx context.Context) ([]comment, error)
+	create(ctx context.Context, body string) error
+	update(ctx context.Context, id, body string) error
+}
+
+/*
+Produces the hidden marker identifying the sticky comment of the key, so
+several comments, e.g. one per workspace, can be kept on a pull request.
+*/
+func marker(key string) string {
+	if key == "" {
+		return "<!-- tfplan -->"
+	}
+	return fmt.Sprintf("<!-- tfplan:%s -->", key)
+}
+
+/*
+Updates the comment holding the marker of the key or creates it when there
+is none.
+*/
+func upsertSticky(ctx context.Context, api commentAPI, key string, msg *Message) error {
+	body := marker(key) + "\n" + msg.Body
+
+	comments, err := api.list(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list comments caused by: %w", err)
+	}
+
+	for _, c := range comments {
+		if strings.HasPrefix(c.body, marker(key)+"\n") {
+			if err := api.update(ctx, c.id, body); err != nil {
+				return fmt.Errorf("unable to update comment %s caused by: %w", c.id, err)
+			}
+			return nil
+		}
+	}
+
+	if err := api.create(ctx, body); err != nil {
+		return fmt.Errorf("unable to create comment caused by: %w", err)
+	}
+	return nil
+}
+
+/*
+Sends a request with an optional JSON body and decodes an optional JSON
+response. Errors on any non-2xx status.
+*/
+func doJSON(ctx context.Context, client Doer, method, rawURL string, headers map[string]string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("unable to marshal request caused by: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	// URLs such as webhooks carry secrets in their path and query so only
+	// the host is put in errors
+	host := redactURL(rawURL)
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return fmt.Errorf("unable to create request to %s caused by: %w", host, unwrapURLError(err))
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send %s %s caused by: %w", method, host, unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("unable to read response of %s %s caused by: %w", method, host, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %v from %s %s: %s", resp.StatusCode, method, host, strings.TrimSpace(string(data)))
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("unable to unmarshal response of %s %s caused by: %w", method, host, err)
+		}
+	}
+	return nil
+}
+
+/*
+Reduces the URL to its scheme and host, leaving out the path, query and
+user info which may hold secrets.
+*/
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "(invalid url)"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+/*
+Returns the cause of a *url.Error, whose message holds the full URL.
+*/
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+// A request received by the stand-in server.
+type request struct {
+	Method string
+	URI    string
+	Header string
+	Body   string
+}
+
+/*
+Starts a stand-in server responding to "METHOD uri" with the scripted
+status and body, recording every request. Unscripted requests get a 404.
+The header named is recorded with each request.
+*/
+func standIn(t *testing.T, header string, responses map[string]string) (*httptest.Server, func() []request) {
+	var mu sync.Mutex
+	received := []request{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, request{Method: r.Method, URI: r.URL.RequestURI(), Header: r.Header.Get(header), Body: string(body)})
+		mu.Unlock()
+
+		response, ok := responses[r.Method+" "+r.URL.RequestURI()]
+		if !ok {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]request{}, received...)
+	}
+}
+
+// An in-memory comments API.
+type fakeAPI struct {
+	comments []comment
+	created  []string
+	updated  map[string]string
+}
+
+func (f *fakeAPI) list(ctx context.Context) ([]comment, error) {
+	return f.comments, nil
+}
+
+func (f *fakeAPI) create(ctx context.Context, body string) error {
+	f.created = append(f.created, body)
+	return nil
+}
+
+func (f *fakeAPI) update(ctx context.Context, id, body string) error {
+	f.updated[id] = body
+	return nil
+}
+
+func Test_upsertSticky(t *testing.T) {
+	cases := map[string]struct {
+		key             string
+		comments        []comment
+		expectedCreated []string
+		expectedUpdated map[string]string
+	}{
+		"create": {
+			comments:        []comment{{id: "1", body: "LGTM"}},
+			expectedCreated: []string{"<!-- tfplan -->\nreport"},
+			expectedUpdated: map[string]string{},
+		},
+		"update": {
+			comments:        []comment{{id: "1", body: "LGTM"}, {id: "2", body: "<!-- tfplan -->\nold report"}},
+			expectedUpdated: map[string]string{"2": "<!-- tfplan -->\nreport"},
+		},
+		"create with another key": {
+			key:             "prod",
+			comments:        []comment{{id: "2", body: "<!-- tfplan -->\nold report"}},
+			expectedCreated: []string{"<!-- tfplan:prod -->\nreport"},
+			expectedUpdated: map[string]string{},
+		},
+		"update with key": {
+			key:             "prod",
+			comments:        []comment{{id: "2", body: "<!-- tfplan -->\nold report"}, {id: "3", body: "<!-- tfplan:prod -->\nold report"}},
+			expectedUpdated: map[string]string{"3": "<!-- tfplan:prod -->\nreport"},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			api := &fakeAPI{comments: tst.comments, updated: map[string]string{}}
+
+			assert.Nil(t, upsertSticky(context.Background(), api, tst.key, &Message{Body: "report"}))
+			diff.Check(t, tst.expectedCreated, api.created)
+			diff.Check(t, tst.expectedUpdated, api.updated)
+		})
+	}
+}
+
+func Test_doJSON(t *testing.T) {
+	server, _ := standIn(t, "", map[string]string{"GET /ok": `{"id": 1}`})
+
+	out := map[string]int{}
+	assert.Nil(t, doJSON(context.Background(), server.Client(), http.MethodGet, server.URL+"/ok", nil, nil, &out))
+	diff.Check(t, map[string]int{"id": 1}, out)
+
+	err := doJSON(context.Background(), server.Client(), http.MethodGet, server.URL+"/missing?token=secret", nil, nil, nil)
+	assert.EqualError(t, err, "unexpected status 404 from GET "+server.URL+`: {"message": "Not Found"}`)
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	err = doJSON(context.Background(), closed.Client(), http.MethodPost, closed.URL+"/services/T000/B000/secret", nil, nil, nil)
+	assert.NotContains(t, err.Error(), "secret")
+	assert.Contains(t, err.Error(), "unable to send POST "+closed.URL+" caused by: ")
+
+	err = doJSON(context.Background(), server.Client(), http.MethodPost, "http://[::1/secret", nil, nil, nil)
+	assert.NotContains(t, err.Error(), "secret")
+}
@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// Posts a message to a webhook.
+type Webhook struct {
+	// Sends the requests.
+	Client Doer
+	// The URL of the webhook.
+	URL string
+	// Post a Slack-compatible {"text": ...} payload instead of the Message.
+	Slack bool
+	// Optional headers of the request, e.g. Authorization.
+	Headers map[string]string
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+/*
+Posts the message as JSON to the webhook.
+*/
+func (w *Webhook) Notify(ctx context.Context, msg *Message) error {
+	var payload any = msg
+	if w.Slack {
+		payload = &slackPayload{Text: msg.Body}
+	}
+	return doJSON(ctx, w.Client, http.MethodPost, w.URL, w.Headers, payload, nil)
+}
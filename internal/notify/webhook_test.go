@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Webhook(t *testing.T) {
+	cases := map[string]struct {
+		slack           bool
+		expectedRequest request
+	}{
+		"generic": {
+			expectedRequest: request{Method: "POST", URI: "/hook", Header: "token", Body: `{"body":"report","hasChanges":true}`},
+		},
+		"slack": {
+			slack:           true,
+			expectedRequest: request{Method: "POST", URI: "/hook", Header: "token", Body: `{"text":"report"}`},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			server, received := standIn(t, "X-Token", map[string]string{"POST /hook": ""})
+
+			w := &Webhook{Client: server.Client(), URL: server.URL + "/hook", Slack: tst.slack, Headers: map[string]string{"X-Token": "token"}}
+			assert.Nil(t, w.Notify(context.Background(), &Message{Body: "report", HasChanges: true}))
+			diff.Check(t, []request{tst.expectedRequest}, received())
+		})
+	}
+}
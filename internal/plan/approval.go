@@ -0,0 +1,270 @@
+package plan
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tfJson "github.com/hashicorp/terraform-json"
+)
+
+// The version of the approval format.
+const ApprovalVersion = 3
+
+// The raw planned change of a resource or output covered by the digest.
+type approvalChange struct {
+	Actions         tfJson.Actions `json:"actions"`
+	Before          any            `json:"before"`
+	After           any            `json:"after"`
+	AfterUnknown    any            `json:"after_unknown"`
+	BeforeSensitive any            `json:"before_sensitive"`
+	AfterSensitive  any            `json:"after_sensitive"`
+}
+
+// The content of a plan covered by its approval digest.
+type approvalDigestContent struct {
+	// The version of the approval format.
+	Version int `json:"version"`
+	// The filter the plan was inspected with.
+	Filter *InspectFilter `json:"filter"`
+	// Values for ${set:name} placeholders in the filter.
+	Set map[string]string `json:"set"`
+	// Every planned resource change keyed by address, filtered or not.
+	ResourceChanges map[string]approvalChange `json:"resourceChanges"`
+	// Every planned output change keyed by name.
+	OutputChanges map[string]approvalChange `json:"outputChanges"`
+}
+
+func newApprovalChange(change *tfJson.Change) approvalChange {
+	return approvalChange{
+		Actions:         change.Actions,
+		Before:          change.Before,
+		After:           change.After,
+		AfterUnknown:    change.AfterUnknown,
+		BeforeSensitive: change.BeforeSensitive,
+		AfterSensitive:  change.AfterSensitive,
+	}
+}
+
+// The fields of an Approval covered by its signature.
+type ApprovalPayload struct {
+	// The version of the approval format.
+	Version int `json:"version"`
+	// The sha256 digest of the approved plan and filter.
+	Digest string `json:"digest"`
+	// The filter the plan was inspected with.
+	Filter *InspectFilter `json:"filter"`
+	// Values for ${set:name} placeholders in the filter.
+	Set map[string]string `json:"set"`
+	// The name of the approver.
+	Signer string `json:"signer"`
+	// The base64 ed25519 public key of the approver.
+	PublicKey string `json:"publicKey"`
+	// When the plan was approved in RFC 3339 format.
+	ApprovedAt string `json:"approvedAt"`
+}
+
+// A signed record of a human approving an inspected plan.
+type Approval struct {
+	ApprovalPayload
+	// The base64 ed25519 signature of the canonical JSON of the payload.
+	Signature string `json:"signature"`
+
+	// The canonical JSON of the payload as it was stored, set when the
+	// approval is unmarshalled.
+	stored []byte
+}
+
+/*
+Unmarshals the approval keeping the canonical JSON of its payload as
+stored, which the signature is verified against.
+*/
+func (a *Approval) UnmarshalJSON(data []byte) error {
+	type approval Approval
+	if err := json.Unmarshal(data, (*approval)(a)); err != nil {
+		return err
+	}
+
+	stored, err := canonicalObject(data, "signature")
+	if err != nil {
+		return err
+	}
+	a.stored = stored
+	return nil
+}
+
+// A signer whose approvals are accepted.
+type AllowedSigner struct {
+	// The name of the signer.
+	Name string `json:"name"`
+	// The base64 ed25519 public key of the signer.
+	PublicKey string `json:"publicKey"`
+}
+
+// The signers whose approvals are accepted.
+type AllowedSigners struct {
+	Signers []AllowedSigner `json:"signers"`
+}
+
+type ApproveInput struct {
+	// Optional filter the plan was inspected with.
+	Filter *InspectFilter
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string
+	// The name of the approver.
+	Signer string
+	// The ed25519 private key of the approver.
+	Key ed25519.PrivateKey
+	// When the plan was approved.
+	Now time.Time
+}
+
+/*
+Computes the canonical digest of the raw planned resource and output
+changes and the filter the plan was inspected with. The raw values are used
+rather than the inspected diff so changes to sensitive or unknown values
+change the digest. Maps are marshalled with sorted keys so the digest is
+stable.
+*/
+func (p *Plan) approvalDigest(filter *InspectFilter, set map[string]string) (string, error) {
+	content := &approvalDigestContent{
+		Version:         ApprovalVersion,
+		Filter:          filter,
+		Set:             set,
+		ResourceChanges: map[string]approvalChange{},
+		OutputChanges:   map[string]approvalChange{},
+	}
+	for _, rChange := range p.ResourceChanges {
+		if rChange.Change == nil {
+			continue
+		}
+		if _, ok := content.ResourceChanges[rChange.Address]; ok {
+			return "", fmt.Errorf("resource %s is planned more than once", rChange.Address)
+		}
+		content.ResourceChanges[rChange.Address] = newApprovalChange(rChange.Change)
+	}
+	for name, oChange := range p.OutputChanges {
+		if oChange == nil {
+			continue
+		}
+		content.OutputChanges[name] = newApprovalChange(oChange)
+	}
+
+	bytes, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal approval digest content caused by: %w", err)
+	}
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+/*
+Produces the canonical JSON of the payload, with sorted keys as it is
+canonicalised when stored.
+*/
+func (a *ApprovalPayload) bytes() ([]byte, error) {
+	bytes, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal approval payload caused by: %w", err)
+	}
+	return canonicalObject(bytes, "")
+}
+
+/*
+Approves the plan inspected with the filter producing an approval signed
+with the key.
+*/
+func (p *Plan) Approve(params *ApproveInput) (*Approval, error) {
+	if len(params.Key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("approval key must be an ed25519 private key")
+	}
+	if params.Signer == "" {
+		return nil, fmt.Errorf("approval signer cannot be empty")
+	}
+
+	digest, err := p.approvalDigest(params.Filter, params.Set)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Approval{
+		ApprovalPayload: ApprovalPayload{
+			Version:    ApprovalVersion,
+			Digest:     digest,
+			Filter:     params.Filter,
+			Set:        params.Set,
+			Signer:     params.Signer,
+			PublicKey:  base64.StdEncoding.EncodeToString(params.Key.Public().(ed25519.PublicKey)),
+			ApprovedAt: params.Now.UTC().Format(time.RFC3339),
+		},
+	}
+
+	payload, err := a.ApprovalPayload.bytes()
+	if err != nil {
+		return nil, err
+	}
+	a.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(params.Key, payload))
+	return a, nil
+}
+
+/*
+Verifies the approval is signed by one of the allowed signers and that the
+plan matches the approved digest when inspected with the approved filter.
+*/
+func (a *Approval) Verify(p *Plan, allowed *AllowedSigners) error {
+	// Approvals read back are verified as stored, which the signature
+	// covers, rather than as they unmarshalled
+	payload := a.stored
+	if payload == nil {
+		var err error
+		if payload, err = a.ApprovalPayload.bytes(); err != nil {
+			return err
+		}
+	}
+	signed := &ApprovalPayload{}
+	if err := json.Unmarshal(payload, signed); err != nil {
+		return fmt.Errorf("unable to unmarshal approval payload caused by: %w", err)
+	}
+
+	if signed.Version != ApprovalVersion {
+		return fmt.Errorf("unsupported approval version %v", signed.Version)
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(signed.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("approval public key is not a base64 ed25519 public key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return fmt.Errorf("approval signature is not base64 caused by: %w", err)
+	}
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return fmt.Errorf("approval signature of %s is invalid", signed.Signer)
+	}
+
+	isAllowed := false
+	if allowed != nil {
+		for _, s := range allowed.Signers {
+			if s.Name == signed.Signer && s.PublicKey == signed.PublicKey {
+				isAllowed = true
+				break
+			}
+		}
+	}
+	if !isAllowed {
+		return fmt.Errorf("signer %s is not allowed", signed.Signer)
+	}
+
+	digest, err := p.approvalDigest(signed.Filter, signed.Set)
+	if err != nil {
+		return err
+	}
+	if digest != signed.Digest {
+		return fmt.Errorf("plan digest %s does not match approved digest %s", digest, signed.Digest)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package plan
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Approval(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	publicKey := base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	otherKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, ed25519.SeedSize))
+
+	filter := &InspectFilter{
+		ResourceChanges: []Filter{
+			{
+				NamePattern:  Patterns{"aws_lambda_function.*"},
+				DiffPatterns: map[string][]DiffPattern{".source_code_hash": {{Before: Patterns{"*"}, After: Patterns{"*"}}}},
+			},
+		},
+	}
+	allowed := &AllowedSigners{Signers: []AllowedSigner{{Name: "alice", PublicKey: publicKey}}}
+
+	p := &Plan{}
+	assert.Nil(t, json.Unmarshal([]byte(ciPlan), p))
+
+	approve := func(t *testing.T, key ed25519.PrivateKey) *Approval {
+		a, err := p.Approve(&ApproveInput{Filter: filter, Signer: "alice", Key: key, Now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)})
+		assert.Nil(t, err)
+		return a
+	}
+
+	t.Run("approved", func(t *testing.T) {
+		a := approve(t, key)
+		assert.Equal(t, ApprovalVersion, a.Version)
+		assert.Equal(t, publicKey, a.PublicKey)
+		assert.Equal(t, "2024-01-02T03:04:05Z", a.ApprovedAt)
+		assert.Nil(t, a.Verify(p, allowed))
+
+		// The approval round trips through JSON
+		data, err := json.Marshal(a)
+		assert.Nil(t, err)
+		parsed, err := ParseApproval(data)
+		assert.Nil(t, err)
+		assert.Nil(t, parsed.Verify(p, allowed))
+	})
+
+	t.Run("round trip with a left out pattern", func(t *testing.T) {
+		// The rule leaves out after
+		filter, err := ParseInspectFilter([]byte(`{"resourceChanges": [{"namePattern": "aws_lambda_function.*", "diffPatterns": {".source_code_hash": [{"before": "*"}]}}]}`))
+		assert.Nil(t, err)
+		a, err := p.Approve(&ApproveInput{Filter: filter, Signer: "alice", Key: key})
+		assert.Nil(t, err)
+
+		for i := 0; i < 2; i++ {
+			data, err := json.Marshal(a)
+			assert.Nil(t, err)
+			a = &Approval{}
+			assert.Nil(t, json.Unmarshal(data, a))
+		}
+		assert.Nil(t, a.Verify(p, allowed))
+	})
+
+	t.Run("tampered after parsing", func(t *testing.T) {
+		data, err := json.Marshal(approve(t, key))
+		assert.Nil(t, err)
+		tampered, err := ParseApproval([]byte(strings.Replace(string(data), `"signer":"alice"`, `"signer":"bob"`, 1)))
+		assert.Nil(t, err)
+		assert.EqualError(t, tampered.Verify(p, allowed), "approval signature of bob is invalid")
+	})
+
+	t.Run("signer not allowed", func(t *testing.T) {
+		a := approve(t, otherKey)
+		assert.EqualError(t, a.Verify(p, allowed), "signer alice is not allowed")
+		assert.EqualError(t, approve(t, key).Verify(p, nil), "signer alice is not allowed")
+	})
+
+	t.Run("tampered filter", func(t *testing.T) {
+		a := approve(t, key)
+		a.Filter = &InspectFilter{}
+		assert.EqualError(t, a.Verify(p, allowed), "approval signature of alice is invalid")
+	})
+
+	t.Run("tampered signer", func(t *testing.T) {
+		a := approve(t, otherKey)
+		a.Signer = "bob"
+		assert.EqualError(t, a.Verify(p, &AllowedSigners{Signers: []AllowedSigner{{Name: "bob", PublicKey: a.PublicKey}}}), "approval signature of bob is invalid")
+	})
+
+	t.Run("plan changed", func(t *testing.T) {
+		a := approve(t, key)
+
+		changed := &Plan{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.Replace(ciPlan, `"after": {"source_code_hash": "b"}`, `"after": {"source_code_hash": "c"}`, 1)), changed))
+		assert.ErrorContains(t, a.Verify(changed, allowed), "does not match approved digest "+a.Digest)
+	})
+
+	t.Run("sensitive value changed", func(t *testing.T) {
+		sensitivePlan := `{
+			"resource_changes": [
+				{
+					"address": "aws_db_instance.this",
+					"mode": "managed",
+					"type": "aws_db_instance",
+					"name": "this",
+					"change": {
+						"actions": ["update"],
+						"before": {"password": "a", "port": 5432},
+						"after": {"password": "b", "port": 5433},
+						"before_sensitive": {"password": true},
+						"after_sensitive": {"password": true}
+					}
+				}
+			]
+		}`
+		approved := &Plan{}
+		assert.Nil(t, json.Unmarshal([]byte(sensitivePlan), approved))
+		a, err := approved.Approve(&ApproveInput{Signer: "alice", Key: key})
+		assert.Nil(t, err)
+		assert.Nil(t, a.Verify(approved, allowed))
+
+		changed := &Plan{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.Replace(sensitivePlan, `"after": {"password": "b"`, `"after": {"password": "c"`, 1)), changed))
+		assert.ErrorContains(t, a.Verify(changed, allowed), "does not match approved digest "+a.Digest)
+	})
+
+	t.Run("output changed", func(t *testing.T) {
+		a := approve(t, key)
+
+		changed := &Plan{}
+		assert.Nil(t, json.Unmarshal([]byte(ciPlan), changed))
+		changed.OutputChanges = map[string]*tfJson.Change{"url": {Actions: tfJson.Actions{tfJson.ActionCreate}, After: "https://example.com"}}
+		assert.ErrorContains(t, a.Verify(changed, allowed), "does not match approved digest "+a.Digest)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		a := approve(t, key)
+		a.Version = 1
+		assert.EqualError(t, a.Verify(p, allowed), "unsupported approval version 1")
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		_, err := p.Approve(&ApproveInput{Signer: "alice"})
+		assert.EqualError(t, err, "approval key must be an ed25519 private key")
+	})
+}
+
+func Test_ParsePrivateKey(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err)
+
+	parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.Nil(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParsePrivateKey([]byte("not a key"))
+	assert.EqualError(t, err, "private key is not a PEM encoded PRIVATE KEY")
+}
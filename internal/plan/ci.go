@@ -0,0 +1,202 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// Write GitHub Actions workflow commands, step summary and outputs.
+	CIOutputGitHub = "github"
+	// Write a GitLab Code Quality report.
+	CIOutputGitLab = "gitlab"
+)
+
+// Result of calling CIReport() to report a plan to a CI system.
+type CIReport struct {
+	// Every change of the plan.
+	All *InspectOutput
+	// The changes not filtered out by the filter, which block the plan from
+	// being applied without review.
+	Blocked *InspectOutput
+
+	// The configuration directory the sources were located in.
+	configDir string
+}
+
+// An issue of a GitLab Code Quality report.
+type CodeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    CodeQualityLocation `json:"location"`
+}
+
+// The location of a CodeQualityIssue.
+type CodeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines CodeQualityLines `json:"lines"`
+}
+
+// The lines of a CodeQualityLocation.
+type CodeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+/*
+Inspects the plan with and without the filter to report every change and
+the changes blocked by the filter. The configuration is only parsed once,
+the blocked changes take their sources from every change.
+*/
+func (p *Plan) CIReport(params *InspectInput) (*CIReport, error) {
+	all, err := p.Inspect(&InspectInput{ConfigDir: params.ConfigDir, Context: params.Context})
+	if err != nil {
+		return nil, err
+	}
+
+	blockedParams := *params
+	blockedParams.ConfigDir = ""
+	blocked, err := p.Inspect(&blockedParams)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range blocked.entries() {
+		if location, ok := all.Diff.Sources[entry.key]; ok {
+			if blocked.Diff.Sources == nil {
+				blocked.Diff.Sources = map[string]SourceLocation{}
+			}
+			blocked.Diff.Sources[entry.key] = location
+		}
+	}
+
+	return &CIReport{All: all, Blocked: blocked, configDir: params.ConfigDir}, nil
+}
+
+/*
+Returns the path of GitLab Code Quality issues without a source location,
+the main.tf of the root module or else the configuration directory.
+*/
+func (r *CIReport) fallbackPath() string {
+	path := filepath.Join(r.configDir, "main.tf")
+	if _, err := os.Stat(path); err != nil && r.configDir != "" {
+		return r.configDir
+	}
+	return path
+}
+
+/*
+Checks if the plan has any changes, filtered or not.
+*/
+func (r *CIReport) HasChanges() bool {
+	return !r.All.IsEmpty()
+}
+
+/*
+Counts the resources, resource drifts and outputs with changes not filtered
+out.
+*/
+func (r *CIReport) BlockedCount() int {
+	return len(r.Blocked.Diff.Resources) + len(r.Blocked.Diff.ResourceDrifts) + len(r.Blocked.Diff.Outputs)
+}
+
+/*
+Produces the GitHub workflow commands printed to stdout, an error annotation
+per blocked change grouped in a collapsible log group.
+*/
+func (r *CIReport) GitHubCommands() []string {
+	var out []string
+	out = append(out, "::group::tfplan un-filtered changes\n")
+	for _, entry := range r.Blocked.entries() {
+		out = append(out, r.Blocked.annotation("error", entry))
+	}
+	out = append(out, "::endgroup::\n")
+
+	total := len(r.All.Diff.Resources) + len(r.All.Diff.ResourceDrifts) + len(r.All.Diff.Outputs)
+	out = append(out, fmt.Sprintf("::notice::%v of %v changes are not filtered out\n", r.BlockedCount(), total))
+	return out
+}
+
+/*
+Produces the Markdown lines of the GitHub step summary, written to the file
+at $GITHUB_STEP_SUMMARY.
+*/
+func (r *CIReport) GitHubSummary() []string {
+	var out []string
+	out = append(out, "## tfplan\n\n")
+
+	if !r.HasChanges() {
+		return append(out, "No changes.\n")
+	}
+	if r.BlockedCount() == 0 {
+		return append(out, "Every change is filtered out.\n")
+	}
+
+	out = append(out, fmt.Sprintf("%v changes are not filtered out:\n\n", r.BlockedCount()))
+	out = append(out, "| Kind | Address | Attributes | Source |\n")
+	out = append(out, "| --- | --- | --- | --- |\n")
+	for _, entry := range r.Blocked.entries() {
+		paths := []string{}
+		for _, path := range entry.paths {
+			paths = append(paths, fmt.Sprintf("`%s`", path))
+		}
+		source := ""
+		if location, ok := r.Blocked.Diff.Sources[entry.key]; ok {
+			source = fmt.Sprintf("`%s`", location)
+		}
+		out = append(out, fmt.Sprintf("| %s | `%s` | %s | %s |\n", entry.kind, entry.address, strings.ReplaceAll(strings.Join(paths, ", "), "|", "\\|"), source))
+	}
+	return out
+}
+
+/*
+Produces the name=value lines of the GitHub step outputs, written to the file
+at $GITHUB_OUTPUT.
+*/
+func (r *CIReport) GitHubOutputs() []string {
+	return []string{
+		fmt.Sprintf("has_changes=%v\n", r.HasChanges()),
+		fmt.Sprintf("blocked_count=%v\n", r.BlockedCount()),
+	}
+}
+
+/*
+Produces the issues of a GitLab Code Quality report, one per blocked change.
+Deletes and replacements are critical, other changes major and drift minor.
+Changes without a source location are reported at the first line of the
+root module.
+*/
+func (r *CIReport) GitLabCodeQuality() []CodeQualityIssue {
+	out := []CodeQualityIssue{}
+	fallback := r.fallbackPath()
+	for _, entry := range r.Blocked.entries() {
+		severity := "major"
+		actions := r.Blocked.Diff.ResourceActions[entry.key]
+		switch {
+		case entry.kind == "drift":
+			severity = "minor"
+		case entry.kind == "resource" && slices.Contains(actions, "delete"):
+			severity = "critical"
+		}
+
+		hash := sha256.Sum256([]byte(entry.kind + ":" + entry.address))
+		issue := CodeQualityIssue{
+			Description: entry.message(),
+			CheckName:   "tfplan-" + entry.kind,
+			Fingerprint: hex.EncodeToString(hash[:]),
+			Severity:    severity,
+			Location:    CodeQualityLocation{Path: fallback, Lines: CodeQualityLines{Begin: 1}},
+		}
+		if location, ok := r.Blocked.Diff.Sources[entry.key]; ok {
+			issue.Location = CodeQualityLocation{Path: location.File, Lines: CodeQualityLines{Begin: location.Line}}
+		}
+		out = append(out, issue)
+	}
+	return out
+}
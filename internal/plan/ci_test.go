@@ -0,0 +1,135 @@
+package plan
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+const ciPlan = `{
+	"format_version": "1.2",
+	"resource_changes": [
+		{
+			"address": "aws_vpc.main",
+			"mode": "managed",
+			"type": "aws_vpc",
+			"name": "main",
+			"change": {"actions": ["delete", "create"], "before": {"cidr_block": "10.0.0.0/16"}, "after": {"cidr_block": "10.1.0.0/16"}}
+		},
+		{
+			"address": "aws_lambda_function.this",
+			"mode": "managed",
+			"type": "aws_lambda_function",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"source_code_hash": "a"}, "after": {"source_code_hash": "b"}}
+		}
+	],
+	"resource_drift": [
+		{
+			"address": "aws_vpc.main",
+			"mode": "managed",
+			"type": "aws_vpc",
+			"name": "main",
+			"change": {"actions": ["update"], "before": {"tags": {"Owner": "a"}}, "after": {"tags": {"Owner": "b"}}}
+		}
+	]
+}`
+
+func Test_CIReport(t *testing.T) {
+	p := &Plan{}
+	assert.Nil(t, json.Unmarshal([]byte(ciPlan), p))
+
+	filter := &InspectFilter{
+		ResourceChanges: []Filter{
+			{
+				NamePattern:  Patterns{"aws_lambda_function.*"},
+				DiffPatterns: map[string][]DiffPattern{".source_code_hash": {{Before: Patterns{"*"}, After: Patterns{"*"}}}},
+			},
+		},
+	}
+
+	report, err := p.CIReport(&InspectInput{Filter: filter})
+	assert.Nil(t, err)
+	report.Blocked.Diff.Sources = map[string]SourceLocation{"aws_vpc.main": {File: "main.tf", Line: 4}}
+
+	assert.True(t, report.HasChanges())
+	assert.Equal(t, 2, report.BlockedCount())
+
+	diff.Check(t, []string{
+		"::group::tfplan un-filtered changes\n",
+		"::error file=main.tf,line=4::aws_vpc.main changes: .cidr_block\n",
+		"::error file=main.tf,line=4::aws_vpc.main drift: .tags.Owner\n",
+		"::endgroup::\n",
+		"::notice::2 of 3 changes are not filtered out\n",
+	}, report.GitHubCommands())
+
+	diff.Check(t, []string{
+		"## tfplan\n\n",
+		"2 changes are not filtered out:\n\n",
+		"| Kind | Address | Attributes | Source |\n",
+		"| --- | --- | --- | --- |\n",
+		"| resource | `aws_vpc.main` | `.cidr_block` | `main.tf:4` |\n",
+		"| drift | `aws_vpc.main` | `.tags.Owner` | `main.tf:4` |\n",
+	}, report.GitHubSummary())
+
+	diff.Check(t, []string{
+		"has_changes=true\n",
+		"blocked_count=2\n",
+	}, report.GitHubOutputs())
+
+	diff.Check(t, []CodeQualityIssue{
+		{
+			Description: "aws_vpc.main changes: .cidr_block",
+			CheckName:   "tfplan-resource",
+			Fingerprint: "5e59d68b09d95ec0f49a652fd2a51971a2929927dcdb33ecb591ea069156f67e",
+			Severity:    "critical",
+			Location:    CodeQualityLocation{Path: "main.tf", Lines: CodeQualityLines{Begin: 4}},
+		},
+		{
+			Description: "aws_vpc.main drift: .tags.Owner",
+			CheckName:   "tfplan-drift",
+			Fingerprint: "5e2529f699d957d183594d2d02dda36c94537e457eb71cc148cd07c554aba8e6",
+			Severity:    "minor",
+			Location:    CodeQualityLocation{Path: "main.tf", Lines: CodeQualityLines{Begin: 4}},
+		},
+	}, report.GitLabCodeQuality())
+
+	t.Run("no blocked changes", func(t *testing.T) {
+		report := &CIReport{
+			All:     &InspectOutput{Diff: &InspectDiff{Resources: map[string]EntityDiff{"aws_vpc.main": {".cidr_block": {Before: "a", After: "b"}}}}},
+			Blocked: &InspectOutput{Diff: &InspectDiff{}},
+		}
+		diff.Check(t, []string{"## tfplan\n\n", "Every change is filtered out.\n"}, report.GitHubSummary())
+		diff.Check(t, []string{"has_changes=true\n", "blocked_count=0\n"}, report.GitHubOutputs())
+		diff.Check(t, []CodeQualityIssue{}, report.GitLabCodeQuality())
+	})
+
+	t.Run("sources", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSourceFiles(t, dir, map[string]string{
+			"network.tf": "\nresource \"aws_vpc\" \"main\" {\n  cidr_block = \"10.1.0.0/16\"\n}\n",
+		})
+
+		report, err := p.CIReport(&InspectInput{Filter: filter, ConfigDir: dir})
+		assert.Nil(t, err)
+		diff.Check(t, map[string]SourceLocation{"aws_vpc.main": {File: filepath.Join(dir, "network.tf"), Line: 2}}, report.Blocked.Diff.Sources)
+	})
+
+	t.Run("fallback location", func(t *testing.T) {
+		dir := t.TempDir()
+		report, err := p.CIReport(&InspectInput{Filter: filter, ConfigDir: dir})
+		assert.Nil(t, err)
+		issues := report.GitLabCodeQuality()
+		assert.Len(t, issues, 2)
+		assert.Equal(t, CodeQualityLocation{Path: dir, Lines: CodeQualityLines{Begin: 1}}, issues[0].Location)
+
+		writeSourceFiles(t, dir, map[string]string{"main.tf": "terraform {}\n"})
+		assert.Equal(t, CodeQualityLocation{Path: filepath.Join(dir, "main.tf"), Lines: CodeQualityLines{Begin: 1}}, report.GitLabCodeQuality()[0].Location)
+
+		report.configDir = ""
+		assert.Equal(t, "main.tf", report.GitLabCodeQuality()[0].Location.Path)
+	})
+}
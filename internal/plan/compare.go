@@ -1,7 +1,9 @@
 package plan
 
 import (
+	"context"
 	"fmt"
+	"sort"
 
 	"github.com/orange-car/tfplan/internal/helpers"
 )
@@ -26,6 +28,31 @@ type CompareDiff struct {
 	Outputs map[string]CompareEntityDiff `json:"outputs"`
 	// Set of resource drifts and their respective diverging plan a and b differences.
 	ResourceDrifts map[string]CompareEntityDiff `json:"resourceDrifts"`
+	// Divergences between the un-filtered changes of the plans. Only set
+	// with the CompareScopeRaw scope.
+	Raw *CompareDiff `json:"raw,omitempty"`
+	// Diverging variable values keyed by variable name and path. Only set
+	// with the CompareScopeVariables scope.
+	Variables map[string]CompareValue `json:"variables,omitempty"`
+	// Diverging provider configuration keyed by provider and path. Only set
+	// with the CompareScopeProviders scope.
+	Providers map[string]CompareValue `json:"providers,omitempty"`
+	// Diverging plan metadata keyed by field. Only set with the
+	// CompareScopeMetadata scope.
+	Metadata map[string]CompareValue `json:"metadata,omitempty"`
+	// Diverging planned actions keyed by resource address. Only set with
+	// the CompareModeActions mode.
+	ResourceActions map[string]CompareValue `json:"resourceActions,omitempty"`
+	// Differences suppressed by the CompareOptions.
+	Suppressed []SuppressedDiff `json:"suppressed,omitempty"`
+}
+
+// A value that diverges between plan A and plan B.
+type CompareValue struct {
+	// The value in plan A.
+	PlanA string `json:"planA"`
+	// The value in plan B.
+	PlanB string `json:"planB"`
 }
 
 // Result of calling CompareInspects() to identify divergences of two inspected plans
@@ -38,11 +65,29 @@ type CompareInspectsOutput struct {
 Checks if a CompareInspectsOutput is empty
 */
 func (c *CompareInspectsOutput) IsEmpty() bool {
-	return len(c.Diff.Outputs) == 0 && len(c.Diff.ResourceDrifts) == 0 && len(c.Diff.ResourceDrifts) == 0
+	return c.Diff.IsEmpty()
 }
 
-func getEntityDiffDivergence(a, b map[string]EntityDiff) map[string]CompareEntityDiff {
+/*
+Checks if a CompareDiff is empty
+*/
+func (c *CompareDiff) IsEmpty() bool {
+	return len(c.Outputs) == 0 && len(c.ResourceDrifts) == 0 && len(c.Resources) == 0 &&
+		(c.Raw == nil || c.Raw.IsEmpty()) && len(c.Variables) == 0 && len(c.Providers) == 0 && len(c.Metadata) == 0 &&
+		len(c.ResourceActions) == 0
+}
+
+func getEntityDiffDivergence(kind string, a, b map[string]EntityDiff, opts *CompareOptions) (map[string]CompareEntityDiff, []SuppressedDiff) {
 	out := map[string]CompareEntityDiff{}
+	suppressed := []SuppressedDiff{}
+
+	// Comparing after values, a plan without a diff at a path of an address
+	// changing in both plans leaves it at its current value, which may well
+	// be the other plan's after value. The current value is not known, so
+	// only paths changing in both plans are compared. Addresses changing in
+	// only one plan are still reported, e.g. a resource only one plan
+	// creates.
+	afterOnly := opts != nil && opts.Mode == CompareModeAfter
 
 	// Go over each item (A)
 	for aAddress, aEntityDiff := range a {
@@ -70,6 +115,10 @@ func getEntityDiffDivergence(a, b map[string]EntityDiff) map[string]CompareEntit
 			if _, ok := b[aAddress][aPath]; !ok {
 				// a path for address is not in b address
 
+				if afterOnly {
+					continue
+				}
+
 				if _, exists := out[aAddress]; !exists {
 					out[aAddress] = CompareEntityDiff{
 						PlanA: EntityDiff{},
@@ -85,7 +134,11 @@ func getEntityDiffDivergence(a, b map[string]EntityDiff) map[string]CompareEntit
 				continue
 			}
 
-			if *aDiff != *b[aAddress][aPath] {
+			if equal, reason := opts.equal(aDiff, b[aAddress][aPath]); equal && reason != "" {
+				// a address and path present in b and the diff is only the same with the options applied
+
+				suppressed = append(suppressed, SuppressedDiff{Kind: kind, Address: aAddress, Path: aPath, Reason: reason, PlanA: aDiff, PlanB: b[aAddress][aPath]})
+			} else if !equal {
 				// a address and path present in b but diff is not the same
 
 				if _, exists := out[aAddress]; !exists {
@@ -127,6 +180,10 @@ func getEntityDiffDivergence(a, b map[string]EntityDiff) map[string]CompareEntit
 			if _, ok := a[bAddress][bPath]; !ok {
 				// b path for address is not in a address
 
+				if afterOnly {
+					continue
+				}
+
 				if _, exists := out[bAddress]; !exists {
 					out[bAddress] = CompareEntityDiff{
 						PlanA: EntityDiff{},
@@ -142,7 +199,7 @@ func getEntityDiffDivergence(a, b map[string]EntityDiff) map[string]CompareEntit
 				continue
 			}
 
-			if *bDiff != *a[bAddress][bPath] {
+			if equal, _ := opts.equal(a[bAddress][bPath], bDiff); !equal {
 				// b address and path present in a but diff is not the same
 
 				if _, exists := out[bAddress]; !exists {
@@ -159,7 +216,7 @@ func getEntityDiffDivergence(a, b map[string]EntityDiff) map[string]CompareEntit
 
 	}
 
-	return out
+	return out, suppressed
 }
 
 /*
@@ -169,112 +226,301 @@ a divergence between InspectOutput a and b. In other words, only
 actual differences are found.
 */
 func CompareInspects(a, b *InspectOutput) *CompareInspectsOutput {
+	// Without options there are no patterns to fail matching
+	out, _ := CompareInspectsWithOptions(a, b, nil)
+	return out
+}
+
+/*
+Compares the output of two Inspects in the same way as CompareInspects
+with the options applied. Differences suppressed by the options are
+reported in the output.
+*/
+func CompareInspectsWithOptions(a, b *InspectOutput, opts *CompareOptions) (*CompareInspectsOutput, error) {
+	if err := opts.validateMode(); err != nil {
+		return nil, err
+	}
+
+	if opts != nil && opts.Mode == CompareModeActions {
+		// Only the shape of the change matters, not the values
+		return &CompareInspectsOutput{
+			Diff: &CompareDiff{
+				Resources:       map[string]CompareEntityDiff{},
+				Outputs:         map[string]CompareEntityDiff{},
+				ResourceDrifts:  map[string]CompareEntityDiff{},
+				ResourceActions: getActionDivergence(a.Diff.ResourceActions, b.Diff.ResourceActions),
+			},
+		}, nil
+	}
+
+	resources, rSuppressed, err := compareEntityDiffs("resource", a.Diff.Resources, b.Diff.Resources, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compare resources caused by: %w", err)
+	}
+	outputs, oSuppressed, err := compareEntityDiffs("output", a.Diff.Outputs, b.Diff.Outputs, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compare outputs caused by: %w", err)
+	}
+	drifts, dSuppressed, err := compareEntityDiffs("drift", a.Diff.ResourceDrifts, b.Diff.ResourceDrifts, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compare resource drifts caused by: %w", err)
+	}
+
 	out := &CompareInspectsOutput{
 		Diff: &CompareDiff{
-			Resources:      getEntityDiffDivergence(a.Diff.Resources, b.Diff.Resources),
-			Outputs:        getEntityDiffDivergence(a.Diff.Outputs, b.Diff.Outputs),
-			ResourceDrifts: getEntityDiffDivergence(a.Diff.ResourceDrifts, b.Diff.ResourceDrifts),
+			Resources:      resources,
+			Outputs:        outputs,
+			ResourceDrifts: drifts,
 		},
 	}
 
-	return out
+	if opts != nil && opts.Mode == CompareModeAfter {
+		// Only paths changing in both plans are compared, so the actions of
+		// resources changing in both plans are compared too, e.g. a replace
+		// against an update in place
+		aActions := map[string][]string{}
+		bActions := map[string][]string{}
+		for address, actions := range a.Diff.ResourceActions {
+			if bAction, ok := b.Diff.ResourceActions[address]; ok {
+				aActions[address] = actions
+				bActions[address] = bAction
+			}
+		}
+		if actions := getActionDivergence(aActions, bActions); len(actions) > 0 {
+			out.Diff.ResourceActions = actions
+		}
+	}
+
+	suppressed := append(append(rSuppressed, oSuppressed...), dSuppressed...)
+	if len(suppressed) > 0 {
+		out.Diff.Suppressed = suppressed
+	}
+
+	return out, nil
+}
+
+type ComparePlansInput struct {
+	// Optional filter to apply to both plans before comparing.
+	Filter *InspectFilter
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string
+	// Optional rewrite rules to apply to both plans before comparing.
+	Rewrites *RewriteRules
+	// Optional additional scopes to compare. See the CompareScope constants.
+	Scopes []string
+	// Optional metadata of plan A and B. Defaults to the metadata held by
+	// the plans when nil.
+	MetadataA, MetadataB *PlanMetadata
+	// Optional options making the compare tolerate equivalent values.
+	Options *CompareOptions
+	// Optional context which stops the operation early when it ends.
+	Context context.Context
+}
+
+func inspectAndRewrite(p *Plan, filter *InspectFilter, params *ComparePlansInput) (*InspectOutput, error) {
+	out, err := p.Inspect(&InspectInput{
+		Filter:  filter,
+		Set:     params.Set,
+		Context: params.Context,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Rewrite(params.Rewrites)
 }
 
 /*
-Produces a slice of strings output which can be printed line by line
-to get a Terraform-style stdout report of the compare.
+Inspects both plans and compares them. Depending on the scopes, the
+un-filtered changes, variables, provider configuration and metadata of
+the plans are compared as well.
 */
-func (c *CompareInspectsOutput) Pretty() []string {
-	var out []string
-	out = append(out, "\tTerraform plans differ at the following un-filtered changes:\n")
+func ComparePlans(a, b *Plan, params *ComparePlansInput) (*CompareInspectsOutput, error) {
+	aOut, err := inspectAndRewrite(a, params.Filter, params)
+	if err != nil {
+		return nil, err
+	}
 
-	for address, compEntityDiff := range c.Diff.Resources {
-		out = append(out, fmt.Sprintf("\n\t\tresource %s\"%s\"%s changes:\n", colorBold, address, colorNone))
-		out = append(out, fmt.Sprintf("\n\t\t\t%sPlan A:%s\n", colorBold, colorNone))
+	bOut, err := inspectAndRewrite(b, params.Filter, params)
+	if err != nil {
+		return nil, err
+	}
 
-		maxWidth := 0
-		for path := range compEntityDiff.PlanA {
-			if len(path) > maxWidth {
-				maxWidth = len(path)
+	out, err := CompareInspectsWithOptions(aOut, bOut, params.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scope := range params.Scopes {
+		switch scope {
+		case CompareScopeRaw:
+			aRaw, err := inspectAndRewrite(a, nil, params)
+			if err != nil {
+				return nil, err
 			}
-		}
+			bRaw, err := inspectAndRewrite(b, nil, params)
+			if err != nil {
+				return nil, err
+			}
+			raw, err := CompareInspectsWithOptions(aRaw, bRaw, params.Options)
+			if err != nil {
+				return nil, err
+			}
+			out.Diff.Raw = raw.Diff
 
-		for path, diff := range compEntityDiff.PlanA {
-			out = append(out, fmt.Sprintf("\t\t\t\t%s:%s%s %s->%s %s\n", path, helpers.FillWithSpaces(path, maxWidth), diff.Before, colorOrange, colorNone, diff.After))
-		}
+		case CompareScopeVariables:
+			aVals, err := variableValues(a)
+			if err != nil {
+				return nil, err
+			}
+			bVals, err := variableValues(b)
+			if err != nil {
+				return nil, err
+			}
+			// A variable is redacted when either plan marks it sensitive
+			sensitive := sensitiveVariables(a)
+			for name := range sensitiveVariables(b) {
+				sensitive[name] = true
+			}
+			out.Diff.Variables = redactVariables(getValueDivergence(aVals, bVals), sensitive)
 
-		out = append(out, fmt.Sprintf("\n\t\t\t%sPlan B:%s\n", colorBold, colorNone))
+		case CompareScopeProviders:
+			aVals, err := providerValues(a)
+			if err != nil {
+				return nil, err
+			}
+			bVals, err := providerValues(b)
+			if err != nil {
+				return nil, err
+			}
+			out.Diff.Providers = getValueDivergence(aVals, bVals)
 
-		maxWidth = 0
-		for path := range compEntityDiff.PlanA {
-			if len(path) > maxWidth {
-				maxWidth = len(path)
+		case CompareScopeMetadata:
+			aMeta, bMeta := params.MetadataA, params.MetadataB
+			if aMeta == nil {
+				aMeta = a.metadata()
 			}
-		}
+			if bMeta == nil {
+				bMeta = b.metadata()
+			}
+			aVals, err := metadataValues(aMeta)
+			if err != nil {
+				return nil, err
+			}
+			bVals, err := metadataValues(bMeta)
+			if err != nil {
+				return nil, err
+			}
+			out.Diff.Metadata = getValueDivergence(aVals, bVals)
 
-		for path, diff := range compEntityDiff.PlanB {
-			out = append(out, fmt.Sprintf("\t\t\t\t%s:%s%s %s->%s %s\n", path, helpers.FillWithSpaces(path, maxWidth), diff.Before, colorOrange, colorNone, diff.After))
+		default:
+			return nil, fmt.Errorf("unknown compare scope %s", scope)
 		}
 	}
 
-	for address, compEntityDiff := range c.Diff.ResourceDrifts {
-		out = append(out, fmt.Sprintf("\n\t\tresource %s\"%s\"%s drift:\n", colorBold, address, colorNone))
-		out = append(out, fmt.Sprintf("\n\t\t\t%sPlan A:%s\n", colorBold, colorNone))
+	return out, nil
+}
 
-		maxWidth := 0
-		for path := range compEntityDiff.PlanA {
-			if len(path) > maxWidth {
-				maxWidth = len(path)
-			}
-		}
+func prettyCompareValues(kind string, values map[string]CompareValue) []string {
+	var out []string
+	if len(values) == 0 {
+		return out
+	}
 
-		for path, diff := range compEntityDiff.PlanA {
-			out = append(out, fmt.Sprintf("\t\t\t\t%s:%s%s %s->%s %s\n", path, helpers.FillWithSpaces(path, maxWidth), diff.Before, colorOrange, colorNone, diff.After))
+	keys := []string{}
+	maxWidth := 0
+	for k := range values {
+		keys = append(keys, k)
+		if len(k) > maxWidth {
+			maxWidth = len(k)
 		}
+	}
+	sort.Strings(keys)
 
-		out = append(out, fmt.Sprintf("\n\t\t\t%sPlan B:%s\n", colorBold, colorNone))
+	out = append(out, fmt.Sprintf("\n\t\t%s%s%s differences:\n", colorBold, kind, colorNone))
+	for _, k := range keys {
+		out = append(out, fmt.Sprintf("\t\t\t%s:%sPlan A: %s %s|%s Plan B: %s\n", k, helpers.FillWithSpaces(k, maxWidth), values[k].PlanA, colorOrange, colorNone, values[k].PlanB))
+	}
+	return out
+}
 
-		maxWidth = 0
-		for path := range compEntityDiff.PlanA {
-			if len(path) > maxWidth {
-				maxWidth = len(path)
-			}
-		}
+/*
+Produces a slice of strings output which can be printed line by line
+to get a Terraform-style stdout report of the compare.
+*/
+func (c *CompareInspectsOutput) Pretty() []string {
+	// Without options there is no view to fail matching
+	out, _ := c.PrettyWithOptions(nil)
+	return out
+}
 
-		for path, diff := range compEntityDiff.PlanB {
-			out = append(out, fmt.Sprintf("\t\t\t\t%s:%s%s %s->%s %s\n", path, helpers.FillWithSpaces(path, maxWidth), diff.Before, colorOrange, colorNone, diff.After))
-		}
+/*
+Produces the same report as Pretty in the view chosen by the options.
+*/
+func (c *CompareInspectsOutput) PrettyWithOptions(opts *PrettyOptions) ([]string, error) {
+	if opts == nil {
+		opts = &PrettyOptions{}
 	}
 
-	for address, compEntityDiff := range c.Diff.Outputs {
-		out = append(out, fmt.Sprintf("\n\t\toutput %s\"%s\"%s changes:\n", colorBold, address, colorNone))
-		out = append(out, fmt.Sprintf("\n\t\t\t%sPlan A:%s\n", colorBold, colorNone))
+	var view compareView
+	switch opts.View {
+	case "", CompareViewBlocks:
+		view = prettyBlocks
+	case CompareViewSideBySide:
+		view = prettySideBySide
+	case CompareViewUnified:
+		view = prettyUnified
+	default:
+		return nil, fmt.Errorf("unknown compare view %s", opts.View)
+	}
 
-		maxWidth := 0
-		for path := range compEntityDiff.PlanA {
-			if len(path) > maxWidth {
-				maxWidth = len(path)
-			}
-		}
+	out := c.pretty(view)
+	if opts.NoColor {
+		out = stripColors(out)
+	}
+	return out, nil
+}
 
-		for path, diff := range compEntityDiff.PlanA {
-			out = append(out, fmt.Sprintf("\t\t\t\t%s:%s%s %s->%s %s\n", path, helpers.FillWithSpaces(path, maxWidth), diff.Before, colorOrange, colorNone, diff.After))
-		}
+func (c *CompareInspectsOutput) pretty(view compareView) []string {
+	var out []string
+	out = append(out, "\tTerraform plans differ at the following un-filtered changes:\n")
 
-		out = append(out, fmt.Sprintf("\n\t\t\t%sPlan B:%s\n", colorBold, colorNone))
+	for _, address := range sortedKeys(c.Diff.Resources) {
+		out = append(out, fmt.Sprintf("\n\t\tresource %s\"%s\"%s changes:\n", colorBold, address, colorNone))
+		out = append(out, view(c.Diff.Resources[address])...)
+	}
 
-		maxWidth = 0
-		for path := range compEntityDiff.PlanA {
-			if len(path) > maxWidth {
-				maxWidth = len(path)
-			}
-		}
+	for _, address := range sortedKeys(c.Diff.ResourceDrifts) {
+		out = append(out, fmt.Sprintf("\n\t\tresource %s\"%s\"%s drift:\n", colorBold, address, colorNone))
+		out = append(out, view(c.Diff.ResourceDrifts[address])...)
+	}
 
-		for path, diff := range compEntityDiff.PlanB {
-			out = append(out, fmt.Sprintf("\t\t\t\t%s:%s%s %s->%s %s\n", path, helpers.FillWithSpaces(path, maxWidth), diff.Before, colorOrange, colorNone, diff.After))
+	for _, name := range sortedKeys(c.Diff.Outputs) {
+		out = append(out, fmt.Sprintf("\n\t\toutput %s\"%s\"%s changes:\n", colorBold, name, colorNone))
+		out = append(out, view(c.Diff.Outputs[name])...)
+	}
+
+	out = append(out, prettyCompareValues("action", c.Diff.ResourceActions)...)
+	out = append(out, prettyCompareValues("variable", c.Diff.Variables)...)
+	out = append(out, prettyCompareValues("provider", c.Diff.Providers)...)
+	out = append(out, prettyCompareValues("metadata", c.Diff.Metadata)...)
+
+	if len(c.Diff.Suppressed) > 0 {
+		out = append(out, fmt.Sprintf("\n\t\t%ssuppressed%s differences:\n", colorBold, colorNone))
+		for _, s := range c.Diff.Suppressed {
+			out = append(out, fmt.Sprintf("\t\t\t%s %s%s (%s): Plan A: %s %s->%s %s %s|%s Plan B: %s %s->%s %s\n", s.Kind, s.Address, s.Path, s.Reason, s.PlanA.Before, colorOrange, colorNone, s.PlanA.After, colorOrange, colorNone, s.PlanB.Before, colorOrange, colorNone, s.PlanB.After))
 		}
 	}
 
-	out = append(out, fmt.Sprintf("\n\tChanges: %v resources, %v resource drifts, %v outputs\n", len(c.Diff.Resources), len(c.Diff.ResourceDrifts), len(c.Diff.Outputs)))
+	out = append(out, fmt.Sprintf("\n\tChanges: %v resources, %v resource drifts, %v outputs\n", len(c.Diff.Resources)+len(c.Diff.ResourceActions), len(c.Diff.ResourceDrifts), len(c.Diff.Outputs)))
+
+	if len(c.Diff.Suppressed) > 0 {
+		out = append(out, fmt.Sprintf("\tSuppressed: %v differences\n", len(c.Diff.Suppressed)))
+	}
+
+	if c.Diff.Raw != nil {
+		raw := (&CompareInspectsOutput{Diff: c.Diff.Raw}).pretty(view)
+		raw[0] = "\n\tTerraform plans differ at the following changes before filtering:\n"
+		out = append(out, raw...)
+	}
 	return out
 }
@@ -0,0 +1,414 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/vodkaslime/wildcard"
+)
+
+/*
+Conditions are small boolean expressions attached to a Filter. They are
+evaluated against the un-filtered diffs of the plan so that a filter can
+depend on other attributes of the same entity or on other changes in the
+plan.
+
+	expr    := or
+	or      := and { "||" and }
+	and     := unary { "&&" unary }
+	unary   := "!" unary | compare
+	compare := primary [ ( "==" | "!=" ) primary ]
+	primary := "(" expr ")" | string | "true" | "false" | call
+	call    := ident "(" [ expr { "," expr } ] ")"
+
+Supported functions:
+  - changed(path) the entity has a change at a path matching the pattern.
+  - before(path) / after(path) the value of the entity's change at exactly
+    the path or "" when the path did not change. Unlike the other functions
+    the path is not a pattern, as a pattern could match several values.
+  - resource_changed(address[, path]) a resource matching the address
+    pattern changes (at a path matching the pattern).
+  - drift_changed(address[, path]) same as resource_changed for drift.
+  - output_changed(name) an output matching the name pattern changes.
+
+Unknown functions and wrong numbers of arguments are parse errors.
+*/
+
+// The environment a condition is evaluated in.
+type conditionEnv struct {
+	// The un-filtered diff of the entity the filter is applied to.
+	self EntityDiff
+	// The un-filtered diffs of the whole plan.
+	plan *InspectDiff
+}
+
+type condExpr interface {
+	eval(env *conditionEnv) (any, error)
+}
+
+type condLiteral struct {
+	value any
+}
+
+type condNot struct {
+	expr condExpr
+}
+
+type condBinary struct {
+	op          string
+	left, right condExpr
+}
+
+type condCall struct {
+	name string
+	args []condExpr
+}
+
+// The minimum and maximum number of arguments of each supported function.
+var condFunctions = map[string][2]int{
+	"changed":          {1, 1},
+	"before":           {1, 1},
+	"after":            {1, 1},
+	"resource_changed": {1, 2},
+	"drift_changed":    {1, 2},
+	"output_changed":   {1, 2},
+}
+
+func (c *condLiteral) eval(env *conditionEnv) (any, error) {
+	return c.value, nil
+}
+
+func (c *condNot) eval(env *conditionEnv) (any, error) {
+	b, err := evalBool(c.expr, env)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+func (c *condBinary) eval(env *conditionEnv) (any, error) {
+	switch c.op {
+	case "&&", "||":
+		l, err := evalBool(c.left, env)
+		if err != nil {
+			return nil, err
+		}
+		if (c.op == "&&" && !l) || (c.op == "||" && l) {
+			return l, nil
+		}
+		return evalBool(c.right, env)
+	}
+
+	l, err := c.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if c.op == "==" {
+		return l == r, nil
+	}
+	return l != r, nil
+}
+
+func (c *condCall) eval(env *conditionEnv) (any, error) {
+	args := []string{}
+	for _, a := range c.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() arguments must be strings", c.name)
+		}
+		args = append(args, s)
+	}
+
+	m := wildcard.NewMatcher()
+
+	switch c.name {
+	case "changed":
+		return anyPathMatches(m, env.self, args[0])
+
+	case "before", "after":
+		d, ok := env.self[args[0]]
+		if !ok {
+			return "", nil
+		}
+		if c.name == "before" {
+			return d.Before, nil
+		}
+		return d.After, nil
+
+	case "resource_changed", "drift_changed", "output_changed":
+		entities := env.plan.Resources
+		if c.name == "drift_changed" {
+			entities = env.plan.ResourceDrifts
+		} else if c.name == "output_changed" {
+			entities = env.plan.Outputs
+		}
+
+		for address, entDiff := range entities {
+			if match, err := matchPattern(m, args[0], address); err != nil {
+				return nil, fmt.Errorf("invalid %s() pattern %s caused by: %w", c.name, args[0], err)
+			} else if !match {
+				continue
+			}
+			if len(args) == 1 {
+				return true, nil
+			}
+			if match, err := anyPathMatches(m, entDiff, args[1]); err != nil {
+				return nil, err
+			} else if match {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return nil, fmt.Errorf("unknown function %s()", c.name)
+}
+
+func anyPathMatches(m *wildcard.Matcher, e EntityDiff, pattern string) (bool, error) {
+	for path := range e {
+		if match, err := matchPattern(m, pattern, path); err != nil {
+			// Not located by the path as the map's order is random
+			return false, fmt.Errorf("invalid path pattern %s caused by: %w", pattern, err)
+		} else if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func evalBool(e condExpr, env *conditionEnv) (bool, error) {
+	v, err := e.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean but got %q", v)
+	}
+	return b, nil
+}
+
+/*
+Parses and evaluates the condition in the environment. The condition
+must evaluate to a boolean.
+*/
+func evalCondition(condition string, env *conditionEnv) (bool, error) {
+	expr, err := parseCondition(condition)
+	if err != nil {
+		return false, err
+	}
+	return evalBool(expr, env)
+}
+
+type condParser struct {
+	tokens []string
+	pos    int
+}
+
+/*
+Parses a condition into an expression tree that can be evaluated.
+*/
+func parseCondition(condition string) (condExpr, error) {
+	tokens, err := tokenizeCondition(condition)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse condition %s caused by: %w", condition, err)
+	}
+
+	p := &condParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse condition %s caused by: %w", condition, err)
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unable to parse condition %s caused by: unexpected %s", condition, p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+/*
+Escapes the backslashes and double quotes of s, e.g. a resolved placeholder
+value, so it can be placed inside a string literal of a condition.
+*/
+func escapeStringLiteral(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func tokenizeCondition(s string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		case strings.ContainsRune("(),", rune(c)):
+			tokens = append(tokens, string(c))
+			i++
+		case i+1 < len(s) && (s[i:i+2] == "&&" || s[i:i+2] == "||" || s[i:i+2] == "==" || s[i:i+2] == "!="):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		case c == '!':
+			tokens = append(tokens, "!")
+			i++
+		case unicode.IsLetter(rune(c)) || c == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *condParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *condParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *condParser) parseOr() (condExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &condBinary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseAnd() (condExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &condBinary{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseUnary() (condExpr, error) {
+	if p.peek() == "!" {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &condNot{expr: expr}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *condParser) parseCompare() (condExpr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if op := p.peek(); op == "==" || op == "!=" {
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &condBinary{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *condParser) parsePrimary() (condExpr, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of condition")
+	case t == "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return expr, nil
+	case strings.HasPrefix(t, `"`):
+		// Other escapes are left for the pattern, e.g. \* for a literal *
+		return &condLiteral{value: strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(t[1 : len(t)-1])}, nil
+	case t == "true" || t == "false":
+		return &condLiteral{value: t == "true"}, nil
+	case unicode.IsLetter(rune(t[0])) || t[0] == '_':
+		if p.next() != "(" {
+			return nil, fmt.Errorf("expected ( after %s", t)
+		}
+		call := &condCall{name: t}
+		for p.peek() != ")" {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.peek() == "," {
+				p.next()
+				if p.peek() == ")" {
+					return nil, fmt.Errorf("trailing , in %s()", t)
+				}
+			} else if p.peek() != ")" {
+				return nil, fmt.Errorf("expected , or ) in %s()", t)
+			}
+		}
+		p.next()
+
+		arity, ok := condFunctions[t]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("unknown function %s()", t)
+		case arity[0] == arity[1] && len(call.args) != arity[0]:
+			return nil, fmt.Errorf("%s() takes %d argument", t, arity[0])
+		case len(call.args) < arity[0] || len(call.args) > arity[1]:
+			return nil, fmt.Errorf("%s() takes %d or %d arguments", t, arity[0], arity[1])
+		}
+		return call, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", t)
+}
@@ -0,0 +1,124 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EvalCondition(t *testing.T) {
+	env := &conditionEnv{
+		self: EntityDiff{
+			".image":  {Before: "app:1", After: "app:2"},
+			".cpu":    {Before: "256", After: "512"},
+			".family": {Before: "app", After: "app"},
+		},
+		plan: &InspectDiff{
+			Resources: map[string]EntityDiff{
+				"aws_s3_object.x_code": {
+					".etag": {Before: "abc", After: "def"},
+				},
+			},
+			ResourceDrifts: map[string]EntityDiff{},
+			Outputs: map[string]EntityDiff{
+				"url": {
+					".": {Before: "foo", After: "bar"},
+				},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		condition      string
+		expectedOutput bool
+		expectedError  error
+	}{
+		"changed": {
+			condition:      `changed(".image")`,
+			expectedOutput: true,
+		},
+		"not changed": {
+			condition:      `!changed(".task_role_arn")`,
+			expectedOutput: true,
+		},
+		"changed wildcard": {
+			condition:      `changed(".c?u")`,
+			expectedOutput: true,
+		},
+		"before and after compare": {
+			condition:      `before(".family") == after(".family") && after(".cpu") != "256"`,
+			expectedOutput: true,
+		},
+		"unchanged path is empty": {
+			condition:      `after(".memory") == ""`,
+			expectedOutput: true,
+		},
+		"resource changed": {
+			condition:      `resource_changed("aws_s3_object.*_code")`,
+			expectedOutput: true,
+		},
+		"resource changed at path": {
+			condition:      `resource_changed("aws_s3_object.x_code", ".key")`,
+			expectedOutput: false,
+		},
+		"drift and output changed": {
+			condition:      `drift_changed("*") || (output_changed("url") && true)`,
+			expectedOutput: true,
+		},
+		"invalid address pattern": {
+			condition:     `resource_changed("aws_\\")`,
+			expectedError: fmt.Errorf(`invalid resource_changed() pattern aws_\ caused by: pattern aws_\ ends with an unescaped backslash`),
+		},
+		"invalid path pattern": {
+			condition:     `changed(".image\\")`,
+			expectedError: fmt.Errorf(`invalid path pattern .image\ caused by: pattern .image\ ends with an unescaped backslash`),
+		},
+		"escaped address pattern": {
+			condition:      `resource_changed("aws_s3_object.x\_code") && !resource_changed("aws_s3_object.\*")`,
+			expectedOutput: true,
+		},
+		"unknown function": {
+			condition:     `foo()`,
+			expectedError: fmt.Errorf("unable to parse condition foo() caused by: unknown function foo()"),
+		},
+		"too many arguments": {
+			condition:     `changed(".image", ".cpu")`,
+			expectedError: fmt.Errorf(`unable to parse condition changed(".image", ".cpu") caused by: changed() takes 1 argument`),
+		},
+		"too few arguments": {
+			condition:     `resource_changed()`,
+			expectedError: fmt.Errorf(`unable to parse condition resource_changed() caused by: resource_changed() takes 1 or 2 arguments`),
+		},
+		"trailing comma": {
+			condition:     `changed(".image",)`,
+			expectedError: fmt.Errorf(`unable to parse condition changed(".image",) caused by: trailing , in changed()`),
+		},
+		"not a boolean": {
+			condition:     `after(".cpu")`,
+			expectedError: fmt.Errorf(`expected a boolean but got "512"`),
+		},
+		"syntax error": {
+			condition:     `changed(".image"`,
+			expectedError: fmt.Errorf(`unable to parse condition changed(".image" caused by: expected , or ) in changed()`),
+		},
+		"trailing tokens": {
+			condition:     `true false`,
+			expectedError: fmt.Errorf(`unable to parse condition true false caused by: unexpected false`),
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := evalCondition(tst.condition, env)
+
+			if tst.expectedError == nil {
+				assert.Nil(t, gotError)
+			} else {
+				assert.EqualError(t, gotError, tst.expectedError.Error())
+			}
+			assert.Equal(t, tst.expectedOutput, gotOut)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package plan
+
+import (
+	"sort"
+	"strings"
+
+	tfJson "github.com/hashicorp/terraform-json"
+)
+
+/*
+The dependency graph of the plan configuration. Nodes are configuration
+addresses without instance keys, e.g. module.app.aws_instance.this, as well
+as module variables (module.app.var.name), module outputs
+(module.app.output.name), module calls (module.app) and root outputs
+(output.name).
+*/
+type dependencyGraph struct {
+	// Node -> the nodes it depends on
+	dependencies map[string]map[string]bool
+}
+
+func (g *dependencyGraph) add(from, to string) {
+	if from == to {
+		return
+	}
+	if _, ok := g.dependencies[from]; !ok {
+		g.dependencies[from] = map[string]bool{}
+	}
+	g.dependencies[from][to] = true
+}
+
+/*
+Finds the nodes depending on the node directly or transitively.
+*/
+func (g *dependencyGraph) dependents(node string) map[string]bool {
+	reverse := map[string][]string{}
+	for from, tos := range g.dependencies {
+		for to := range tos {
+			reverse[to] = append(reverse[to], from)
+		}
+	}
+
+	out := map[string]bool{}
+	queue := []string{node}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range reverse[current] {
+			if dependent != node && !out[dependent] {
+				out[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	return out
+}
+
+/*
+Removes the instance keys from an address, e.g.
+module.app[0].aws_instance.this["a"] -> module.app.aws_instance.this.
+*/
+func stripInstanceKeys(address string) string {
+	var b strings.Builder
+	depth := 0
+	inQuote := false
+	for _, c := range address {
+		switch {
+		case inQuote:
+			if c == '"' {
+				inQuote = false
+			}
+		case c == '"' && depth > 0:
+			inQuote = true
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+/*
+Returns the configuration address of a resource change.
+*/
+func configAddress(rChange *tfJson.ResourceChange) string {
+	address := rChange.Type + "." + rChange.Name
+	if rChange.Mode == tfJson.DataResourceMode {
+		address = "data." + address
+	}
+	if rChange.ModuleAddress != "" {
+		address = stripInstanceKeys(rChange.ModuleAddress) + "." + address
+	}
+	return address
+}
+
+/*
+Collects the references of an expression including its nested blocks.
+*/
+func expressionReferences(e *tfJson.Expression) []string {
+	if e == nil || e.ExpressionData == nil {
+		return nil
+	}
+	refs := append([]string{}, e.References...)
+	for _, block := range e.NestedBlocks {
+		for _, nested := range block {
+			refs = append(refs, expressionReferences(nested)...)
+		}
+	}
+	return refs
+}
+
+func expressionsReferences(expressions map[string]*tfJson.Expression) []string {
+	refs := []string{}
+	for _, e := range expressions {
+		refs = append(refs, expressionReferences(e)...)
+	}
+	return refs
+}
+
+/*
+Resolves a reference made in the module with the prefix to the nodes it
+refers to. References to values the graph does not hold, e.g. locals or
+count.index, resolve to nothing.
+*/
+func resolveReference(prefix, ref string, m *tfJson.ConfigModule) []string {
+	parts := strings.Split(stripInstanceKeys(ref), ".")
+
+	switch parts[0] {
+	case "var":
+		if len(parts) > 1 {
+			return []string{prefix + "var." + parts[1]}
+		}
+	case "module":
+		if len(parts) > 2 {
+			return []string{prefix + "module." + parts[1] + ".output." + parts[2]}
+		}
+		if len(parts) == 2 && m != nil && m.ModuleCalls[parts[1]] != nil && m.ModuleCalls[parts[1]].Module != nil {
+			// The whole module is referenced
+			out := []string{}
+			for name := range m.ModuleCalls[parts[1]].Module.Outputs {
+				out = append(out, prefix+"module."+parts[1]+".output."+name)
+			}
+			return out
+		}
+	case "data":
+		if len(parts) > 2 {
+			return []string{prefix + "data." + parts[1] + "." + parts[2]}
+		}
+	case "local", "each", "count", "path", "terraform", "self":
+	default:
+		if len(parts) > 1 {
+			return []string{prefix + parts[0] + "." + parts[1]}
+		}
+	}
+	return nil
+}
+
+/*
+Builds the dependency graph from the configuration references and
+depends_on of the plan.
+*/
+func (p *Plan) dependencyGraph() *dependencyGraph {
+	g := &dependencyGraph{dependencies: map[string]map[string]bool{}}
+	if p.Config == nil || p.Config.RootModule == nil {
+		return g
+	}
+
+	var walk func(prefix string, m *tfJson.ConfigModule)
+	walk = func(prefix string, m *tfJson.ConfigModule) {
+		dependOn := func(node string, refs []string) {
+			for _, ref := range refs {
+				for _, to := range resolveReference(prefix, ref, m) {
+					g.add(node, to)
+				}
+			}
+		}
+
+		// Everything within a module depends on what the module call depends on
+		moduleCall := strings.TrimSuffix(prefix, ".")
+
+		for _, r := range m.Resources {
+			node := prefix + r.Address
+			dependOn(node, expressionsReferences(r.Expressions))
+			dependOn(node, expressionReferences(r.CountExpression))
+			dependOn(node, expressionReferences(r.ForEachExpression))
+			dependOn(node, r.DependsOn)
+			for _, provisioner := range r.Provisioners {
+				dependOn(node, expressionsReferences(provisioner.Expressions))
+			}
+			if moduleCall != "" {
+				g.add(node, moduleCall)
+			}
+		}
+
+		for name, o := range m.Outputs {
+			node := prefix + "output." + name
+			dependOn(node, expressionReferences(o.Expression))
+			dependOn(node, o.DependsOn)
+		}
+
+		for name, call := range m.ModuleCalls {
+			child := prefix + "module." + name
+			dependOn(child, expressionReferences(call.CountExpression))
+			dependOn(child, expressionReferences(call.ForEachExpression))
+			dependOn(child, call.DependsOn)
+			if moduleCall != "" {
+				g.add(child, moduleCall)
+			}
+
+			for variable, e := range call.Expressions {
+				dependOn(child+".var."+variable, expressionReferences(e))
+			}
+
+			if call.Module != nil {
+				walk(child+".", call.Module)
+			}
+		}
+	}
+	walk("", p.Config.RootModule)
+
+	return g
+}
+
+/*
+Finds the planned changes of the plan keyed by configuration address. Root
+outputs are keyed as output.name.
+*/
+func (p *Plan) plannedChanges() map[string][]string {
+	out := map[string][]string{}
+	for _, rChange := range p.ResourceChanges {
+		if rChange.Change == nil || actionName(rChange.Change.Actions) == "" {
+			continue
+		}
+		node := configAddress(rChange)
+		out[node] = append(out[node], rChange.Address)
+	}
+	for name, oChange := range p.OutputChanges {
+		if oChange == nil || actionName(oChange.Actions) == "" {
+			continue
+		}
+		out["output."+name] = append(out["output."+name], "output."+name)
+	}
+	for node := range out {
+		sort.Strings(out[node])
+	}
+	return out
+}
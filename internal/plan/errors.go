@@ -0,0 +1,84 @@
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var (
+	// The plan is not valid JSON or does not match the plan format.
+	ErrInvalidPlan = errors.New("invalid plan")
+	// The format version of the plan is not supported.
+	ErrUnsupportedFormatVersion = errors.New("unsupported plan format version")
+	// The filter is not valid JSON or holds an invalid rule, e.g. an
+	// unknown mode or a condition which does not parse.
+	ErrInvalidFilter = errors.New("invalid filter")
+	// A filter rule holds a pattern which cannot match as intended, e.g. a
+	// lone "!" or an empty path pattern in a comma separated list.
+	ErrInvalidPattern = errors.New("invalid filter pattern")
+	// A filter rule both includes and excludes the same pattern so the
+	// earlier of the two never applies. Reported by InspectFilter.Conflicts
+	// and as a lint warning, Inspect applies the rule with the later
+	// pattern winning.
+	ErrFilterConflict = errors.New("filter rule conflict")
+)
+
+// An error with where in the plan and filter it occurred. Matches its Kind
+// with errors.Is and is found in a chain of wrapped errors with errors.As.
+type Error struct {
+	// The sentinel the error is an instance of, e.g. ErrInvalidPattern.
+	Kind error
+	// What went wrong.
+	Message string
+	// Optional address of the resource or name of the output.
+	Address string
+	// Optional path of the attribute.
+	Path string
+	// Optional list of the filter rule. One of outputChanges,
+	// resourceChanges or driftChanges.
+	Filter string
+	// The index of the filter rule within its list. Only set when Filter is.
+	Rule int
+	// Optional underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + " caused by: " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	errs := []error{}
+	for _, err := range []error{e.Kind, e.Err} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (e *Error) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Kind    string `json:"kind,omitempty"`
+		Message string `json:"message"`
+		Address string `json:"address,omitempty"`
+		Path    string `json:"path,omitempty"`
+		Filter  string `json:"filter,omitempty"`
+		Rule    *int   `json:"rule,omitempty"`
+	}{
+		Message: e.Error(),
+		Address: e.Address,
+		Path:    e.Path,
+		Filter:  e.Filter,
+	}
+	if e.Kind != nil {
+		out.Kind = e.Kind.Error()
+	}
+	if e.Filter != "" {
+		out.Rule = &e.Rule
+	}
+	return json.Marshal(out)
+}
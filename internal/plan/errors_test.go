@@ -0,0 +1,178 @@
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Error(t *testing.T) {
+	cause := fmt.Errorf("unexpected end of condition")
+	err := fmt.Errorf("failed to apply filter caused by: %w", &Error{
+		Kind:    ErrInvalidFilter,
+		Message: "unable to evaluate condition",
+		Address: "aws_lambda_function.this",
+		Filter:  "resourceChanges",
+		Rule:    0,
+		Err:     cause,
+	})
+
+	assert.EqualError(t, err, "failed to apply filter caused by: unable to evaluate condition caused by: unexpected end of condition")
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+	assert.ErrorIs(t, err, cause)
+	assert.False(t, errors.Is(err, ErrInvalidPattern))
+
+	planErr := &Error{}
+	assert.True(t, errors.As(err, &planErr))
+	assert.Equal(t, "aws_lambda_function.this", planErr.Address)
+
+	bytes, jsonErr := json.Marshal(planErr)
+	assert.Nil(t, jsonErr)
+	assert.JSONEq(t, `{
+		"kind": "invalid filter",
+		"message": "unable to evaluate condition caused by: unexpected end of condition",
+		"address": "aws_lambda_function.this",
+		"filter": "resourceChanges",
+		"rule": 0
+	}`, string(bytes))
+
+	bytes, jsonErr = json.Marshal(&Error{Kind: ErrInvalidPlan, Message: "unable to unmarshal terraform plan"})
+	assert.Nil(t, jsonErr)
+	assert.JSONEq(t, `{"kind": "invalid plan", "message": "unable to unmarshal terraform plan"}`, string(bytes))
+}
+
+func Test_InspectErrors(t *testing.T) {
+	p, err := ParsePlan([]byte(`{
+		"resource_changes": [
+			{
+				"address": "aws_ecs_task_definition.this",
+				"mode": "managed",
+				"type": "aws_ecs_task_definition",
+				"name": "this",
+				"change": {"actions": ["update"], "before": {"cpu": "256"}, "after": {"cpu": "512"}}
+			}
+		]
+	}`))
+	assert.Nil(t, err)
+
+	cases := map[string]struct {
+		filter        *InspectFilter
+		expectedError *Error
+	}{
+		"invalid pattern": {
+			filter: &InspectFilter{
+				OutputChanges: []Filter{{NamePattern: Patterns{"*"}}},
+				DriftChanges: []Filter{
+					{NamePattern: Patterns{"*"}},
+					{NamePattern: Patterns{"*"}, DiffPatterns: map[string][]DiffPattern{".cpu": {{Before: Patterns{"*"}, After: Patterns{"!"}}}}},
+				},
+			},
+			expectedError: &Error{Kind: ErrInvalidPattern, Message: "pattern ! in diffPatterns[.cpu].after excludes nothing", Filter: "driftChanges", Rule: 1},
+		},
+		"empty path pattern": {
+			filter: &InspectFilter{
+				ResourceChanges: []Filter{{NamePattern: Patterns{"*"}, DiffPatterns: map[string][]DiffPattern{".cpu,": {{Before: Patterns{"*"}, After: Patterns{"*"}}}}}},
+			},
+			expectedError: &Error{Kind: ErrInvalidPattern, Message: "empty path pattern in diffPatterns[.cpu,]", Filter: "resourceChanges", Rule: 0},
+		},
+		"unknown mode": {
+			filter: &InspectFilter{
+				OutputChanges: []Filter{{NamePattern: Patterns{"*"}, Mode: "any"}},
+			},
+			expectedError: &Error{Kind: ErrInvalidFilter, Message: "unknown filter mode any", Filter: "outputChanges", Rule: 0},
+		},
+		"condition not a boolean": {
+			filter: &InspectFilter{
+				ResourceChanges: []Filter{
+					{NamePattern: Patterns{"aws_s3_*"}, Condition: `after(".cpu")`},
+					{NamePattern: Patterns{"aws_ecs_*"}, Condition: `after(".cpu")`},
+				},
+			},
+			expectedError: &Error{Kind: ErrInvalidFilter, Message: "unable to evaluate condition", Address: "aws_ecs_task_definition.this", Filter: "resourceChanges", Rule: 1},
+		},
+		"unknown condition function on an unmatched rule": {
+			filter: &InspectFilter{
+				ResourceChanges: []Filter{
+					{NamePattern: Patterns{"aws_s3_*"}, Condition: `modified(".cpu")`},
+				},
+			},
+			expectedError: &Error{Kind: ErrInvalidFilter, Message: "invalid condition", Filter: "resourceChanges", Rule: 0},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, gotError := p.Inspect(&InspectInput{Filter: tst.filter})
+
+			assert.ErrorIs(t, gotError, tst.expectedError.Kind)
+			planErr := &Error{}
+			if assert.True(t, errors.As(gotError, &planErr)) {
+				assert.Equal(t, tst.expectedError.Message, planErr.Message)
+				assert.Equal(t, tst.expectedError.Address, planErr.Address)
+				assert.Equal(t, tst.expectedError.Path, planErr.Path)
+				assert.Equal(t, tst.expectedError.Filter, planErr.Filter)
+				assert.Equal(t, tst.expectedError.Rule, planErr.Rule)
+			}
+		})
+	}
+}
+
+func Test_InspectConflictingPatterns(t *testing.T) {
+	p, err := ParsePlan([]byte(`{
+		"resource_changes": [
+			{
+				"address": "aws_ecs_task_definition.this",
+				"mode": "managed",
+				"type": "aws_ecs_task_definition",
+				"name": "this",
+				"change": {"actions": ["update"], "before": {"cpu": "256"}, "after": {"cpu": "512"}}
+			}
+		]
+	}`))
+	assert.Nil(t, err)
+
+	// Conflicting patterns are only reported by Lint and Conflicts, the
+	// later one wins
+	out, err := p.Inspect(&InspectInput{Filter: &InspectFilter{
+		ResourceChanges: []Filter{{NamePattern: Patterns{"aws_ecs_*", "!aws_ecs_*"}, DiffPatterns: map[string][]DiffPattern{".cpu": {{Before: Patterns{"*"}, After: Patterns{"*"}}}}}},
+	}})
+	assert.Nil(t, err)
+	assert.Contains(t, out.Diff.Resources, "aws_ecs_task_definition.this")
+
+	out, err = p.Inspect(&InspectInput{Filter: &InspectFilter{
+		ResourceChanges: []Filter{{NamePattern: Patterns{"!aws_ecs_*", "aws_ecs_*"}, DiffPatterns: map[string][]DiffPattern{".cpu": {{Before: Patterns{"*"}, After: Patterns{"*"}}}}}},
+	}})
+	assert.Nil(t, err)
+	assert.True(t, out.IsEmpty())
+}
+
+func Test_FilterConflicts(t *testing.T) {
+	filter := &InspectFilter{
+		OutputChanges: []Filter{{NamePattern: Patterns{"*"}}},
+		ResourceChanges: []Filter{
+			{NamePattern: Patterns{"!aws_ecs_*", "aws_*", "aws_ecs_*"}},
+			{
+				NamePattern:  Patterns{"aws_*"},
+				DiffPatterns: map[string][]DiffPattern{".cpu": {{Before: Patterns{"*"}, After: Patterns{"1", "!1"}}}},
+			},
+		},
+	}
+
+	err := filter.Conflicts()
+	assert.ErrorIs(t, err, ErrFilterConflict)
+	assert.EqualError(t, err, "pattern aws_ecs_* in namePattern is both included and excluded\npattern 1 in diffPatterns[.cpu].after is both included and excluded")
+
+	planErr := &Error{}
+	if assert.True(t, errors.As(err, &planErr)) {
+		assert.Equal(t, "pattern aws_ecs_* in namePattern is both included and excluded", planErr.Message)
+		assert.Equal(t, "resourceChanges", planErr.Filter)
+		assert.Equal(t, 0, planErr.Rule)
+	}
+
+	assert.Nil(t, (&InspectFilter{ResourceChanges: []Filter{{NamePattern: Patterns{"aws_*", "!aws_ecs_*"}}}}).Conflicts())
+	assert.Nil(t, (*InspectFilter)(nil).Conflicts())
+}
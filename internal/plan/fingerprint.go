@@ -0,0 +1,104 @@
+package plan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// The version of the fingerprint content, changed whenever the same plan
+// would produce a different fingerprint.
+const FingerprintVersion = 1
+
+// The semantic change set covered by a fingerprint.
+type fingerprintContent struct {
+	Version         int                   `json:"version"`
+	Resources       map[string]EntityDiff `json:"resources"`
+	ResourceActions map[string][]string   `json:"resourceActions"`
+	Outputs         map[string]EntityDiff `json:"outputs"`
+}
+
+type FingerprintInput struct {
+	// Optional filter to apply to the plan for the remaining fingerprint.
+	Filter *InspectFilter
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string
+	// Optional wildcard-supported patterns of volatile paths, e.g.
+	// ".tags.LastModified", to leave out of the fingerprints.
+	IgnorePaths Patterns
+	// Optional wildcard-supported patterns of list paths whose elements are
+	// fingerprinted regardless of order.
+	UnorderedLists Patterns
+	// Optional context which stops the operation early when it ends.
+	Context context.Context
+}
+
+// Result of calling Fingerprint() to fingerprint a plan.
+type FingerprintOutput struct {
+	// The sha256 fingerprint of every change of the plan.
+	All string `json:"all"`
+	// The sha256 fingerprint of the changes remaining after the filter.
+	Remaining string `json:"remaining"`
+}
+
+/*
+Computes the fingerprint of the resource changes and output changes of the
+inspected plan. Drift is left out as it does not change what is applied.
+*/
+func fingerprint(out *InspectOutput, opts *CompareOptions) (string, error) {
+	resources, _, _, err := opts.prepare("resource", out.Diff.Resources, map[string]EntityDiff{})
+	if err != nil {
+		return "", fmt.Errorf("unable to prepare resources caused by: %w", err)
+	}
+	outputs, _, _, err := opts.prepare("output", out.Diff.Outputs, map[string]EntityDiff{})
+	if err != nil {
+		return "", fmt.Errorf("unable to prepare outputs caused by: %w", err)
+	}
+
+	actions := map[string][]string{}
+	for address := range resources {
+		actions[address] = out.Diff.ResourceActions[address]
+	}
+
+	// Maps are marshalled with sorted keys so their order does not matter
+	bytes, err := json.Marshal(&fingerprintContent{
+		Version:         FingerprintVersion,
+		Resources:       resources,
+		ResourceActions: actions,
+		Outputs:         outputs,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal fingerprint content caused by: %w", err)
+	}
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+/*
+Computes stable fingerprints of the semantic change set of the plan, with
+and without the filter applied. Plans with the same changes have the same
+fingerprints regardless of ordering, drift or changes to ignored paths.
+*/
+func (p *Plan) Fingerprint(params *FingerprintInput) (*FingerprintOutput, error) {
+	opts := &CompareOptions{IgnorePaths: params.IgnorePaths, UnorderedLists: params.UnorderedLists}
+
+	all, err := p.Inspect(&InspectInput{Context: params.Context})
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := p.Inspect(&InspectInput{Filter: params.Filter, Set: params.Set, Context: params.Context})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &FingerprintOutput{}
+	if out.All, err = fingerprint(all, opts); err != nil {
+		return nil, err
+	}
+	if out.Remaining, err = fingerprint(remaining, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,125 @@
+package plan
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fingerprintPlan = `{
+	"format_version": "1.2",
+	"resource_changes": [
+		{
+			"address": "aws_security_group.this",
+			"mode": "managed",
+			"type": "aws_security_group",
+			"name": "this",
+			"change": {
+				"actions": ["update"],
+				"before": {"cidr_blocks": ["10.0.0.0/8"], "tags": {"Name": "sg", "LastModified": "2024-01-01"}},
+				"after": {"cidr_blocks": ["10.0.0.0/8", "192.168.0.0/16", "172.16.0.0/12"], "tags": {"Name": "sg", "LastModified": "2024-01-02"}}
+			}
+		},
+		{
+			"address": "aws_lambda_function.this",
+			"mode": "managed",
+			"type": "aws_lambda_function",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"source_code_hash": "a"}, "after": {"source_code_hash": "b"}}
+		}
+	],
+	"resource_drift": [
+		{
+			"address": "aws_security_group.this",
+			"mode": "managed",
+			"type": "aws_security_group",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"description": "a"}, "after": {"description": "b"}}
+		}
+	],
+	"output_changes": {
+		"sg_id": {"actions": ["update"], "before": "a", "after": "b"}
+	}
+}`
+
+func Test_Fingerprint(t *testing.T) {
+	input := &FingerprintInput{
+		Filter: &InspectFilter{
+			ResourceChanges: []Filter{
+				{
+					NamePattern:  Patterns{"aws_lambda_function.*"},
+					DiffPatterns: map[string][]DiffPattern{".source_code_hash": {{Before: Patterns{"*"}, After: Patterns{"*"}}}},
+				},
+			},
+		},
+		IgnorePaths:    Patterns{".tags.LastModified"},
+		UnorderedLists: Patterns{".cidr_blocks"},
+	}
+
+	fingerprint := func(t *testing.T, plan string) *FingerprintOutput {
+		p := &Plan{}
+		assert.Nil(t, json.Unmarshal([]byte(plan), p))
+		out, err := p.Fingerprint(input)
+		assert.Nil(t, err)
+		return out
+	}
+
+	base := fingerprint(t, fingerprintPlan)
+	assert.Len(t, base.All, 64)
+	assert.NotEqual(t, base.All, base.Remaining)
+
+	cases := map[string]struct {
+		plan              string
+		expectedAll       bool
+		expectedRemaining bool
+	}{
+		"volatile path changed": {
+			plan:              strings.Replace(fingerprintPlan, `"LastModified": "2024-01-02"`, `"LastModified": "2024-02-03"`, 1),
+			expectedAll:       true,
+			expectedRemaining: true,
+		},
+		"unordered list reordered": {
+			plan:              strings.Replace(fingerprintPlan, `["10.0.0.0/8", "192.168.0.0/16", "172.16.0.0/12"]`, `["10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"]`, 1),
+			expectedAll:       true,
+			expectedRemaining: true,
+		},
+		"drift changed": {
+			plan:              strings.Replace(fingerprintPlan, `"after": {"description": "b"}`, `"after": {"description": "c"}`, 1),
+			expectedAll:       true,
+			expectedRemaining: true,
+		},
+		"filtered change changed": {
+			plan:              strings.Replace(fingerprintPlan, `"after": {"source_code_hash": "b"}`, `"after": {"source_code_hash": "c"}`, 1),
+			expectedAll:       false,
+			expectedRemaining: true,
+		},
+		"un-filtered change changed": {
+			plan:              strings.Replace(fingerprintPlan, `"192.168.0.0/16"`, `"192.168.1.0/24"`, 1),
+			expectedAll:       false,
+			expectedRemaining: false,
+		},
+		"action changed": {
+			plan:              strings.Replace(fingerprintPlan, `"actions": ["update"],`, `"actions": ["delete", "create"],`, 1),
+			expectedAll:       false,
+			expectedRemaining: false,
+		},
+		"output changed": {
+			plan:              strings.Replace(fingerprintPlan, `"before": "a", "after": "b"`, `"before": "a", "after": "c"`, 1),
+			expectedAll:       false,
+			expectedRemaining: false,
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.NotEqual(t, fingerprintPlan, tst.plan)
+			out := fingerprint(t, tst.plan)
+
+			assert.Equal(t, tst.expectedAll, out.All == base.All)
+			assert.Equal(t, tst.expectedRemaining, out.Remaining == base.Remaining)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Render the graph in the Graphviz DOT language.
+	GraphFormatDOT = "dot"
+	// Render the graph as a Mermaid flowchart.
+	GraphFormatMermaid = "mermaid"
+	// Render the graph as JSON.
+	GraphFormatJSON = "json"
+
+	// Action of a resource moved to a new address without other changes.
+	graphActionMove = "move"
+)
+
+// Fill colours of the graph nodes by action.
+var graphActionColors = map[string]string{
+	"create":        "#c3e6cb",
+	"update":        "#ffeeba",
+	"replace":       "#d6c8f0",
+	"delete":        "#f5c6cb",
+	"read":          "#e2e3e5",
+	graphActionMove: "#bee5eb",
+}
+
+func graphActionColor(action string) string {
+	if color, ok := graphActionColors[action]; ok {
+		return color
+	}
+	return "#ffffff"
+}
+
+// A planned change in the graph.
+type GraphNode struct {
+	// The address of the resource or output.name of a root output.
+	ID string `json:"id"`
+	// The kind of entity. Either resource or output.
+	Kind string `json:"kind"`
+	// The module address of the resource. Empty for the root module.
+	Module string `json:"module"`
+	// The planned action, e.g. create, update, replace, delete or move.
+	Action string `json:"action"`
+}
+
+// A dependency between two planned changes.
+type GraphEdge struct {
+	// The ID of the change depended on.
+	Source string `json:"source"`
+	// The ID of the change depending on the source.
+	Target string `json:"target"`
+}
+
+// Result of calling Graph() to build the graph of a plan's changes.
+type GraphOutput struct {
+	// The planned changes sorted by ID.
+	Nodes []GraphNode `json:"nodes"`
+	// The dependencies between the planned changes.
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphInput struct {
+	// Optional filter to apply to the plan.
+	Filter *InspectFilter
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string
+	// Only include the changes not filtered out by the filter. Edges
+	// through filtered out changes are kept.
+	UnfilteredOnly bool
+	// Optional context which stops the operation early when it ends.
+	Context context.Context
+}
+
+/*
+Builds the graph of the planned changes of the plan with edges taken from
+the configuration references and depends_on. Changes depending on each
+other through values that are not changed, e.g. module variables, are
+connected directly.
+*/
+func (p *Plan) Graph(params *GraphInput) (*GraphOutput, error) {
+	inspect, err := p.Inspect(&InspectInput{Filter: params.Filter, Set: params.Set, Context: params.Context})
+	if err != nil {
+		return nil, err
+	}
+
+	// Configuration address -> the planned changes
+	nodes := map[string][]GraphNode{}
+
+	for _, rChange := range p.ResourceChanges {
+		if rChange.Change == nil {
+			continue
+		}
+		action := actionName(rChange.Change.Actions)
+		if action == "" && rChange.PreviousAddress != "" && rChange.PreviousAddress != rChange.Address {
+			action = graphActionMove
+		}
+		if action == "" {
+			continue
+		}
+		if _, ok := inspect.Diff.Resources[rChange.Address]; params.UnfilteredOnly && !ok {
+			continue
+		}
+
+		node := configAddress(rChange)
+		nodes[node] = append(nodes[node], GraphNode{ID: rChange.Address, Kind: "resource", Module: rChange.ModuleAddress, Action: action})
+	}
+
+	for name, oChange := range p.OutputChanges {
+		if oChange == nil {
+			continue
+		}
+		action := actionName(oChange.Actions)
+		if action == "" {
+			continue
+		}
+		if _, ok := inspect.Diff.Outputs[name]; params.UnfilteredOnly && !ok {
+			continue
+		}
+
+		nodes["output."+name] = append(nodes["output."+name], GraphNode{ID: "output." + name, Kind: "output", Action: action})
+	}
+
+	graph := p.dependencyGraph()
+	out := &GraphOutput{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+
+	for node, changes := range nodes {
+		out.Nodes = append(out.Nodes, changes...)
+
+		// Walk the dependencies until reaching another planned change
+		visited := map[string]bool{node: true}
+		queue := []string{node}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for dependency := range graph.dependencies[current] {
+				if visited[dependency] {
+					continue
+				}
+				visited[dependency] = true
+
+				if _, ok := nodes[dependency]; !ok {
+					queue = append(queue, dependency)
+					continue
+				}
+				for _, source := range nodes[dependency] {
+					for _, target := range changes {
+						out.Edges = append(out.Edges, GraphEdge{Source: source.ID, Target: target.ID})
+					}
+				}
+			}
+		}
+	}
+
+	sort.Slice(out.Nodes, func(i, j int) bool {
+		return out.Nodes[i].ID < out.Nodes[j].ID
+	})
+	sort.Slice(out.Edges, func(i, j int) bool {
+		if out.Edges[i].Source != out.Edges[j].Source {
+			return out.Edges[i].Source < out.Edges[j].Source
+		}
+		return out.Edges[i].Target < out.Edges[j].Target
+	})
+
+	return out, nil
+}
+
+/*
+Groups the nodes by module keeping the module order stable. The root
+module is keyed by an empty string.
+*/
+func (g *GraphOutput) modules() ([]string, map[string][]GraphNode) {
+	byModule := map[string][]GraphNode{}
+	for _, node := range g.Nodes {
+		byModule[node.Module] = append(byModule[node.Module], node)
+	}
+	return sortedKeys(byModule), byModule
+}
+
+/*
+Produces the lines of the graph in the Graphviz DOT language.
+*/
+func (g *GraphOutput) DOT() []string {
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	nodeLine := func(indent string, node GraphNode) string {
+		return fmt.Sprintf("%s%s [fillcolor=%s, tooltip=%s];\n", indent, quote(node.ID), quote(graphActionColor(node.Action)), quote(node.Action))
+	}
+
+	var out []string
+	out = append(out, "digraph plan {\n")
+	out = append(out, "  rankdir=LR;\n")
+	out = append(out, "  node [shape=box, style=filled];\n")
+
+	modules, byModule := g.modules()
+	for _, module := range modules {
+		if module == "" {
+			for _, node := range byModule[module] {
+				out = append(out, nodeLine("  ", node))
+			}
+			continue
+		}
+
+		out = append(out, fmt.Sprintf("  subgraph %s {\n", quote("cluster_"+module)))
+		out = append(out, fmt.Sprintf("    label=%s;\n", quote(module)))
+		for _, node := range byModule[module] {
+			out = append(out, nodeLine("    ", node))
+		}
+		out = append(out, "  }\n")
+	}
+
+	for _, edge := range g.Edges {
+		out = append(out, fmt.Sprintf("  %s -> %s;\n", quote(edge.Source), quote(edge.Target)))
+	}
+	out = append(out, "}\n")
+	return out
+}
+
+/*
+Produces the lines of the graph as a Mermaid flowchart.
+*/
+func (g *GraphOutput) Mermaid() []string {
+	// Mermaid IDs can't hold the characters of addresses
+	ids := map[string]string{}
+	for i, node := range g.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%v", i)
+	}
+	label := func(s string) string {
+		return `["` + strings.ReplaceAll(s, `"`, "#quot;") + `"]`
+	}
+
+	var out []string
+	out = append(out, "flowchart LR\n")
+
+	modules, byModule := g.modules()
+	for i, module := range modules {
+		indent := "  "
+		if module != "" {
+			out = append(out, fmt.Sprintf("  subgraph m%v%s\n", i, label(module)))
+			indent = "    "
+		}
+		for _, node := range byModule[module] {
+			out = append(out, fmt.Sprintf("%s%s%s\n", indent, ids[node.ID], label(node.ID)))
+		}
+		if module != "" {
+			out = append(out, "  end\n")
+		}
+	}
+
+	for _, edge := range g.Edges {
+		out = append(out, fmt.Sprintf("  %s --> %s\n", ids[edge.Source], ids[edge.Target]))
+	}
+
+	for _, action := range sortedKeys(graphActionColors) {
+		out = append(out, fmt.Sprintf("  classDef %s fill:%s\n", action, graphActionColors[action]))
+	}
+	for _, node := range g.Nodes {
+		if _, ok := graphActionColors[node.Action]; ok {
+			out = append(out, fmt.Sprintf("  class %s %s\n", ids[node.ID], node.Action))
+		}
+	}
+	return out
+}
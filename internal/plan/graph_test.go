@@ -0,0 +1,116 @@
+package plan
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Graph(t *testing.T) {
+	p := &Plan{}
+	assert.Nil(t, json.Unmarshal([]byte(impactPlan), p))
+
+	cases := map[string]struct {
+		input          *GraphInput
+		expectedOutput *GraphOutput
+	}{
+		"all changes": {
+			input: &GraphInput{},
+			expectedOutput: &GraphOutput{
+				Nodes: []GraphNode{
+					{ID: "aws_s3_bucket.logs", Kind: "resource", Action: "update"},
+					{ID: "aws_subnet.a[0]", Kind: "resource", Action: "replace"},
+					{ID: "aws_subnet.a[1]", Kind: "resource", Action: "replace"},
+					{ID: "aws_vpc.main", Kind: "resource", Action: "replace"},
+					{ID: "module.app.aws_instance.this", Kind: "resource", Module: "module.app", Action: "update"},
+					{ID: "output.instance_id", Kind: "output", Action: "update"},
+				},
+				Edges: []GraphEdge{
+					{Source: "aws_subnet.a[0]", Target: "module.app.aws_instance.this"},
+					{Source: "aws_subnet.a[1]", Target: "module.app.aws_instance.this"},
+					{Source: "aws_vpc.main", Target: "aws_subnet.a[0]"},
+					{Source: "aws_vpc.main", Target: "aws_subnet.a[1]"},
+					{Source: "module.app.aws_instance.this", Target: "output.instance_id"},
+				},
+			},
+		},
+		"unfiltered only": {
+			input: &GraphInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern:  Patterns{"aws_subnet.*", "aws_s3_bucket.*"},
+							DiffPatterns: map[string][]DiffPattern{"*": {{Before: Patterns{"*"}, After: Patterns{"*"}}}},
+						},
+					},
+				},
+				UnfilteredOnly: true,
+			},
+			expectedOutput: &GraphOutput{
+				Nodes: []GraphNode{
+					{ID: "aws_vpc.main", Kind: "resource", Action: "replace"},
+					{ID: "module.app.aws_instance.this", Kind: "resource", Module: "module.app", Action: "update"},
+					{ID: "output.instance_id", Kind: "output", Action: "update"},
+				},
+				Edges: []GraphEdge{
+					{Source: "aws_vpc.main", Target: "module.app.aws_instance.this"},
+					{Source: "module.app.aws_instance.this", Target: "output.instance_id"},
+				},
+			},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := p.Graph(tst.input)
+
+			assert.Nil(t, gotError)
+			diff.Check(t, tst.expectedOutput, gotOut)
+		})
+	}
+}
+
+func Test_GraphRender(t *testing.T) {
+	g := &GraphOutput{
+		Nodes: []GraphNode{
+			{ID: "aws_vpc.main", Kind: "resource", Action: "replace"},
+			{ID: `module.app["a"].aws_instance.this`, Kind: "resource", Module: `module.app["a"]`, Action: "move"},
+		},
+		Edges: []GraphEdge{
+			{Source: "aws_vpc.main", Target: `module.app["a"].aws_instance.this`},
+		},
+	}
+
+	diff.Check(t, []string{
+		"digraph plan {\n",
+		"  rankdir=LR;\n",
+		"  node [shape=box, style=filled];\n",
+		"  \"aws_vpc.main\" [fillcolor=\"#d6c8f0\", tooltip=\"replace\"];\n",
+		"  subgraph \"cluster_module.app[\\\"a\\\"]\" {\n",
+		"    label=\"module.app[\\\"a\\\"]\";\n",
+		"    \"module.app[\\\"a\\\"].aws_instance.this\" [fillcolor=\"#bee5eb\", tooltip=\"move\"];\n",
+		"  }\n",
+		"  \"aws_vpc.main\" -> \"module.app[\\\"a\\\"].aws_instance.this\";\n",
+		"}\n",
+	}, g.DOT())
+
+	diff.Check(t, []string{
+		"flowchart LR\n",
+		"  n0[\"aws_vpc.main\"]\n",
+		"  subgraph m1[\"module.app[#quot;a#quot;]\"]\n",
+		"    n1[\"module.app[#quot;a#quot;].aws_instance.this\"]\n",
+		"  end\n",
+		"  n0 --> n1\n",
+		"  classDef create fill:#c3e6cb\n",
+		"  classDef delete fill:#f5c6cb\n",
+		"  classDef move fill:#bee5eb\n",
+		"  classDef read fill:#e2e3e5\n",
+		"  classDef replace fill:#d6c8f0\n",
+		"  classDef update fill:#ffeeba\n",
+		"  class n0 replace\n",
+		"  class n1 move\n",
+	}, g.Mermaid())
+}
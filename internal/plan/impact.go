@@ -0,0 +1,116 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/vodkaslime/wildcard"
+)
+
+// The downstream impact of a planned resource change.
+type ImpactEntry struct {
+	// The address of the changed resource.
+	Address string `json:"address"`
+	// The other planned changes depending on the resource directly or
+	// transitively through references and depends_on. Root outputs are
+	// listed as output.name.
+	Dependents []string `json:"dependents"`
+}
+
+// Result of calling Impact() to analyse the blast radius of a plan.
+type ImpactOutput struct {
+	// The un-filtered resource changes ranked by their number of dependents.
+	Impacts []ImpactEntry `json:"impacts"`
+}
+
+type ImpactInput struct {
+	// Optional filter to apply to the plan. Only un-filtered resource
+	// changes are analysed.
+	Filter *InspectFilter
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string
+	// Optional wildcard-supported patterns of the resource addresses to
+	// analyse. Defaults to all un-filtered resource changes.
+	Addresses Patterns
+	// Optional context which stops the operation early when it ends.
+	Context context.Context
+}
+
+/*
+Checks if an ImpactOutput is empty
+*/
+func (i *ImpactOutput) IsEmpty() bool {
+	for _, impact := range i.Impacts {
+		if len(impact.Dependents) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Finds the planned changes depending on each un-filtered resource change by
+walking the configuration references and depends_on of the plan. The
+changes are ranked by how many downstream changes they cause.
+*/
+func (p *Plan) Impact(params *ImpactInput) (*ImpactOutput, error) {
+	inspect, err := p.Inspect(&InspectInput{Filter: params.Filter, Set: params.Set, Context: params.Context})
+	if err != nil {
+		return nil, err
+	}
+
+	graph := p.dependencyGraph()
+	planned := p.plannedChanges()
+	m := wildcard.NewMatcher()
+
+	out := &ImpactOutput{Impacts: []ImpactEntry{}}
+	for _, rChange := range p.ResourceChanges {
+		if _, ok := inspect.Diff.Resources[rChange.Address]; !ok {
+			continue
+		}
+		if len(params.Addresses) > 0 {
+			if match, err := params.Addresses.match(m, rChange.Address); err != nil {
+				return nil, fmt.Errorf("unable to match %s with address patterns caused by: %w", rChange.Address, err)
+			} else if !match {
+				continue
+			}
+		}
+
+		dependents := []string{}
+		for node := range graph.dependents(configAddress(rChange)) {
+			dependents = append(dependents, planned[node]...)
+		}
+		sort.Strings(dependents)
+
+		out.Impacts = append(out.Impacts, ImpactEntry{Address: rChange.Address, Dependents: dependents})
+	}
+
+	sort.SliceStable(out.Impacts, func(i, j int) bool {
+		if len(out.Impacts[i].Dependents) != len(out.Impacts[j].Dependents) {
+			return len(out.Impacts[i].Dependents) > len(out.Impacts[j].Dependents)
+		}
+		return out.Impacts[i].Address < out.Impacts[j].Address
+	})
+
+	return out, nil
+}
+
+/*
+Produces a slice of strings output which can be printed line by line
+to get a stdout report of the impact.
+*/
+func (i *ImpactOutput) Pretty() []string {
+	var out []string
+	out = append(out, "\tPlanned changes ranked by the downstream changes they cause:\n")
+
+	for _, impact := range i.Impacts {
+		out = append(out, fmt.Sprintf("\n\t\tresource %s\"%s\"%s affects %v changes:\n", colorBold, impact.Address, colorNone, len(impact.Dependents)))
+		for _, dependent := range impact.Dependents {
+			out = append(out, fmt.Sprintf("\t\t\t%s\n", dependent))
+		}
+	}
+
+	out = append(out, fmt.Sprintf("\n\tAnalysed: %v resources\n", len(i.Impacts)))
+	return out
+}
@@ -0,0 +1,135 @@
+package plan
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+const impactPlan = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main",
+     "change": {"actions": ["delete", "create"], "before": {"cidr_block": "10.0.0.0/16"}, "after": {"cidr_block": "10.1.0.0/16"}}},
+    {"address": "aws_subnet.a[0]", "mode": "managed", "type": "aws_subnet", "name": "a",
+     "change": {"actions": ["delete", "create"], "before": {"vpc_id": "vpc-1"}, "after": {"vpc_id": "vpc-2"}}},
+    {"address": "aws_subnet.a[1]", "mode": "managed", "type": "aws_subnet", "name": "a",
+     "change": {"actions": ["delete", "create"], "before": {"vpc_id": "vpc-1"}, "after": {"vpc_id": "vpc-2"}}},
+    {"address": "module.app.aws_instance.this", "module_address": "module.app", "mode": "managed", "type": "aws_instance", "name": "this",
+     "change": {"actions": ["update"], "before": {"subnet_id": "subnet-1"}, "after": {"subnet_id": "subnet-2"}}},
+    {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "name": "logs",
+     "change": {"actions": ["update"], "before": {"tags": {"a": "1"}}, "after": {"tags": {"a": "2"}}}},
+    {"address": "aws_iam_role.this", "mode": "managed", "type": "aws_iam_role", "name": "this",
+     "change": {"actions": ["no-op"], "before": {"name": "a"}, "after": {"name": "a"}}}
+  ],
+  "output_changes": {
+    "instance_id": {"actions": ["update"], "before": "i-1", "after": "i-2"}
+  },
+  "configuration": {
+    "root_module": {
+      "outputs": {
+        "instance_id": {"expression": {"references": ["module.app.instance_id", "module.app"]}}
+      },
+      "resources": [
+        {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main",
+         "expressions": {"cidr_block": {"references": ["var.cidr"]}}},
+        {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a",
+         "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}},
+        {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "name": "logs"},
+        {"address": "aws_iam_role.this", "mode": "managed", "type": "aws_iam_role", "name": "this",
+         "depends_on": ["aws_vpc.main"]}
+      ],
+      "module_calls": {
+        "app": {
+          "source": "./app",
+          "expressions": {"subnet_id": {"references": ["aws_subnet.a[0].id", "aws_subnet.a[0]", "aws_subnet.a"]}},
+          "module": {
+            "outputs": {
+              "instance_id": {"expression": {"references": ["aws_instance.this.id", "aws_instance.this"]}}
+            },
+            "resources": [
+              {"address": "aws_instance.this", "mode": "managed", "type": "aws_instance", "name": "this",
+               "expressions": {"network_interface": [{"subnet_id": {"references": ["var.subnet_id"]}}]}}
+            ]
+          }
+        }
+      }
+    }
+  }
+}`
+
+func Test_Impact(t *testing.T) {
+	p := &Plan{}
+	assert.Nil(t, json.Unmarshal([]byte(impactPlan), p))
+
+	cases := map[string]struct {
+		input          *ImpactInput
+		expectedOutput *ImpactOutput
+	}{
+		"all changes": {
+			input: &ImpactInput{},
+			expectedOutput: &ImpactOutput{
+				Impacts: []ImpactEntry{
+					{Address: "aws_vpc.main", Dependents: []string{"aws_subnet.a[0]", "aws_subnet.a[1]", "module.app.aws_instance.this", "output.instance_id"}},
+					{Address: "aws_subnet.a[0]", Dependents: []string{"module.app.aws_instance.this", "output.instance_id"}},
+					{Address: "aws_subnet.a[1]", Dependents: []string{"module.app.aws_instance.this", "output.instance_id"}},
+					{Address: "module.app.aws_instance.this", Dependents: []string{"output.instance_id"}},
+					{Address: "aws_s3_bucket.logs", Dependents: []string{}},
+				},
+			},
+		},
+		"filtered and addressed": {
+			input: &ImpactInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern:  Patterns{"aws_subnet.*"},
+							DiffPatterns: map[string][]DiffPattern{".vpc_id": {{Before: Patterns{"*"}, After: Patterns{"*"}}}},
+						},
+					},
+				},
+				Addresses: Patterns{"aws_*", "!aws_s3_bucket.*"},
+			},
+			expectedOutput: &ImpactOutput{
+				Impacts: []ImpactEntry{
+					{Address: "aws_vpc.main", Dependents: []string{"aws_subnet.a[0]", "aws_subnet.a[1]", "module.app.aws_instance.this", "output.instance_id"}},
+				},
+			},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := p.Impact(tst.input)
+
+			assert.Nil(t, gotError)
+			diff.Check(t, tst.expectedOutput, gotOut)
+		})
+	}
+}
+
+func Test_stripInstanceKeys(t *testing.T) {
+	cases := map[string]struct {
+		address        string
+		expectedOutput string
+	}{
+		"no keys": {
+			address:        "aws_instance.this",
+			expectedOutput: "aws_instance.this",
+		},
+		"count and for_each keys": {
+			address:        `module.app[0].aws_instance.this["a.]b"]`,
+			expectedOutput: "module.app.aws_instance.this",
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			diff.Check(t, tst.expectedOutput, stripInstanceKeys(tst.address))
+		})
+	}
+}
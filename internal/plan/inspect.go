@@ -1,7 +1,11 @@
 package plan
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 
@@ -17,13 +21,304 @@ type Diff struct {
 	After string `json:"after"`
 }
 
+// An ordered list of wildcard-supported patterns. Patterns prefixed with
+// "!" are exclusions, un-matching anything they match. Later patterns win
+// over earlier ones so exceptions can be carved out, e.g.
+// ["aws_lambda_function.*", "!aws_lambda_function.authorizer"]. A list
+// starting with an exclusion implicitly starts from "*". A backslash
+// escapes the next character, e.g. \* matches a literal "*" and a leading
+// \! a literal "!". In JSON the backslash itself is escaped, i.e. "\\*".
+//
+// Can be unmarshalled from either a single string or a list of strings.
+type Patterns []string
+
+func (p *Patterns) UnmarshalJSON(data []byte) error {
+	// Left out patterns stay nil so they marshal back the same
+	if string(bytes.TrimSpace(data)) == "null" {
+		*p = nil
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*p = Patterns{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("patterns must be a string or a list of strings")
+	}
+	*p = Patterns(list)
+	return nil
+}
+
+/*
+Checks if s matches the patterns, applying each pattern in order. An
+empty list of patterns only matches an empty string.
+*/
+func (p Patterns) match(m *wildcard.Matcher, s string) (bool, error) {
+	if len(p) == 0 {
+		return s == "", nil
+	}
+
+	matched := strings.HasPrefix(p[0], "!")
+	for _, pattern := range p {
+		exclude := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		match, err := matchPattern(m, pattern, s)
+		if err != nil {
+			return false, fmt.Errorf("unable to match %s with pattern %s caused by: %w", s, pattern, err)
+		}
+		if match {
+			matched = !exclude
+		}
+	}
+	return matched, nil
+}
+
+// Compiled patterns by their wildcard symbols and pattern.
+var patternRegexps sync.Map
+
+/*
+Checks if s matches the wildcard pattern. A backslash escapes the next
+character so it is matched literally and "?" matches a single character,
+not a byte. Errors if the pattern ends with an unescaped backslash.
+*/
+func matchPattern(m *wildcard.Matcher, pattern, s string) (bool, error) {
+	key := string([]byte{m.M, m.S}) + pattern
+	if re, ok := patternRegexps.Load(key); ok {
+		return re.(*regexp.Regexp).MatchString(s), nil
+	}
+
+	// The matcher has no escapes and its "?" matches a byte, so the
+	// pattern is matched as a regex
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\':
+			if i+1 == len(runes) {
+				return false, fmt.Errorf("pattern %s ends with an unescaped backslash", pattern)
+			}
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case runes[i] == rune(m.M):
+			expr.WriteString("(?s:.*)")
+		case runes[i] == rune(m.S):
+			expr.WriteString("(?s:.)")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return false, err
+	}
+	patternRegexps.Store(key, re)
+	return re.MatchString(s), nil
+}
+
+/*
+Escapes s so a filter pattern matches it literally, i.e. its wildcards,
+backslashes, commas, a leading "!" and placeholders, which are resolved
+before the pattern is matched.
+*/
+func EscapePattern(s string) string {
+	return strings.ReplaceAll(escapeWildcards(s), "${", "$${")
+}
+
+/*
+Escapes the wildcards, backslashes, commas and a leading "!" of s, e.g. a
+resolved placeholder value, so a filter pattern matches it literally.
+*/
+func escapeWildcards(s string) string {
+	out := strings.Builder{}
+	for i, r := range s {
+		if strings.ContainsRune(`\*?[,`, r) || (i == 0 && r == '!') {
+			out.WriteRune('\\')
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+/*
+Splits a DiffPatterns key into its comma separated path patterns. A comma
+escaped with a backslash is part of the pattern.
+*/
+func pathPatterns(key string) Patterns {
+	patterns := Patterns{}
+	start := 0
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case '\\':
+			i++
+		case ',':
+			patterns = append(patterns, key[start:i])
+			start = i + 1
+		}
+	}
+	return append(patterns, key[start:])
+}
+
+/*
+Checks the patterns of the field of a filter rule for a lone "!", which
+excludes nothing.
+*/
+func (p Patterns) validate(field string) *Error {
+	for _, pattern := range p {
+		if pattern == "!" {
+			return &Error{Kind: ErrInvalidPattern, Message: fmt.Sprintf("pattern ! in %s excludes nothing", field)}
+		}
+	}
+	return nil
+}
+
+/*
+Checks the patterns of the field of a filter rule for the first pattern
+which is both included and excluded, where the later of the two always
+wins so the earlier never applies.
+*/
+func (p Patterns) conflict(field string) *Error {
+	included := map[string]bool{}
+	excluded := map[string]bool{}
+	for _, pattern := range p {
+		if trimmed, ok := strings.CutPrefix(pattern, "!"); ok {
+			excluded[trimmed] = true
+		} else {
+			included[pattern] = true
+		}
+		if trimmed := strings.TrimPrefix(pattern, "!"); included[trimmed] && excluded[trimmed] {
+			return &Error{Kind: ErrFilterConflict, Message: fmt.Sprintf("pattern %s in %s is both included and excluded", trimmed, field)}
+		}
+	}
+	return nil
+}
+
+/*
+Checks the comma separated path patterns of a DiffPatterns key, which may
+not hold empty entries.
+*/
+func validatePathPatterns(key string) *Error {
+	field := fmt.Sprintf("diffPatterns[%s]", key)
+	patterns := pathPatterns(key)
+	if len(patterns) > 1 {
+		for _, pattern := range patterns {
+			if pattern == "" {
+				return &Error{Kind: ErrInvalidPattern, Message: fmt.Sprintf("empty path pattern in %s", field)}
+			}
+		}
+	}
+	return patterns.validate(field)
+}
+
+// Before and after patterns to match against a Diff.
+type DiffPattern struct {
+	// Patterns to match against the value of the attribute before the planned change.
+	Before Patterns `json:"before"`
+	// Patterns to match against the value of the attribute after the planned change.
+	After Patterns `json:"after"`
+}
+
+const (
+	// Filters out each matching attribute change on its own. The default.
+	FilterModeAttribute = "attribute"
+	// Filters out the entity only when every attribute change matches.
+	FilterModeAll = "all"
+)
+
 type Filter struct {
-	// A wildcard-supported string to match against entity addresses.
-	NamePattern string `json:"namePattern"`
-	// A wildcard-supported map string of slice Diff to match against
+	// Wildcard-supported patterns to match against entity addresses.
+	NamePattern Patterns `json:"namePattern"`
+	// A wildcard-supported map string of slice DiffPattern to match against
 	// the entity planned change. The key is the field within the resource/
-	// output/drift.
-	DiffPatterns map[string][]Diff `json:"diffPatterns"`
+	// output/drift and may hold several comma separated path patterns.
+	DiffPatterns map[string][]DiffPattern `json:"diffPatterns"`
+	// Optional mode of the filter, either FilterModeAttribute (default) or
+	// FilterModeAll.
+	Mode string `json:"mode,omitempty"`
+	// Optional maximum number of attribute changes the entity may have for
+	// the filter to apply. Zero means no limit.
+	MaxChanges int `json:"maxChanges,omitempty"`
+	// Optional condition which must evaluate to true for the filter to
+	// apply. Evaluated against the un-filtered diffs of the plan. See
+	// condition.go for the expression language.
+	Condition string `json:"condition,omitempty"`
+}
+
+/*
+Finds the first invalid pattern, unknown mode or condition which does not
+parse in the filter rule. Returns the parsed condition, nil when the rule
+has none, so it is only parsed once.
+*/
+func (f *Filter) validate() (condExpr, *Error) {
+	switch f.Mode {
+	case "", FilterModeAttribute, FilterModeAll:
+	default:
+		return nil, &Error{Kind: ErrInvalidFilter, Message: fmt.Sprintf("unknown filter mode %s", f.Mode)}
+	}
+
+	if err := f.NamePattern.validate("namePattern"); err != nil {
+		return nil, err
+	}
+
+	for _, pathPattern := range sortedKeys(f.DiffPatterns) {
+		if err := validatePathPatterns(pathPattern); err != nil {
+			return nil, err
+		}
+		for _, diffPattern := range f.DiffPatterns[pathPattern] {
+			if err := diffPattern.Before.validate(fmt.Sprintf("diffPatterns[%s].before", pathPattern)); err != nil {
+				return nil, err
+			}
+			if err := diffPattern.After.validate(fmt.Sprintf("diffPatterns[%s].after", pathPattern)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if f.Condition == "" {
+		return nil, nil
+	}
+	condition, err := parseCondition(f.Condition)
+	if err != nil {
+		return nil, &Error{Kind: ErrInvalidFilter, Message: "invalid condition", Err: err}
+	}
+	return condition, nil
+}
+
+/*
+Checks if a diff at the path matches any of the filter's diff patterns.
+*/
+func (f *Filter) matchDiff(m *wildcard.Matcher, address, path string, diff *Diff) (bool, error) {
+	for pathPattern, diffPatterns := range f.DiffPatterns {
+		if match, err := pathPatterns(pathPattern).match(m, path); err != nil {
+			return false, fmt.Errorf("unable to match %s.%s with pattern %s caused by: %w", address, path, pathPattern, err)
+		} else if !match {
+			continue
+		}
+		// The path has matched a filter rule. Now to check if the before and after patterns apply
+
+		for _, diffPattern := range diffPatterns {
+			bMatch, err := diffPattern.Before.match(m, diff.Before)
+			if err != nil {
+				return false, fmt.Errorf("unable to match %s.%s before value %s caused by: %w", address, path, diff.Before, err)
+			}
+			aMatch, err := diffPattern.After.match(m, diff.After)
+			if err != nil {
+				return false, fmt.Errorf("unable to match %s.%s after value %s caused by: %w", address, path, diff.After, err)
+			}
+
+			if bMatch && aMatch {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
 }
 
 type InspectFilter struct {
@@ -38,6 +333,13 @@ type InspectFilter struct {
 type InspectInput struct {
 	// Optional filter to apply to the plan during inspection.
 	Filter *InspectFilter `json:"filter"`
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string `json:"set"`
+	// Optional directory of the Terraform configuration to locate the
+	// blocks declaring the changes in.
+	ConfigDir string `json:"configDir,omitempty"`
+	// Optional context which stops the inspection early when it ends.
+	Context context.Context `json:"-"`
 }
 
 // Differences in attributes between two entities. Map key is the attribute. Map
@@ -52,6 +354,13 @@ type InspectDiff struct {
 	Outputs map[string]EntityDiff `json:"outputs"`
 	// Planned changes to resource drifts.
 	ResourceDrifts map[string]EntityDiff `json:"resourceDrifts"`
+	// Planned actions (create, update, delete...) of the changed resources
+	// keyed by address.
+	ResourceActions map[string][]string `json:"resourceActions,omitempty"`
+	// Locations of the blocks declaring the changed resources and resource
+	// drifts keyed by address, and outputs keyed by name. Only set when
+	// inspecting with a configuration directory.
+	Sources map[string]SourceLocation `json:"sources,omitempty"`
 }
 
 // Result of calling Inspect() to inspect a Terraform plan.
@@ -215,80 +524,171 @@ func (i *InspectOutput) IsEmpty() bool {
 	return len(i.Diff.Outputs) == 0 && len(i.Diff.ResourceDrifts) == 0 && len(i.Diff.Resources) == 0
 }
 
-func filterEntityDiffs(address string, entityDiff EntityDiff, filters []Filter, inspectDiffMap map[string]EntityDiff) (map[string]EntityDiff, error) {
+/*
+Applies the filter rules to the entity, deleting the diffs they match from
+inspectDiffMap. entityDiff is the entity's original diff, left untouched so
+maxChanges and mode all see every change whatever earlier rules removed.
+conditions holds the parsed condition of each rule.
+*/
+func filterEntityDiffs(kind, address string, entityDiff EntityDiff, filters []Filter, conditions []condExpr, inspectDiffMap map[string]EntityDiff, env *conditionEnv) (map[string]EntityDiff, error) {
 	m := wildcard.NewMatcher()
 
-	for _, filter := range filters {
-		if match, err := m.Match(filter.NamePattern, address); err != nil {
-			return nil, fmt.Errorf("unable to match %s with pattern %s caused by: %v", address, filter.NamePattern, err)
-		} else if match {
-			// The name has matched a filter rule. Now to check if any of the diff patterns apply to the entity's diffs
-
-			for path, diff := range entityDiff {
-				for pathPattern, diffPatterns := range filter.DiffPatterns {
-					if match, err := m.Match(pathPattern, path); err != nil {
-						return nil, fmt.Errorf("unable to match %s.%s with pattern %s caused by: %v", address, path, pathPattern, err)
-					} else if match {
-						// The path has matched a filter rule. Now to check if the before and after patterns apply
-
-						for _, diffPattern := range diffPatterns {
-							bMatch, err := m.Match(diffPattern.Before, diff.Before)
-							if err != nil {
-								return nil, fmt.Errorf("unable to match %s.%s before value %s with pattern %s caused by: %v", address, path, diff.Before, diffPattern.Before, err)
-							}
-							aMatch, err := m.Match(diffPattern.After, diff.After)
-							if err != nil {
-								return nil, fmt.Errorf("unable to match %s.%s after value %s with pattern %s caused by: %v", address, path, diff.After, diffPattern.After, err)
-							}
-
-							if bMatch && aMatch {
-								// The before and after patterns both match. This diff should be filtered out
-
-								delete(inspectDiffMap[address], path)
-								if len(inspectDiffMap[address]) == 0 {
-									// There are no more diffs for this entity. Remove it from the output
-
-									delete(inspectDiffMap, address)
-								}
-							}
-						}
-					}
-				}
+	for rule, filter := range filters {
+		switch filter.Mode {
+		case "", FilterModeAttribute, FilterModeAll:
+		default:
+			return nil, &Error{Kind: ErrInvalidFilter, Message: fmt.Sprintf("unknown filter mode %s", filter.Mode), Address: address, Filter: kind, Rule: rule}
+		}
+
+		if match, err := filter.NamePattern.match(m, address); err != nil {
+			return nil, &Error{Kind: ErrInvalidPattern, Message: fmt.Sprintf("unable to match %s with name patterns", address), Address: address, Filter: kind, Rule: rule, Err: err}
+		} else if !match {
+			continue
+		}
+		// The name has matched a filter rule. Now to check if any of the diff patterns apply to the entity's diffs
+
+		if conditions[rule] != nil {
+			if ok, err := evalBool(conditions[rule], env); err != nil {
+				return nil, &Error{Kind: ErrInvalidFilter, Message: "unable to evaluate condition", Address: address, Filter: kind, Rule: rule, Err: err}
+			} else if !ok {
+				continue
 			}
 		}
+
+		if filter.MaxChanges > 0 && len(entityDiff) > filter.MaxChanges {
+			// Too many changes for the filter to apply. Leave the entity fully visible
+
+			continue
+		}
+
+		matched := []string{}
+		for path, diff := range entityDiff {
+			match, err := filter.matchDiff(m, address, path, diff)
+			if err != nil {
+				return nil, &Error{Kind: ErrInvalidPattern, Message: "unable to match diff", Address: address, Path: path, Filter: kind, Rule: rule, Err: err}
+			}
+			if match {
+				matched = append(matched, path)
+			}
+		}
+
+		if filter.Mode == FilterModeAll && len(matched) != len(entityDiff) {
+			// Not every change is allowed. Leave the entity fully visible
+
+			continue
+		}
+
+		for _, path := range matched {
+			// The before and after patterns both match. This diff should be filtered out
+
+			delete(inspectDiffMap[address], path)
+		}
+		if len(inspectDiffMap[address]) == 0 {
+			// There are no more diffs for this entity. Remove it from the output
+
+			delete(inspectDiffMap, address)
+		}
 	}
 	return inspectDiffMap, nil
 }
 
-func (i *InspectFilter) apply(in *InspectDiff) (*InspectDiff, error) {
-	// m := wildcard.NewMatcher()
+/*
+Deep copies the InspectDiff so it can be referenced while the original
+is being filtered.
+*/
+func (d *InspectDiff) copy() *InspectDiff {
+	copyMap := func(m map[string]EntityDiff) map[string]EntityDiff {
+		out := map[string]EntityDiff{}
+		for address, entDiff := range m {
+			out[address] = EntityDiff{}
+			for path, diff := range entDiff {
+				d := *diff
+				out[address][path] = &d
+			}
+		}
+		return out
+	}
+
+	return &InspectDiff{
+		Resources:      copyMap(d.Resources),
+		Outputs:        copyMap(d.Outputs),
+		ResourceDrifts: copyMap(d.ResourceDrifts),
+	}
+}
+
+// The parsed conditions of the filter rules by list and rule, nil for rules
+// without one.
+type filterConditions map[string][]condExpr
+
+/*
+Validates every filter rule, returning the first error located by the list
+and index of its rule, and the parsed conditions of the rules.
+*/
 
+func (i *InspectFilter) validate() (filterConditions, error) {
+	conditions := filterConditions{}
+	lists := []struct {
+		kind    string
+		filters []Filter
+	}{
+		{"outputChanges", i.OutputChanges},
+		{"resourceChanges", i.ResourceChanges},
+		{"driftChanges", i.DriftChanges},
+	}
+	for _, list := range lists {
+		for rule := range list.filters {
+			condition, err := list.filters[rule].validate()
+			if err != nil {
+				err.Filter = list.kind
+				err.Rule = rule
+				return nil, err
+			}
+			conditions[list.kind] = append(conditions[list.kind], condition)
+		}
+	}
+	return conditions, nil
+}
+
+func (i *InspectFilter) apply(ctx context.Context, in *InspectDiff, conditions filterConditions) (*InspectDiff, error) {
 	inspectDiff := in
+	original := in.copy()
+
+	for address := range in.Resources {
+		if err := ctx.Err(); err != nil {
+			return in, err
+		}
 
-	for address, entDiff := range in.Resources {
 		var err error
-		in.Resources, err = filterEntityDiffs(address, entDiff, i.ResourceChanges, in.Resources)
+		in.Resources, err = filterEntityDiffs("resourceChanges", address, original.Resources[address], i.ResourceChanges, conditions["resourceChanges"], in.Resources, &conditionEnv{self: original.Resources[address], plan: original})
 
 		if err != nil {
-			return in, fmt.Errorf("unable to apply filters to resource at address %s caused by: %v", address, err)
+			return in, fmt.Errorf("unable to apply filters to resource at address %s caused by: %w", address, err)
 		}
 	}
 
-	for address, entDiff := range in.ResourceDrifts {
+	for address := range in.ResourceDrifts {
+		if err := ctx.Err(); err != nil {
+			return in, err
+		}
+
 		var err error
-		in.ResourceDrifts, err = filterEntityDiffs(address, entDiff, i.DriftChanges, in.ResourceDrifts)
+		in.ResourceDrifts, err = filterEntityDiffs("driftChanges", address, original.ResourceDrifts[address], i.DriftChanges, conditions["driftChanges"], in.ResourceDrifts, &conditionEnv{self: original.ResourceDrifts[address], plan: original})
 
 		if err != nil {
-			return in, fmt.Errorf("unable to apply filters to resource drift at address %s caused by: %v", address, err)
+			return in, fmt.Errorf("unable to apply filters to resource drift at address %s caused by: %w", address, err)
 		}
 	}
 
-	for name, entDiff := range in.Outputs {
+	for name := range in.Outputs {
+		if err := ctx.Err(); err != nil {
+			return in, err
+		}
+
 		var err error
-		in.Outputs, err = filterEntityDiffs(name, entDiff, i.OutputChanges, in.Outputs)
+		in.Outputs, err = filterEntityDiffs("outputChanges", name, original.Outputs[name], i.OutputChanges, conditions["outputChanges"], in.Outputs, &conditionEnv{self: original.Outputs[name], plan: original})
 
 		if err != nil {
-			return in, fmt.Errorf("unable to apply filters to output name %s caused by: %v", name, err)
+			return in, fmt.Errorf("unable to apply filters to output name %s caused by: %w", name, err)
 		}
 	}
 
@@ -300,6 +700,10 @@ Inspects the plan with the provided filter to find changes not
 captured by the filter.
 */
 func (p *Plan) Inspect(params *InspectInput) (*InspectOutput, error) {
+	ctx := params.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
 	out := &InspectOutput{
 		Diff: &InspectDiff{
@@ -314,11 +718,23 @@ func (p *Plan) Inspect(params *InspectInput) (*InspectOutput, error) {
 
 	go func() {
 		for _, rChange := range p.ResourceChanges {
+			if ctx.Err() != nil {
+				break
+			}
 			if strings.HasPrefix(rChange.Address, "data.") {
 				continue
 			}
 			if chng := parseChange(rChange.Change); !chng.IsEmpty() {
 				out.Diff.Resources[rChange.Address] = chng
+
+				if len(rChange.Change.Actions) > 0 {
+					if out.Diff.ResourceActions == nil {
+						out.Diff.ResourceActions = map[string][]string{}
+					}
+					for _, action := range rChange.Change.Actions {
+						out.Diff.ResourceActions[rChange.Address] = append(out.Diff.ResourceActions[rChange.Address], string(action))
+					}
+				}
 			}
 		}
 		wg.Done()
@@ -326,6 +742,9 @@ func (p *Plan) Inspect(params *InspectInput) (*InspectOutput, error) {
 
 	go func() {
 		for _, dChange := range p.ResourceDrift {
+			if ctx.Err() != nil {
+				break
+			}
 			if strings.HasPrefix(dChange.Address, "data.") {
 				continue
 			}
@@ -347,11 +766,37 @@ func (p *Plan) Inspect(params *InspectInput) (*InspectOutput, error) {
 	}()
 
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	var err error
-	out.Diff, err = params.Filter.apply(out.Diff)
+	filter, err := params.Filter.resolve(&filterVars{plan: p.Variables, set: params.Set})
 	if err != nil {
-		return nil, fmt.Errorf("failed to apply filter caused by: %e", err)
+		return nil, fmt.Errorf("failed to resolve filter caused by: %w", err)
+	}
+
+	conditions, err := filter.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter caused by: %w", err)
+	}
+
+	out.Diff, err = filter.apply(ctx, out.Diff, conditions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply filter caused by: %w", err)
+	}
+
+	for address := range out.Diff.ResourceActions {
+		if _, ok := out.Diff.Resources[address]; !ok {
+			// Every change of the resource was filtered out
+
+			delete(out.Diff.ResourceActions, address)
+		}
+	}
+
+	if params.ConfigDir != "" {
+		if err := p.locateSources(params.ConfigDir, out.Diff); err != nil {
+			return nil, fmt.Errorf("failed to locate sources caused by: %w", err)
+		}
 	}
 
 	return out, nil
@@ -361,8 +806,20 @@ const (
 	colorNone   = "\033[0m"
 	colorBold   = "\033[1m"
 	colorOrange = "\033[33m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
 )
 
+/*
+Formats the source location of the entity for the pretty report, if known.
+*/
+func (o *InspectOutput) source(key string) string {
+	if location, ok := o.Diff.Sources[key]; ok {
+		return fmt.Sprintf(" (%s)", location)
+	}
+	return ""
+}
+
 /*
 Produces a slice of strings output which can be printed line by line
 to get a Terraform-style stdout report of the inspect.
@@ -372,7 +829,7 @@ func (o *InspectOutput) Pretty() []string {
 	out = append(out, "\tTerraform plan contained the following un-filtered changes:\n")
 
 	for address, diffs := range o.Diff.Resources {
-		out = append(out, fmt.Sprintf("\n\t\tresource %s\"%s\"%s changes:\n", colorBold, address, colorNone))
+		out = append(out, fmt.Sprintf("\n\t\tresource %s\"%s\"%s changes%s:\n", colorBold, address, colorNone, o.source(address)))
 		maxWidth := 0
 		for path := range diffs {
 			if len(path) > maxWidth {
@@ -386,7 +843,7 @@ func (o *InspectOutput) Pretty() []string {
 	}
 
 	for address, diffs := range o.Diff.ResourceDrifts {
-		out = append(out, fmt.Sprintf("\n\t\tresource %s\"%s\"%s drift:\n", colorBold, address, colorNone))
+		out = append(out, fmt.Sprintf("\n\t\tresource %s\"%s\"%s drift%s:\n", colorBold, address, colorNone, o.source(address)))
 		maxWidth := 0
 		for path := range diffs {
 			if len(path) > maxWidth {
@@ -400,7 +857,7 @@ func (o *InspectOutput) Pretty() []string {
 	}
 
 	for name, diffs := range o.Diff.Outputs {
-		out = append(out, fmt.Sprintf("\n\t\toutput %s\"%s\"%s changes:\n", colorBold, name, colorNone))
+		out = append(out, fmt.Sprintf("\n\t\toutput %s\"%s\"%s changes%s:\n", colorBold, name, colorNone, o.source(name)))
 		maxWidth := 0
 		for path := range diffs {
 			if len(path) > maxWidth {
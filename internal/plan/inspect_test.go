@@ -1,12 +1,14 @@
 package plan
 
 import (
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp/cmpopts"
 	tfJson "github.com/hashicorp/terraform-json"
 	"github.com/orange-car/tfplan/internal/testing/diff"
 	"github.com/stretchr/testify/assert"
+	"github.com/vodkaslime/wildcard"
 )
 
 func Test_InspectWithoutWildcard(t *testing.T) {
@@ -38,13 +40,13 @@ func Test_InspectWithoutWildcard(t *testing.T) {
 				Filter: &InspectFilter{
 					ResourceChanges: []Filter{
 						{
-							NamePattern: "aws_instance.example",
-							DiffPatterns: map[string][]Diff{
+							NamePattern: Patterns{"aws_instance.example"},
+							DiffPatterns: map[string][]DiffPattern{
 								".ami": {
-									{Before: "ami-0397850", After: "ami-12345678"},
+									{Before: Patterns{"ami-0397850"}, After: Patterns{"ami-12345678"}},
 								},
 								".instance_type": {
-									{Before: "t2.medium", After: "t2.micro"},
+									{Before: Patterns{"t2.medium"}, After: Patterns{"t2.micro"}},
 								},
 							},
 						},
@@ -82,13 +84,13 @@ func Test_InspectWithoutWildcard(t *testing.T) {
 				Filter: &InspectFilter{
 					DriftChanges: []Filter{
 						{
-							NamePattern: "aws_instance.example",
-							DiffPatterns: map[string][]Diff{
+							NamePattern: Patterns{"aws_instance.example"},
+							DiffPatterns: map[string][]DiffPattern{
 								".ami": {
-									{Before: "ami-0397850", After: "ami-12345678"},
+									{Before: Patterns{"ami-0397850"}, After: Patterns{"ami-12345678"}},
 								},
 								".instance_type": {
-									{Before: "t2.medium", After: "t2.micro"},
+									{Before: Patterns{"t2.medium"}, After: Patterns{"t2.micro"}},
 								},
 							},
 						},
@@ -117,10 +119,10 @@ func Test_InspectWithoutWildcard(t *testing.T) {
 				Filter: &InspectFilter{
 					OutputChanges: []Filter{
 						{
-							NamePattern: "foo-output",
-							DiffPatterns: map[string][]Diff{
+							NamePattern: Patterns{"foo-output"},
+							DiffPatterns: map[string][]DiffPattern{
 								".": {
-									{Before: "this", After: "that"},
+									{Before: Patterns{"this"}, After: Patterns{"that"}},
 								},
 							},
 						},
@@ -157,10 +159,10 @@ func Test_InspectWithoutWildcard(t *testing.T) {
 				Filter: &InspectFilter{
 					ResourceChanges: []Filter{
 						{
-							NamePattern: "aws_cloudwatch_log_group.this",
-							DiffPatterns: map[string][]Diff{
+							NamePattern: Patterns{"aws_cloudwatch_log_group.this"},
+							DiffPatterns: map[string][]DiffPattern{
 								".retention": {
-									{Before: "7", After: "10"},
+									{Before: Patterns{"7"}, After: Patterns{"10"}},
 								},
 							},
 						},
@@ -203,10 +205,10 @@ func Test_InspectWithoutWildcard(t *testing.T) {
 				Filter: &InspectFilter{
 					DriftChanges: []Filter{
 						{
-							NamePattern: "aws_cloudwatch_log_group.this",
-							DiffPatterns: map[string][]Diff{
+							NamePattern: Patterns{"aws_cloudwatch_log_group.this"},
+							DiffPatterns: map[string][]DiffPattern{
 								".retention": {
-									{Before: "7", After: "10"},
+									{Before: Patterns{"7"}, After: Patterns{"10"}},
 								},
 							},
 						},
@@ -240,10 +242,10 @@ func Test_InspectWithoutWildcard(t *testing.T) {
 				Filter: &InspectFilter{
 					OutputChanges: []Filter{
 						{
-							NamePattern: "bar-output",
-							DiffPatterns: map[string][]Diff{
+							NamePattern: Patterns{"bar-output"},
+							DiffPatterns: map[string][]DiffPattern{
 								".": {
-									{Before: "1", After: "2"},
+									{Before: Patterns{"1"}, After: Patterns{"2"}},
 								},
 							},
 						},
@@ -295,10 +297,10 @@ func Test_InspectWithoutWildcard(t *testing.T) {
 				Filter: &InspectFilter{
 					ResourceChanges: []Filter{
 						{
-							NamePattern: "aws_s3_bucket.this",
-							DiffPatterns: map[string][]Diff{
+							NamePattern: Patterns{"aws_s3_bucket.this"},
+							DiffPatterns: map[string][]DiffPattern{
 								".bucket": {
-									{Before: "foo", After: "bar"},
+									{Before: Patterns{"foo"}, After: Patterns{"bar"}},
 								},
 							},
 						},
@@ -576,10 +578,10 @@ func Test_InspectWithWildcard(t *testing.T) {
 				Filter: &InspectFilter{
 					ResourceChanges: []Filter{
 						{
-							NamePattern: "aws_instance.*",
-							DiffPatterns: map[string][]Diff{
+							NamePattern: Patterns{"aws_instance.*"},
+							DiffPatterns: map[string][]DiffPattern{
 								".ami": {
-									{Before: "ami-0397850", After: "ami-*"},
+									{Before: Patterns{"ami-0397850"}, After: Patterns{"ami-*"}},
 								},
 							},
 						},
@@ -621,10 +623,10 @@ func Test_InspectWithWildcard(t *testing.T) {
 				Filter: &InspectFilter{
 					ResourceChanges: []Filter{
 						{
-							NamePattern: "aws_instance.?xample",
-							DiffPatterns: map[string][]Diff{
+							NamePattern: Patterns{"aws_instance.?xample"},
+							DiffPatterns: map[string][]DiffPattern{
 								".ami": {
-									{Before: "ami-0397850", After: "ami-*"},
+									{Before: Patterns{"ami-0397850"}, After: Patterns{"ami-*"}},
 								},
 							},
 						},
@@ -644,6 +646,412 @@ func Test_InspectWithWildcard(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		"filter name exclusion": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_lambda_function.api",
+						Change: &tfJson.Change{
+							Before: map[string]any{"source_code_hash": "abc"},
+							After:  map[string]any{"source_code_hash": "def"},
+						},
+					},
+					{
+						Address: "aws_lambda_function.authorizer",
+						Change: &tfJson.Change{
+							Before: map[string]any{"source_code_hash": "abc"},
+							After:  map[string]any{"source_code_hash": "def"},
+						},
+					},
+				},
+			},
+			input: &InspectInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern: Patterns{"aws_lambda_function.*", "!aws_lambda_function.authorizer"},
+							DiffPatterns: map[string][]DiffPattern{
+								"*": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_lambda_function.authorizer": {
+							".source_code_hash": {Before: "abc", After: "def"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			expectedError: nil,
+		},
+		"filter path exclusion": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_lambda_function.api",
+						Change: &tfJson.Change{
+							Before: map[string]any{"source_code_hash": "abc", "role": "foo", "timeout": 3},
+							After:  map[string]any{"source_code_hash": "def", "role": "bar", "timeout": 5},
+						},
+					},
+				},
+			},
+			input: &InspectInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern: Patterns{"aws_lambda_function.api"},
+							DiffPatterns: map[string][]DiffPattern{
+								"!.role": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_lambda_function.api": {
+							".role": {Before: "foo", After: "bar"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			expectedError: nil,
+		},
+		"filter comma separated paths and value exclusion": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_instance.example",
+						Change: &tfJson.Change{
+							Before: map[string]any{"ami": "ami-0397850", "instance_type": "t2.medium", "key_name": "foo"},
+							After:  map[string]any{"ami": "ami-12345678", "instance_type": "t2.micro", "key_name": "bar"},
+						},
+					},
+				},
+			},
+			input: &InspectInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern: Patterns{"aws_instance.example"},
+							DiffPatterns: map[string][]DiffPattern{
+								".ami,.instance_type": {
+									{Before: Patterns{"*"}, After: Patterns{"*", "!t2.micro"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.example": {
+							".instance_type": {Before: "t2.medium", After: "t2.micro"},
+							".key_name":      {Before: "foo", After: "bar"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			expectedError: nil,
+		},
+		"filter mode all with a disallowed change": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_lambda_function.api",
+						Change: &tfJson.Change{
+							Before: map[string]any{"source_code_hash": "abc", "role": "foo"},
+							After:  map[string]any{"source_code_hash": "def", "role": "bar"},
+						},
+					},
+				},
+			},
+			input: &InspectInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern: Patterns{"aws_lambda_function.*"},
+							Mode:        FilterModeAll,
+							DiffPatterns: map[string][]DiffPattern{
+								".source_code_hash": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_lambda_function.api": {
+							".source_code_hash": {Before: "abc", After: "def"},
+							".role":             {Before: "foo", After: "bar"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			expectedError: nil,
+		},
+		"filter mode all with only allowed changes": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_lambda_function.api",
+						Change: &tfJson.Change{
+							Before: map[string]any{"source_code_hash": "abc", "role": "foo"},
+							After:  map[string]any{"source_code_hash": "def", "role": "foo"},
+						},
+					},
+				},
+			},
+			input: &InspectInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern: Patterns{"aws_lambda_function.*"},
+							Mode:        FilterModeAll,
+							DiffPatterns: map[string][]DiffPattern{
+								".source_code_hash": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources:      map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			expectedError: nil,
+		},
+		"filter max changes exceeded": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_instance.example",
+						Change: &tfJson.Change{
+							Before: map[string]any{"ami": "ami-0397850", "instance_type": "t2.medium"},
+							After:  map[string]any{"ami": "ami-12345678", "instance_type": "t2.micro"},
+						},
+					},
+				},
+			},
+			input: &InspectInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern: Patterns{"aws_instance.*"},
+							MaxChanges:  1,
+							DiffPatterns: map[string][]DiffPattern{
+								".ami": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.example": {
+							".ami":           {Before: "ami-0397850", After: "ami-12345678"},
+							".instance_type": {Before: "t2.medium", After: "t2.micro"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			expectedError: nil,
+		},
+		"filter mode all after an earlier rule": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_lambda_function.api",
+						Change: &tfJson.Change{
+							Before: map[string]any{"source_code_hash": "abc", "role": "foo"},
+							After:  map[string]any{"source_code_hash": "def", "role": "bar"},
+						},
+					},
+				},
+			},
+			input: &InspectInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern: Patterns{"aws_lambda_function.*"},
+							DiffPatterns: map[string][]DiffPattern{
+								".role": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+						{
+							NamePattern: Patterns{"aws_lambda_function.*"},
+							Mode:        FilterModeAll,
+							DiffPatterns: map[string][]DiffPattern{
+								".source_code_hash": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_lambda_function.api": {
+							".source_code_hash": {Before: "abc", After: "def"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			expectedError: nil,
+		},
+		"filter max changes after an earlier rule": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_instance.example",
+						Change: &tfJson.Change{
+							Before: map[string]any{"ami": "ami-0397850", "instance_type": "t2.medium"},
+							After:  map[string]any{"ami": "ami-12345678", "instance_type": "t2.micro"},
+						},
+					},
+				},
+			},
+			input: &InspectInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern: Patterns{"aws_instance.*"},
+							DiffPatterns: map[string][]DiffPattern{
+								".ami": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+						{
+							NamePattern: Patterns{"aws_instance.*"},
+							MaxChanges:  1,
+							DiffPatterns: map[string][]DiffPattern{
+								".instance_type": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.example": {
+							".instance_type": {Before: "t2.medium", After: "t2.micro"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			expectedError: nil,
+		},
+		"filter condition on other attribute and resource": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_ecs_task_definition.app",
+						Change: &tfJson.Change{
+							Before: map[string]any{"image": "app:1", "family": "app"},
+							After:  map[string]any{"image": "app:2", "family": "app"},
+						},
+					},
+					{
+						Address: "aws_ecs_task_definition.worker",
+						Change: &tfJson.Change{
+							Before: map[string]any{"image": "worker:1", "family": "worker"},
+							After:  map[string]any{"image": "worker:2", "family": "worker-v2"},
+						},
+					},
+					{
+						Address: "aws_lambda_function.x",
+						Change: &tfJson.Change{
+							Before: map[string]any{"source_code_hash": "abc"},
+							After:  map[string]any{"source_code_hash": "def"},
+						},
+					},
+				},
+			},
+			input: &InspectInput{
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern: Patterns{"aws_ecs_task_definition.*"},
+							Condition:   `!changed(".family")`,
+							DiffPatterns: map[string][]DiffPattern{
+								".image": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+						{
+							NamePattern: Patterns{"aws_lambda_function.x"},
+							Condition:   `resource_changed("aws_s3_object.x_code")`,
+							DiffPatterns: map[string][]DiffPattern{
+								"*": {
+									{Before: Patterns{"*"}, After: Patterns{"*"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_ecs_task_definition.worker": {
+							".image":  {Before: "worker:1", After: "worker:2"},
+							".family": {Before: "worker", After: "worker-v2"},
+						},
+						"aws_lambda_function.x": {
+							".source_code_hash": {Before: "abc", After: "def"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			expectedError: nil,
+		},
 	}
 
 	for name, tst := range cases {
@@ -710,3 +1118,94 @@ func Test_InspectPretty(t *testing.T) {
 		})
 	}
 }
+
+func Test_PatternEscapes(t *testing.T) {
+	cases := map[string]struct {
+		patterns      Patterns
+		s             string
+		expectedMatch bool
+	}{
+		"escaped wildcard matches literally": {
+			patterns:      Patterns{`\*`},
+			s:             "*",
+			expectedMatch: true,
+		},
+		"escaped wildcard does not match anything": {
+			patterns:      Patterns{`\*`},
+			s:             "abc",
+			expectedMatch: false,
+		},
+		"escaped exclusion is a literal": {
+			patterns:      Patterns{`\!important`},
+			s:             "!important",
+			expectedMatch: true,
+		},
+		"wildcard with escaped comma": {
+			patterns:      Patterns{`.tags["a\,*"]`},
+			s:             `.tags["a,b"]`,
+			expectedMatch: true,
+		},
+		"escaped pattern": {
+			patterns:      Patterns{EscapePattern(`!a*b?[c],\d`)},
+			s:             `!a*b?[c],\d`,
+			expectedMatch: true,
+		},
+		"escaped pattern does not match wildcards": {
+			patterns:      Patterns{EscapePattern(`a*`)},
+			s:             "ab",
+			expectedMatch: false,
+		},
+		"single character wildcard matches a multi-byte character": {
+			patterns:      Patterns{"caf?"},
+			s:             "café",
+			expectedMatch: true,
+		},
+		"single character wildcard matches a multi-byte character with escapes": {
+			patterns:      Patterns{`\*caf?`},
+			s:             "*café",
+			expectedMatch: true,
+		},
+		"single character wildcard does not match two characters": {
+			patterns:      Patterns{"caf?"},
+			s:             "cafés",
+			expectedMatch: false,
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			match, err := tst.patterns.match(wildcard.NewMatcher(), tst.s)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tst.expectedMatch, match)
+		})
+	}
+
+	diff.Check(t, Patterns{".a", `.tags["b\,c"]`, ".d"}, pathPatterns(`.a,.tags["b\,c"],.d`))
+	diff.Check(t, Patterns{".a"}, pathPatterns(".a"))
+}
+
+func Test_InspectContext(t *testing.T) {
+	p := &Plan{
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: "aws_instance.this",
+				Change:  &tfJson.Change{Actions: tfJson.Actions{tfJson.ActionUpdate}, Before: map[string]any{"ami": "a"}, After: map[string]any{"ami": "b"}},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := p.Inspect(&InspectInput{Context: ctx})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = p.Summary(&SummaryInput{Context: ctx})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	out, err := p.Inspect(&InspectInput{Context: context.Background()})
+	assert.Nil(t, err)
+	assert.Contains(t, out.Diff.Resources, "aws_instance.this")
+}
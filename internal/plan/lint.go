@@ -0,0 +1,230 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// The filter cannot be applied as is.
+	LintSeverityError = "error"
+	// The filter can be applied but likely does not do what was intended.
+	LintSeverityWarning = "warning"
+)
+
+// An issue found in a single filter of an InspectFilter.
+type LintIssue struct {
+	// One of LintSeverityError or LintSeverityWarning.
+	Severity string `json:"severity"`
+	// The list of the filter. One of outputChanges, resourceChanges or
+	// driftChanges.
+	Kind string `json:"kind"`
+	// The index of the filter within its list.
+	Index int `json:"index"`
+	// The field of the filter holding the issue.
+	Field string `json:"field"`
+	// What is wrong with the field.
+	Message string `json:"message"`
+}
+
+// Result of calling Lint() on an InspectFilter.
+type LintOutput struct {
+	// The issues found, ordered by kind, index and field.
+	Issues []LintIssue `json:"issues"`
+}
+
+func (o *LintOutput) IsEmpty() bool {
+	return len(o.Issues) == 0
+}
+
+func (o *LintOutput) HasErrors() bool {
+	for _, issue := range o.Issues {
+		if issue.Severity == LintSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Finds placeholders in s which are not one of the supported ${env:NAME},
+${var.name} or ${set:name} forms.
+*/
+func unknownPlaceholders(s string) []string {
+	unknown := []string{}
+	for _, match := range placeholderRegex.FindAllStringSubmatch(s, -1) {
+		if strings.HasPrefix(match[0], "$$") {
+			continue
+		}
+		key := match[1]
+		if !strings.HasPrefix(key, "env:") && !strings.HasPrefix(key, "var.") && !strings.HasPrefix(key, "set:") {
+			unknown = append(unknown, match[0])
+		}
+	}
+	return unknown
+}
+
+func lintFilter(kind string, index int, filter *Filter) []LintIssue {
+	issues := []LintIssue{}
+	add := func(severity, field, format string, a ...any) {
+		issues = append(issues, LintIssue{Severity: severity, Kind: kind, Index: index, Field: field, Message: fmt.Sprintf(format, a...)})
+	}
+	patterns := func(field string, err *Error) {
+		if err != nil {
+			add(LintSeverityError, field, "%s", err.Message)
+		}
+	}
+	// Conflicting patterns are accepted by Inspect, the later one wins
+	conflict := func(field string, err *Error) {
+		if err != nil {
+			add(LintSeverityWarning, field, "%s", err.Message)
+		}
+	}
+	placeholders := func(field string, values ...string) {
+		for _, v := range values {
+			for _, placeholder := range unknownPlaceholders(v) {
+				add(LintSeverityError, field, "unknown placeholder %s", placeholder)
+			}
+		}
+	}
+
+	if len(filter.NamePattern) == 0 {
+		add(LintSeverityWarning, "namePattern", "no name patterns so the filter never matches")
+	}
+	placeholders("namePattern", filter.NamePattern...)
+	patterns("namePattern", filter.NamePattern.validate("namePattern"))
+	conflict("namePattern", filter.NamePattern.conflict("namePattern"))
+
+	if len(filter.DiffPatterns) == 0 {
+		add(LintSeverityWarning, "diffPatterns", "no diff patterns so the filter never filters out changes")
+	}
+	for _, pathPattern := range sortedKeys(filter.DiffPatterns) {
+		field := fmt.Sprintf("diffPatterns[%s]", pathPattern)
+		placeholders(field, pathPattern)
+		patterns(field, validatePathPatterns(pathPattern))
+		conflict(field, pathPatterns(pathPattern).conflict(field))
+
+		if len(filter.DiffPatterns[pathPattern]) == 0 {
+			add(LintSeverityWarning, field, "no before and after patterns so the path never matches")
+		}
+		for _, diffPattern := range filter.DiffPatterns[pathPattern] {
+			placeholders(field, diffPattern.Before...)
+			placeholders(field, diffPattern.After...)
+			patterns(field, diffPattern.Before.validate(field+".before"))
+			patterns(field, diffPattern.After.validate(field+".after"))
+			conflict(field, diffPattern.Before.conflict(field+".before"))
+			conflict(field, diffPattern.After.conflict(field+".after"))
+		}
+	}
+
+	switch filter.Mode {
+	case "", FilterModeAttribute, FilterModeAll:
+	default:
+		add(LintSeverityError, "mode", "unknown filter mode %s", filter.Mode)
+	}
+
+	if filter.MaxChanges < 0 {
+		add(LintSeverityError, "maxChanges", "maxChanges %d must not be negative", filter.MaxChanges)
+	}
+
+	if filter.Condition != "" {
+		placeholders("condition", filter.Condition)
+
+		// Placeholders are only resolved against a plan, so conditions
+		// holding any are not parsed
+		if !placeholderRegex.MatchString(filter.Condition) {
+			if _, err := parseCondition(filter.Condition); err != nil {
+				add(LintSeverityError, "condition", "%v", err)
+			}
+		}
+	}
+
+	return issues
+}
+
+/*
+Checks the filter for mistakes without a plan, e.g. unknown modes,
+conditions which do not parse or filters which can never match.
+*/
+func (i *InspectFilter) Lint() *LintOutput {
+	out := &LintOutput{Issues: []LintIssue{}}
+	if i == nil {
+		return out
+	}
+
+	lists := []struct {
+		kind    string
+		filters []Filter
+	}{
+		{"outputChanges", i.OutputChanges},
+		{"resourceChanges", i.ResourceChanges},
+		{"driftChanges", i.DriftChanges},
+	}
+	for _, list := range lists {
+		for index := range list.filters {
+			out.Issues = append(out.Issues, lintFilter(list.kind, index, &list.filters[index])...)
+		}
+	}
+	return out
+}
+
+/*
+Checks the filter for patterns which are both included and excluded. Inspect
+applies them with the later pattern winning, so they are only reported here
+and as lint warnings. Returns nil when there are none, otherwise every
+conflict as an *Error of kind ErrFilterConflict joined into one error.
+*/
+func (i *InspectFilter) Conflicts() error {
+	if i == nil {
+		return nil
+	}
+
+	errs := []error{}
+	lists := []struct {
+		kind    string
+		filters []Filter
+	}{
+		{"outputChanges", i.OutputChanges},
+		{"resourceChanges", i.ResourceChanges},
+		{"driftChanges", i.DriftChanges},
+	}
+	for _, list := range lists {
+		for index, filter := range list.filters {
+			add := func(err *Error) {
+				if err != nil {
+					err.Filter = list.kind
+					err.Rule = index
+					errs = append(errs, err)
+				}
+			}
+			add(filter.NamePattern.conflict("namePattern"))
+			for _, pathPattern := range sortedKeys(filter.DiffPatterns) {
+				field := fmt.Sprintf("diffPatterns[%s]", pathPattern)
+				add(pathPatterns(pathPattern).conflict(field))
+				for _, diffPattern := range filter.DiffPatterns[pathPattern] {
+					add(diffPattern.Before.conflict(field + ".before"))
+					add(diffPattern.After.conflict(field + ".after"))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o *LintOutput) Pretty() []string {
+	if o.IsEmpty() {
+		return []string{"\tFilter has no issues\n"}
+	}
+
+	var out []string
+	out = append(out, "\tFilter has the following issues:\n")
+	for _, issue := range o.Issues {
+		color := colorOrange
+		if issue.Severity == LintSeverityError {
+			color = colorRed
+		}
+		out = append(out, fmt.Sprintf("\t\t%s%s%s %s[%d].%s: %s\n", color, issue.Severity, colorNone, issue.Kind, issue.Index, issue.Field, issue.Message))
+	}
+	return out
+}
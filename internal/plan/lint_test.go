@@ -0,0 +1,84 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Lint(t *testing.T) {
+	valid := Filter{
+		NamePattern:  Patterns{"aws_lambda_function.*"},
+		DiffPatterns: map[string][]DiffPattern{".source_code_hash": {{Before: Patterns{"*"}, After: Patterns{"${set:hash}"}}}},
+		Condition:    `changed(".runtime") == false`,
+	}
+
+	cases := map[string]struct {
+		filter            *InspectFilter
+		expectedIssues    []LintIssue
+		expectedHasErrors bool
+	}{
+		"valid": {
+			filter:         &InspectFilter{ResourceChanges: []Filter{valid}},
+			expectedIssues: []LintIssue{},
+		},
+		"nil": {
+			expectedIssues: []LintIssue{},
+		},
+		"never matches": {
+			filter: &InspectFilter{OutputChanges: []Filter{{DiffPatterns: map[string][]DiffPattern{"": {}}}}},
+			expectedIssues: []LintIssue{
+				{Severity: LintSeverityWarning, Kind: "outputChanges", Index: 0, Field: "namePattern", Message: "no name patterns so the filter never matches"},
+				{Severity: LintSeverityWarning, Kind: "outputChanges", Index: 0, Field: "diffPatterns[]", Message: "no before and after patterns so the path never matches"},
+			},
+		},
+		"invalid": {
+			filter: &InspectFilter{
+				ResourceChanges: []Filter{valid},
+				DriftChanges: []Filter{
+					{
+						NamePattern: Patterns{"${workspace}"},
+						Mode:        "any",
+						MaxChanges:  -1,
+						Condition:   `changed(".runtime") ==`,
+					},
+				},
+			},
+			expectedIssues: []LintIssue{
+				{Severity: LintSeverityError, Kind: "driftChanges", Index: 0, Field: "namePattern", Message: "unknown placeholder ${workspace}"},
+				{Severity: LintSeverityWarning, Kind: "driftChanges", Index: 0, Field: "diffPatterns", Message: "no diff patterns so the filter never filters out changes"},
+				{Severity: LintSeverityError, Kind: "driftChanges", Index: 0, Field: "mode", Message: "unknown filter mode any"},
+				{Severity: LintSeverityError, Kind: "driftChanges", Index: 0, Field: "maxChanges", Message: "maxChanges -1 must not be negative"},
+				{Severity: LintSeverityError, Kind: "driftChanges", Index: 0, Field: "condition", Message: `unable to parse condition changed(".runtime") == caused by: unexpected end of condition`},
+			},
+			expectedHasErrors: true,
+		},
+		"patterns": {
+			filter: &InspectFilter{
+				ResourceChanges: []Filter{
+					{
+						NamePattern:  Patterns{"aws_*", "!aws_*"},
+						DiffPatterns: map[string][]DiffPattern{".a,,.b": {{Before: Patterns{"!"}, After: Patterns{"*"}}}},
+					},
+				},
+			},
+			expectedIssues: []LintIssue{
+				{Severity: LintSeverityWarning, Kind: "resourceChanges", Index: 0, Field: "namePattern", Message: "pattern aws_* in namePattern is both included and excluded"},
+				{Severity: LintSeverityError, Kind: "resourceChanges", Index: 0, Field: "diffPatterns[.a,,.b]", Message: "empty path pattern in diffPatterns[.a,,.b]"},
+				{Severity: LintSeverityError, Kind: "resourceChanges", Index: 0, Field: "diffPatterns[.a,,.b]", Message: "pattern ! in diffPatterns[.a,,.b].before excludes nothing"},
+			},
+			expectedHasErrors: true,
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			out := tst.filter.Lint()
+
+			diff.Check(t, tst.expectedIssues, out.Issues)
+			assert.Equal(t, tst.expectedHasErrors, out.HasErrors())
+		})
+	}
+}
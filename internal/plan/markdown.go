@@ -0,0 +1,142 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Escapes a value for a Markdown table cell.
+*/
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+/*
+Formats an attribute path for a Markdown table cell. The empty path of a
+whole value, e.g. an output, is shown as (value).
+*/
+func markdownPath(path string) string {
+	if path == "" {
+		return "(value)"
+	}
+	return fmt.Sprintf("`%s`", markdownCell(path))
+}
+
+/*
+Formats the before and after values of a diff for a Markdown table cell.
+*/
+func markdownDiff(d *Diff) string {
+	if d == nil || isAbsent(d) {
+		return "(no change)"
+	}
+	return fmt.Sprintf("%s → %s", markdownCell(d.Before), markdownCell(d.After))
+}
+
+func markdownEntityDiffs(kind, suffix string, diffs map[string]EntityDiff) []string {
+	var out []string
+	for _, address := range sortedKeys(diffs) {
+		out = append(out, fmt.Sprintf("\n#### %s `%s` %s\n\n", kind, address, suffix))
+		out = append(out, "| Path | Before | After |\n")
+		out = append(out, "| --- | --- | --- |\n")
+		for _, path := range sortedKeys(diffs[address]) {
+			d := diffs[address][path]
+			out = append(out, fmt.Sprintf("| %s | %s | %s |\n", markdownPath(path), markdownCell(d.Before), markdownCell(d.After)))
+		}
+	}
+	return out
+}
+
+/*
+Produces the lines of a Markdown report of the inspect, e.g. for a pull
+request comment.
+*/
+func (o *InspectOutput) Markdown() []string {
+	var out []string
+	out = append(out, "### Terraform plan inspection\n\n")
+
+	if o.IsEmpty() {
+		return append(out, "No un-filtered changes.\n")
+	}
+
+	out = append(out, fmt.Sprintf("Un-filtered changes: %v resources, %v resource drifts, %v outputs\n", len(o.Diff.Resources), len(o.Diff.ResourceDrifts), len(o.Diff.Outputs)))
+	out = append(out, markdownEntityDiffs("resource", "changes", o.Diff.Resources)...)
+	out = append(out, markdownEntityDiffs("resource", "drift", o.Diff.ResourceDrifts)...)
+	out = append(out, markdownEntityDiffs("output", "changes", o.Diff.Outputs)...)
+	return out
+}
+
+func markdownCompareEntityDiffs(kind, suffix string, diffs map[string]CompareEntityDiff) []string {
+	var out []string
+	for _, address := range sortedKeys(diffs) {
+		c := diffs[address]
+		paths := map[string]bool{}
+		for path := range c.PlanA {
+			paths[path] = true
+		}
+		for path := range c.PlanB {
+			paths[path] = true
+		}
+
+		out = append(out, fmt.Sprintf("\n#### %s `%s` %s\n\n", kind, address, suffix))
+		out = append(out, "| Path | Plan A | Plan B |\n")
+		out = append(out, "| --- | --- | --- |\n")
+		for _, path := range sortedKeys(paths) {
+			out = append(out, fmt.Sprintf("| %s | %s | %s |\n", markdownPath(path), markdownDiff(c.PlanA[path]), markdownDiff(c.PlanB[path])))
+		}
+	}
+	return out
+}
+
+func markdownCompareValues(kind string, values map[string]CompareValue) []string {
+	var out []string
+	if len(values) == 0 {
+		return out
+	}
+
+	out = append(out, fmt.Sprintf("\n#### %s differences\n\n", kind))
+	out = append(out, "| Key | Plan A | Plan B |\n")
+	out = append(out, "| --- | --- | --- |\n")
+	for _, k := range sortedKeys(values) {
+		out = append(out, fmt.Sprintf("| `%s` | %s | %s |\n", k, markdownCell(values[k].PlanA), markdownCell(values[k].PlanB)))
+	}
+	return out
+}
+
+func (c *CompareDiff) markdown() []string {
+	var out []string
+	out = append(out, markdownCompareEntityDiffs("resource", "changes", c.Resources)...)
+	out = append(out, markdownCompareEntityDiffs("resource", "drift", c.ResourceDrifts)...)
+	out = append(out, markdownCompareEntityDiffs("output", "changes", c.Outputs)...)
+	out = append(out, markdownCompareValues("action", c.ResourceActions)...)
+	out = append(out, markdownCompareValues("variable", c.Variables)...)
+	out = append(out, markdownCompareValues("provider", c.Providers)...)
+	out = append(out, markdownCompareValues("metadata", c.Metadata)...)
+	return out
+}
+
+/*
+Produces the lines of a Markdown report of the compare, e.g. for a pull
+request comment.
+*/
+func (c *CompareInspectsOutput) Markdown() []string {
+	var out []string
+	out = append(out, "### Terraform plan comparison\n\n")
+
+	if c.IsEmpty() {
+		return append(out, "The plans do not differ.\n")
+	}
+
+	out = append(out, fmt.Sprintf("Differences: %v resources, %v resource drifts, %v outputs\n", len(c.Diff.Resources)+len(c.Diff.ResourceActions), len(c.Diff.ResourceDrifts), len(c.Diff.Outputs)))
+	if len(c.Diff.Suppressed) > 0 {
+		out = append(out, fmt.Sprintf("\nSuppressed: %v differences\n", len(c.Diff.Suppressed)))
+	}
+	out = append(out, c.Diff.markdown()...)
+
+	if c.Diff.Raw != nil && !c.Diff.Raw.IsEmpty() {
+		out = append(out, "\n### Differences before filtering\n")
+		out = append(out, c.Diff.Raw.markdown()...)
+	}
+	return out
+}
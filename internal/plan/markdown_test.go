@@ -0,0 +1,125 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+)
+
+func Test_InspectMarkdown(t *testing.T) {
+	cases := map[string]struct {
+		input          *InspectOutput
+		expectedOutput []string
+	}{
+		"no changes": {
+			input: &InspectOutput{Diff: &InspectDiff{}},
+			expectedOutput: []string{
+				"### Terraform plan inspection\n\n",
+				"No un-filtered changes.\n",
+			},
+		},
+		"changes": {
+			input: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_vpc.main": {".cidr_block": {Before: "10.0.0.0/16", After: "10.1.0.0/16"}},
+					},
+					ResourceDrifts: map[string]EntityDiff{
+						"aws_vpc.main": {".tags.Owner": {Before: "a|b", After: "c"}},
+					},
+					Outputs: map[string]EntityDiff{
+						"vpc_id": {"": {Before: "a", After: "b"}},
+					},
+				},
+			},
+			expectedOutput: []string{
+				"### Terraform plan inspection\n\n",
+				"Un-filtered changes: 1 resources, 1 resource drifts, 1 outputs\n",
+				"\n#### resource `aws_vpc.main` changes\n\n",
+				"| Path | Before | After |\n",
+				"| --- | --- | --- |\n",
+				"| `.cidr_block` | 10.0.0.0/16 | 10.1.0.0/16 |\n",
+				"\n#### resource `aws_vpc.main` drift\n\n",
+				"| Path | Before | After |\n",
+				"| --- | --- | --- |\n",
+				"| `.tags.Owner` | a\\|b | c |\n",
+				"\n#### output `vpc_id` changes\n\n",
+				"| Path | Before | After |\n",
+				"| --- | --- | --- |\n",
+				"| (value) | a | b |\n",
+			},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			diff.Check(t, tst.expectedOutput, tst.input.Markdown())
+		})
+	}
+}
+
+func Test_CompareMarkdown(t *testing.T) {
+	cases := map[string]struct {
+		input          *CompareInspectsOutput
+		expectedOutput []string
+	}{
+		"no differences": {
+			input: &CompareInspectsOutput{Diff: &CompareDiff{}},
+			expectedOutput: []string{
+				"### Terraform plan comparison\n\n",
+				"The plans do not differ.\n",
+			},
+		},
+		"differences": {
+			input: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources: map[string]CompareEntityDiff{
+						"aws_vpc.main": {
+							PlanA: EntityDiff{".cidr_block": {Before: "10.0.0.0/16", After: "10.1.0.0/16"}},
+							PlanB: EntityDiff{".tags.Name": {Before: "a", After: "b"}},
+						},
+					},
+					Variables: map[string]CompareValue{
+						"region": {PlanA: "eu-west-1", PlanB: "eu-west-2"},
+					},
+					Suppressed: []SuppressedDiff{{Kind: "resource", Address: "aws_vpc.main", Path: ".tags.Owner"}},
+					Raw: &CompareDiff{
+						Outputs: map[string]CompareEntityDiff{
+							"vpc_id": {
+								PlanA: EntityDiff{"": {Before: "a", After: "b"}},
+								PlanB: EntityDiff{"": {Before: "(empty)", After: "(empty)"}},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: []string{
+				"### Terraform plan comparison\n\n",
+				"Differences: 1 resources, 0 resource drifts, 0 outputs\n",
+				"\nSuppressed: 1 differences\n",
+				"\n#### resource `aws_vpc.main` changes\n\n",
+				"| Path | Plan A | Plan B |\n",
+				"| --- | --- | --- |\n",
+				"| `.cidr_block` | 10.0.0.0/16 → 10.1.0.0/16 | (no change) |\n",
+				"| `.tags.Name` | (no change) | a → b |\n",
+				"\n#### variable differences\n\n",
+				"| Key | Plan A | Plan B |\n",
+				"| --- | --- | --- |\n",
+				"| `region` | eu-west-1 | eu-west-2 |\n",
+				"\n### Differences before filtering\n",
+				"\n#### output `vpc_id` changes\n\n",
+				"| Path | Plan A | Plan B |\n",
+				"| --- | --- | --- |\n",
+				"| (value) | a → b | (no change) |\n",
+			},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			diff.Check(t, tst.expectedOutput, tst.input.Markdown())
+		})
+	}
+}
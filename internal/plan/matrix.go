@@ -0,0 +1,244 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/orange-car/tfplan/internal/helpers"
+)
+
+// An inspected plan and the label it is reported under.
+type LabelledInspect struct {
+	// Label of the plan, e.g. the environment or workspace name.
+	Label string `json:"label"`
+	// The inspected plan.
+	Output *InspectOutput `json:"output"`
+}
+
+// The diff of a single attribute of an entity in each plan.
+type CompareMatrixRow struct {
+	// Map of plan label to the diff of the attribute in that plan.
+	Diffs map[string]*Diff `json:"diffs"`
+	// Labels of the plans whose diff differs from the majority. Every plan
+	// when no diff is shared by more plans than any other, e.g. when two
+	// plans differ.
+	Outliers []string `json:"outliers"`
+}
+
+// The identified divergences between N inspected plans
+type CompareMatrix struct {
+	// Resources and their diverging attribute paths.
+	Resources map[string]map[string]*CompareMatrixRow `json:"resources"`
+	// Outputs and their diverging attribute paths.
+	Outputs map[string]map[string]*CompareMatrixRow `json:"outputs"`
+	// Resource drifts and their diverging attribute paths.
+	ResourceDrifts map[string]map[string]*CompareMatrixRow `json:"resourceDrifts"`
+}
+
+// Result of calling CompareManyInspects() to identify divergences of N inspected plans
+type CompareMatrixOutput struct {
+	// Labels of the compared plans in the order given.
+	Labels []string `json:"labels"`
+	// The identified divergences between the plans.
+	Diff *CompareMatrix `json:"diff"`
+	// Map of plan label to the number of attributes it is an outlier for.
+	Outliers map[string]int `json:"outliers"`
+}
+
+/*
+Checks if a CompareMatrixOutput is empty
+*/
+func (c *CompareMatrixOutput) IsEmpty() bool {
+	return len(c.Diff.Outputs) == 0 && len(c.Diff.ResourceDrifts) == 0 && len(c.Diff.Resources) == 0
+}
+
+/*
+Builds the rows for each diverging path of each entity across the plans.
+*/
+func getMatrixDivergence(labels []string, diffs []map[string]EntityDiff) map[string]map[string]*CompareMatrixRow {
+	out := map[string]map[string]*CompareMatrixRow{}
+
+	paths := map[string]map[string]bool{}
+	for _, m := range diffs {
+		for address, entDiff := range m {
+			if _, ok := paths[address]; !ok {
+				paths[address] = map[string]bool{}
+			}
+			for path := range entDiff {
+				paths[address][path] = true
+			}
+		}
+	}
+
+	for address, addressPaths := range paths {
+		for path := range addressPaths {
+			row := &CompareMatrixRow{
+				Diffs:    map[string]*Diff{},
+				Outliers: []string{},
+			}
+
+			// Group the labels by their diff. The largest group is the
+			// majority, there is none when several groups tie for largest.
+			groups := map[Diff][]string{}
+			order := []Diff{}
+			for i, label := range labels {
+				d := &Diff{Before: "(empty)", After: "(empty)"}
+				if v, ok := diffs[i][address][path]; ok {
+					d = v
+				}
+				row.Diffs[label] = d
+
+				if _, ok := groups[*d]; !ok {
+					order = append(order, *d)
+				}
+				groups[*d] = append(groups[*d], label)
+			}
+
+			if len(groups) == 1 {
+				continue
+			}
+
+			majority := order[0]
+			tied := false
+			for _, d := range order[1:] {
+				switch {
+				case len(groups[d]) > len(groups[majority]):
+					majority = d
+					tied = false
+				case len(groups[d]) == len(groups[majority]):
+					tied = true
+				}
+			}
+			for _, label := range labels {
+				if tied || *row.Diffs[label] != majority {
+					row.Outliers = append(row.Outliers, label)
+				}
+			}
+
+			if _, ok := out[address]; !ok {
+				out[address] = map[string]*CompareMatrixRow{}
+			}
+			out[address][path] = row
+		}
+	}
+
+	return out
+}
+
+/*
+Compares the output of N Inspects against each other to produce a
+matrix of each diverging attribute against each plan's diff. For each
+attribute, the plans differing from the majority are marked as outliers.
+*/
+func CompareManyInspects(inspects []LabelledInspect) *CompareMatrixOutput {
+	labels := []string{}
+	resources := []map[string]EntityDiff{}
+	outputs := []map[string]EntityDiff{}
+	drifts := []map[string]EntityDiff{}
+	for _, i := range inspects {
+		labels = append(labels, i.Label)
+		resources = append(resources, i.Output.Diff.Resources)
+		outputs = append(outputs, i.Output.Diff.Outputs)
+		drifts = append(drifts, i.Output.Diff.ResourceDrifts)
+	}
+
+	out := &CompareMatrixOutput{
+		Labels: labels,
+		Diff: &CompareMatrix{
+			Resources:      getMatrixDivergence(labels, resources),
+			Outputs:        getMatrixDivergence(labels, outputs),
+			ResourceDrifts: getMatrixDivergence(labels, drifts),
+		},
+		Outliers: map[string]int{},
+	}
+
+	for _, label := range labels {
+		out.Outliers[label] = 0
+	}
+	for _, m := range []map[string]map[string]*CompareMatrixRow{out.Diff.Resources, out.Diff.Outputs, out.Diff.ResourceDrifts} {
+		for _, rows := range m {
+			for _, row := range rows {
+				for _, label := range row.Outliers {
+					out.Outliers[label]++
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+func prettyMatrixEntities(kind, suffix string, labels []string, m map[string]map[string]*CompareMatrixRow) []string {
+	var out []string
+
+	addresses := []string{}
+	for address := range m {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	maxWidth := 0
+	for _, label := range labels {
+		if len(label) > maxWidth {
+			maxWidth = len(label)
+		}
+	}
+
+	for _, address := range addresses {
+		out = append(out, fmt.Sprintf("\n\t\t%s %s\"%s\"%s %s:\n", kind, colorBold, address, colorNone, suffix))
+
+		paths := []string{}
+		for path := range m[address] {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			row := m[address][path]
+			if len(row.Outliers) == len(labels) {
+				out = append(out, fmt.Sprintf("\t\t\t%s (no majority of %v plans):\n", path, len(labels)))
+			} else {
+				// Every plan which is not an outlier shares the majority's diff
+				out = append(out, fmt.Sprintf("\t\t\t%s (%v of %v plans agree):\n", path, len(labels)-len(row.Outliers), len(labels)))
+			}
+
+			outliers := map[string]bool{}
+			for _, label := range row.Outliers {
+				outliers[label] = true
+			}
+
+			for _, label := range labels {
+				diff := row.Diffs[label]
+				marker := ""
+				if outliers[label] {
+					marker = fmt.Sprintf(" %s(outlier)%s", colorOrange, colorNone)
+				}
+				out = append(out, fmt.Sprintf("\t\t\t\t%s:%s%s %s->%s %s%s\n", label, helpers.FillWithSpaces(label, maxWidth), diff.Before, colorOrange, colorNone, diff.After, marker))
+			}
+		}
+	}
+	return out
+}
+
+/*
+Produces a slice of strings output which can be printed line by line
+to get a Terraform-style stdout report of the N-way compare.
+*/
+func (c *CompareMatrixOutput) Pretty() []string {
+	var out []string
+	out = append(out, fmt.Sprintf("\tTerraform plans (%s) differ at the following un-filtered changes:\n", strings.Join(c.Labels, ", ")))
+
+	out = append(out, prettyMatrixEntities("resource", "changes", c.Labels, c.Diff.Resources)...)
+	out = append(out, prettyMatrixEntities("resource", "drift", c.Labels, c.Diff.ResourceDrifts)...)
+	out = append(out, prettyMatrixEntities("output", "changes", c.Labels, c.Diff.Outputs)...)
+
+	for _, label := range c.Labels {
+		if c.Outliers[label] > 0 {
+			out = append(out, fmt.Sprintf("\n\t%s is an outlier at %v attributes\n", label, c.Outliers[label]))
+		}
+	}
+
+	out = append(out, fmt.Sprintf("\n\tChanges: %v resources, %v resource drifts, %v outputs\n", len(c.Diff.Resources), len(c.Diff.ResourceDrifts), len(c.Diff.Outputs)))
+	return out
+}
@@ -0,0 +1,200 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+)
+
+func Test_CompareManyInspects(t *testing.T) {
+	same := &InspectOutput{
+		Diff: &InspectDiff{
+			Resources: map[string]EntityDiff{
+				"aws_instance.this": {
+					".instance_type": {Before: "t2.medium", After: "t2.micro"},
+				},
+			},
+			Outputs:        map[string]EntityDiff{},
+			ResourceDrifts: map[string]EntityDiff{},
+		},
+	}
+
+	cases := map[string]struct {
+		inspects       []LabelledInspect
+		expectedOutput *CompareMatrixOutput
+	}{
+		"no differences": {
+			inspects: []LabelledInspect{
+				{Label: "dev", Output: same},
+				{Label: "staging", Output: same},
+				{Label: "prod", Output: same},
+			},
+			expectedOutput: &CompareMatrixOutput{
+				Labels: []string{"dev", "staging", "prod"},
+				Diff: &CompareMatrix{
+					Resources:      map[string]map[string]*CompareMatrixRow{},
+					Outputs:        map[string]map[string]*CompareMatrixRow{},
+					ResourceDrifts: map[string]map[string]*CompareMatrixRow{},
+				},
+				Outliers: map[string]int{"dev": 0, "staging": 0, "prod": 0},
+			},
+		},
+		"prod outlier": {
+			inspects: []LabelledInspect{
+				{Label: "dev", Output: same},
+				{Label: "staging", Output: same},
+				{
+					Label: "prod",
+					Output: &InspectOutput{
+						Diff: &InspectDiff{
+							Resources: map[string]EntityDiff{
+								"aws_instance.this": {
+									".instance_type": {Before: "t2.medium", After: "t2.large"},
+								},
+							},
+							Outputs: map[string]EntityDiff{
+								"foo-output": {
+									".": {Before: "this", After: "that"},
+								},
+							},
+							ResourceDrifts: map[string]EntityDiff{},
+						},
+					},
+				},
+			},
+			expectedOutput: &CompareMatrixOutput{
+				Labels: []string{"dev", "staging", "prod"},
+				Diff: &CompareMatrix{
+					Resources: map[string]map[string]*CompareMatrixRow{
+						"aws_instance.this": {
+							".instance_type": {
+								Diffs: map[string]*Diff{
+									"dev":     {Before: "t2.medium", After: "t2.micro"},
+									"staging": {Before: "t2.medium", After: "t2.micro"},
+									"prod":    {Before: "t2.medium", After: "t2.large"},
+								},
+								Outliers: []string{"prod"},
+							},
+						},
+					},
+					Outputs: map[string]map[string]*CompareMatrixRow{
+						"foo-output": {
+							".": {
+								Diffs: map[string]*Diff{
+									"dev":     {Before: "(empty)", After: "(empty)"},
+									"staging": {Before: "(empty)", After: "(empty)"},
+									"prod":    {Before: "this", After: "that"},
+								},
+								Outliers: []string{"prod"},
+							},
+						},
+					},
+					ResourceDrifts: map[string]map[string]*CompareMatrixRow{},
+				},
+				Outliers: map[string]int{"dev": 0, "staging": 0, "prod": 2},
+			},
+		},
+		"two plans": {
+			inspects: []LabelledInspect{
+				{Label: "dev", Output: same},
+				{
+					Label: "prod",
+					Output: &InspectOutput{
+						Diff: &InspectDiff{
+							Resources: map[string]EntityDiff{
+								"aws_instance.this": {
+									".instance_type": {Before: "t2.medium", After: "t2.large"},
+								},
+							},
+							Outputs:        map[string]EntityDiff{},
+							ResourceDrifts: map[string]EntityDiff{},
+						},
+					},
+				},
+			},
+			expectedOutput: &CompareMatrixOutput{
+				Labels: []string{"dev", "prod"},
+				Diff: &CompareMatrix{
+					Resources: map[string]map[string]*CompareMatrixRow{
+						"aws_instance.this": {
+							".instance_type": {
+								Diffs: map[string]*Diff{
+									"dev":  {Before: "t2.medium", After: "t2.micro"},
+									"prod": {Before: "t2.medium", After: "t2.large"},
+								},
+								// Neither plan is the majority
+								Outliers: []string{"dev", "prod"},
+							},
+						},
+					},
+					Outputs:        map[string]map[string]*CompareMatrixRow{},
+					ResourceDrifts: map[string]map[string]*CompareMatrixRow{},
+				},
+				Outliers: map[string]int{"dev": 1, "prod": 1},
+			},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut := CompareManyInspects(tst.inspects)
+			diff.Check(t, tst.expectedOutput, gotOut)
+		})
+	}
+}
+
+func Test_CompareMatrixPretty(t *testing.T) {
+	out := &CompareMatrixOutput{
+		Labels: []string{"dev", "staging", "prod"},
+		Diff: &CompareMatrix{
+			Resources: map[string]map[string]*CompareMatrixRow{
+				"aws_instance.this": {
+					".instance_type": {
+						Diffs: map[string]*Diff{
+							"dev":     {Before: "t2.medium", After: "t2.micro"},
+							"staging": {Before: "t2.medium", After: "t2.micro"},
+							"prod":    {Before: "t2.medium", After: "t2.large"},
+						},
+						Outliers: []string{"prod"},
+					},
+				},
+			},
+			Outputs:        map[string]map[string]*CompareMatrixRow{},
+			ResourceDrifts: map[string]map[string]*CompareMatrixRow{},
+		},
+		Outliers: map[string]int{"dev": 0, "staging": 0, "prod": 1},
+	}
+
+	diff.Check(t, []string{
+		"\tTerraform plans (dev, staging, prod) differ at the following un-filtered changes:\n",
+		"\n\t\tresource \x1b[1m\"aws_instance.this\"\x1b[0m changes:\n",
+		"\t\t\t.instance_type (2 of 3 plans agree):\n",
+		"\t\t\t\tdev:     t2.medium \x1b[33m->\x1b[0m t2.micro\n",
+		"\t\t\t\tstaging: t2.medium \x1b[33m->\x1b[0m t2.micro\n",
+		"\t\t\t\tprod:    t2.medium \x1b[33m->\x1b[0m t2.large \x1b[33m(outlier)\x1b[0m\n",
+		"\n\tprod is an outlier at 1 attributes\n",
+		"\n\tChanges: 1 resources, 0 resource drifts, 0 outputs\n",
+	}, out.Pretty())
+}
+
+func Test_CompareMatrixPrettyNoMajority(t *testing.T) {
+	out := CompareManyInspects([]LabelledInspect{
+		{Label: "dev", Output: &InspectOutput{Diff: &InspectDiff{Resources: map[string]EntityDiff{"aws_instance.this": {".instance_type": {Before: "t2.medium", After: "t2.micro"}}}}}},
+		{Label: "staging", Output: &InspectOutput{Diff: &InspectDiff{Resources: map[string]EntityDiff{"aws_instance.this": {".instance_type": {Before: "t2.medium", After: "t2.small"}}}}}},
+		{Label: "prod", Output: &InspectOutput{Diff: &InspectDiff{Resources: map[string]EntityDiff{"aws_instance.this": {".instance_type": {Before: "t2.medium", After: "t2.large"}}}}}},
+	})
+
+	diff.Check(t, []string{
+		"\tTerraform plans (dev, staging, prod) differ at the following un-filtered changes:\n",
+		"\n\t\tresource \x1b[1m\"aws_instance.this\"\x1b[0m changes:\n",
+		"\t\t\t.instance_type (no majority of 3 plans):\n",
+		"\t\t\t\tdev:     t2.medium \x1b[33m->\x1b[0m t2.micro \x1b[33m(outlier)\x1b[0m\n",
+		"\t\t\t\tstaging: t2.medium \x1b[33m->\x1b[0m t2.small \x1b[33m(outlier)\x1b[0m\n",
+		"\t\t\t\tprod:    t2.medium \x1b[33m->\x1b[0m t2.large \x1b[33m(outlier)\x1b[0m\n",
+		"\n\tdev is an outlier at 1 attributes\n",
+		"\n\tstaging is an outlier at 1 attributes\n",
+		"\n\tprod is an outlier at 1 attributes\n",
+		"\n\tChanges: 1 resources, 0 resource drifts, 0 outputs\n",
+	}, out.Pretty())
+}
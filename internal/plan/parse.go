@@ -1,9 +1,13 @@
 package plan
 
 import (
+	"crypto/ed25519"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 
+	"github.com/hashicorp/go-version"
 	tfJson "github.com/hashicorp/terraform-json"
 )
 
@@ -11,17 +15,46 @@ type Plan tfJson.Plan
 
 /*
 Takes a simple JSON Terraform plan and parses it into a struct.
-Errors if there is a problem parsing the json.
+Errors if there is a problem parsing the json or the plan's format
+version is not supported.
 */
 func ParsePlan(data []byte) (*Plan, error) {
 
 	p := &Plan{}
 	if err := json.Unmarshal(data, p); err != nil {
-		return nil, fmt.Errorf("unable to unmarshal terraform plan caused by: %v", err)
+		return nil, &Error{Kind: ErrInvalidPlan, Message: "unable to unmarshal terraform plan", Err: err}
+	}
+
+	if err := checkFormatVersion(p.FormatVersion); err != nil {
+		return nil, err
 	}
 	return p, nil
 }
 
+/*
+Checks the format version against the versions supported by
+terraform-json. Plans without a format version are let through.
+*/
+func checkFormatVersion(formatVersion string) error {
+	if formatVersion == "" {
+		return nil
+	}
+
+	constraints, err := version.NewConstraint(tfJson.PlanFormatVersionConstraints)
+	if err != nil {
+		return fmt.Errorf("unable to parse format version constraints caused by: %w", err)
+	}
+
+	v, err := version.NewVersion(formatVersion)
+	if err != nil {
+		return &Error{Kind: ErrUnsupportedFormatVersion, Message: fmt.Sprintf("unable to parse plan format version %s", formatVersion), Err: err}
+	}
+	if !constraints.Check(v) {
+		return &Error{Kind: ErrUnsupportedFormatVersion, Message: fmt.Sprintf("unsupported plan format version %s, expected %s", formatVersion, tfJson.PlanFormatVersionConstraints)}
+	}
+	return nil
+}
+
 /*
 Parses Json byte data into an InspectFilter
 */
@@ -29,7 +62,149 @@ func ParseInspectFilter(data []byte) (*InspectFilter, error) {
 
 	i := &InspectFilter{}
 	if err := json.Unmarshal(data, i); err != nil {
-		return nil, fmt.Errorf("unable to unmarshal inspect filter caused by: %v", err)
+		return nil, &Error{Kind: ErrInvalidFilter, Message: "unable to unmarshal inspect filter", Err: err}
 	}
 	return i, nil
 }
+
+/*
+Parses Json byte data into a PolicySet
+*/
+func ParsePolicySet(data []byte) (*PolicySet, error) {
+
+	s := &PolicySet{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal policy set caused by: %w", err)
+	}
+	return s, nil
+}
+
+/*
+Parses Json byte data into RewriteRules
+*/
+func ParseRewriteRules(data []byte) (*RewriteRules, error) {
+
+	r := &RewriteRules{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal rewrite rules caused by: %w", err)
+	}
+	return r, nil
+}
+
+/*
+Parses Json byte data into a Snapshot. Errors if the snapshot version is
+unsupported or its content does not match its hash.
+*/
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+
+	s := &Snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal snapshot caused by: %w", err)
+	}
+
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %v", s.Version)
+	}
+
+	// The stored bytes are hashed, so the hash does not depend on how the
+	// content unmarshals
+	hash, err := hashSnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+	if hash != s.Hash {
+		return nil, fmt.Errorf("snapshot hash %s does not match its content", s.Hash)
+	}
+	return s, nil
+}
+
+/*
+Parses the metadata of a simple JSON Terraform plan, including fields
+not held by Plan such as applyable and errored.
+*/
+func ParsePlanMetadata(data []byte) (*PlanMetadata, error) {
+
+	m := &PlanMetadata{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal terraform plan metadata caused by: %w", err)
+	}
+	return m, nil
+}
+
+/*
+Parses Json byte data into an InspectOutput, e.g. the output of inspect.
+*/
+func ParseInspectOutput(data []byte) (*InspectOutput, error) {
+
+	o := &InspectOutput{}
+	if err := json.Unmarshal(data, o); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal inspect output caused by: %w", err)
+	}
+	if o.Diff == nil {
+		return nil, fmt.Errorf("inspect output has no diff")
+	}
+	return o, nil
+}
+
+/*
+Parses Json byte data into a CompareInspectsOutput, e.g. the output of
+compare.
+*/
+func ParseCompareInspectsOutput(data []byte) (*CompareInspectsOutput, error) {
+
+	c := &CompareInspectsOutput{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal compare output caused by: %w", err)
+	}
+	if c.Diff == nil {
+		return nil, fmt.Errorf("compare output has no diff")
+	}
+	return c, nil
+}
+
+/*
+Parses Json byte data into an Approval.
+*/
+func ParseApproval(data []byte) (*Approval, error) {
+
+	a := &Approval{}
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal approval caused by: %w", err)
+	}
+	return a, nil
+}
+
+/*
+Parses Json byte data into AllowedSigners.
+*/
+func ParseAllowedSigners(data []byte) (*AllowedSigners, error) {
+
+	s := &AllowedSigners{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal allowed signers caused by: %w", err)
+	}
+	return s, nil
+}
+
+/*
+Parses a PEM encoded PKCS #8 ed25519 private key, e.g. generated with
+openssl genpkey -algorithm ed25519.
+*/
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("private key is not a PEM encoded PRIVATE KEY")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key caused by: %w", err)
+	}
+
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an ed25519 key")
+	}
+	return edKey, nil
+}
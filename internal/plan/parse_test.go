@@ -17,6 +17,7 @@ func Test_ParsePlan(t *testing.T) {
 		jsonPlan       []byte
 		expectedOutput *Plan
 		expectedError  error
+		expectedKind   error
 	}{
 		"no error": {
 			jsonPlan: []byte(`
@@ -83,6 +84,25 @@ func Test_ParsePlan(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		"supported format version": {
+			jsonPlan:       []byte(`{"format_version": "1.2"}`),
+			expectedOutput: &Plan{FormatVersion: "1.2"},
+		},
+		"unsupported format version": {
+			jsonPlan:      []byte(`{"format_version": "2.0"}`),
+			expectedError: fmt.Errorf("unsupported plan format version 2.0, expected >= 0.1, < 2.0"),
+			expectedKind:  ErrUnsupportedFormatVersion,
+		},
+		"invalid format version": {
+			jsonPlan:      []byte(`{"format_version": "one"}`),
+			expectedError: fmt.Errorf("unable to parse plan format version one caused by: Malformed version: one"),
+			expectedKind:  ErrUnsupportedFormatVersion,
+		},
+		"json error": {
+			jsonPlan:      []byte(`{`),
+			expectedError: fmt.Errorf("unable to unmarshal terraform plan caused by: unexpected end of JSON input"),
+			expectedKind:  ErrInvalidPlan,
+		},
 	}
 
 	for name, tst := range cases {
@@ -90,7 +110,12 @@ func Test_ParsePlan(t *testing.T) {
 			t.Parallel()
 			gotOut, gotError := ParsePlan(tst.jsonPlan)
 
-			assert.Equal(t, tst.expectedError, gotError)
+			if tst.expectedError == nil {
+				assert.Nil(t, gotError)
+			} else {
+				assert.EqualError(t, gotError, tst.expectedError.Error())
+				assert.ErrorIs(t, gotError, tst.expectedKind)
+			}
 			diff.Check(t, tst.expectedOutput, gotOut, cmpopts.IgnoreUnexported(Plan{}))
 		})
 	}
@@ -150,12 +175,12 @@ func Test_ParseInspectFilter(t *testing.T) {
 			expectedOutput: &InspectFilter{
 				OutputChanges: []Filter{
 					{
-						NamePattern: "foo_output_name",
-						DiffPatterns: map[string][]Diff{
+						NamePattern: Patterns{"foo_output_name"},
+						DiffPatterns: map[string][]DiffPattern{
 							"._foo_output_path": {
 								{
-									Before: "foo_output_before",
-									After:  "foo_output_after",
+									Before: Patterns{"foo_output_before"},
+									After:  Patterns{"foo_output_after"},
 								},
 							},
 						},
@@ -163,12 +188,12 @@ func Test_ParseInspectFilter(t *testing.T) {
 				},
 				ResourceChanges: []Filter{
 					{
-						NamePattern: "foo_resource_name",
-						DiffPatterns: map[string][]Diff{
+						NamePattern: Patterns{"foo_resource_name"},
+						DiffPatterns: map[string][]DiffPattern{
 							"._foo_resource_path": {
 								{
-									Before: "foo_resource_before",
-									After:  "foo_resource_after",
+									Before: Patterns{"foo_resource_before"},
+									After:  Patterns{"foo_resource_after"},
 								},
 							},
 						},
@@ -176,12 +201,12 @@ func Test_ParseInspectFilter(t *testing.T) {
 				},
 				DriftChanges: []Filter{
 					{
-						NamePattern: "foo_drift_resource_name",
-						DiffPatterns: map[string][]Diff{
+						NamePattern: Patterns{"foo_drift_resource_name"},
+						DiffPatterns: map[string][]DiffPattern{
 							"._foo_drift_resource_path": {
 								{
-									Before: "foo_drift_resource_before",
-									After:  "foo_drift_resource_after",
+									Before: Patterns{"foo_drift_resource_before"},
+									After:  Patterns{"foo_drift_resource_after"},
 								},
 							},
 						},
@@ -190,6 +215,46 @@ func Test_ParseInspectFilter(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		"pattern lists": {
+			jsonFilter: []byte(`
+				{
+					"resourceChanges": [
+						{
+							"namePattern": ["aws_lambda_function.*", "!aws_lambda_function.authorizer"],
+							"diffPatterns": {
+								"!.role": [
+									{
+										"before": "*",
+										"after": ["*", "!(known after apply)"]
+									}
+								]
+							}
+						}
+					]
+				}
+			`),
+			expectedOutput: &InspectFilter{
+				ResourceChanges: []Filter{
+					{
+						NamePattern: Patterns{"aws_lambda_function.*", "!aws_lambda_function.authorizer"},
+						DiffPatterns: map[string][]DiffPattern{
+							"!.role": {
+								{
+									Before: Patterns{"*"},
+									After:  Patterns{"*", "!(known after apply)"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		"pattern type error": {
+			jsonFilter:     []byte(`{"resourceChanges": [{"namePattern": 1}]}`),
+			expectedOutput: nil,
+			expectedError:  fmt.Errorf("unable to unmarshal inspect filter caused by: patterns must be a string or a list of strings"),
+		},
 		"json error": {
 			jsonFilter:     []byte(``),
 			expectedOutput: nil,
@@ -202,8 +267,29 @@ func Test_ParseInspectFilter(t *testing.T) {
 			t.Parallel()
 			gotOut, gotError := ParseInspectFilter(tst.jsonFilter)
 
-			assert.Equal(t, tst.expectedError, gotError)
+			if tst.expectedError == nil {
+				assert.Nil(t, gotError)
+			} else {
+				assert.EqualError(t, gotError, tst.expectedError.Error())
+				assert.ErrorIs(t, gotError, ErrInvalidFilter)
+			}
 			diff.Check(t, tst.expectedOutput, gotOut, cmpopts.IgnoreUnexported(Plan{}))
 		})
 	}
 }
+
+func Test_ParseInspectOutput(t *testing.T) {
+	out, err := ParseInspectOutput([]byte(`{"diff": {"resources": {"aws_vpc.main": {".cidr_block": {"before": "a", "after": "b"}}}}}`))
+	assert.Nil(t, err)
+	diff.Check(t, &InspectOutput{
+		Diff: &InspectDiff{
+			Resources: map[string]EntityDiff{"aws_vpc.main": {".cidr_block": {Before: "a", After: "b"}}},
+		},
+	}, out)
+
+	_, err = ParseInspectOutput([]byte(`{}`))
+	assert.EqualError(t, err, "inspect output has no diff")
+
+	_, err = ParseCompareInspectsOutput([]byte(`{"diff": []}`))
+	assert.ErrorContains(t, err, "unable to unmarshal compare output caused by:")
+}
@@ -0,0 +1,252 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/helpers"
+	"github.com/vodkaslime/wildcard"
+)
+
+const (
+	// The resource satisfies the policy.
+	PolicyStatusPass = "pass"
+	// The resource violates a policy with warn severity.
+	PolicyStatusWarn = "warn"
+	// The resource violates a policy with fail severity.
+	PolicyStatusFail = "fail"
+)
+
+// A policy written as a CEL expression evaluated against each planned
+// resource change.
+type Policy struct {
+	// Name of the policy used when reporting results.
+	Name string `json:"name"`
+	// Optional description of the policy used when reporting violations.
+	Description string `json:"description"`
+	// Optional wildcard-supported patterns to match against resource
+	// addresses. Defaults to all resources.
+	NamePattern Patterns `json:"namePattern"`
+	// CEL expression which must evaluate to true for the resource to pass.
+	// The variables address, resource and diff are available. See
+	// Readme.md for their shape.
+	Expression string `json:"expression"`
+	// Status reported when the expression evaluates to false. Either
+	// PolicyStatusWarn or PolicyStatusFail (default).
+	Severity string `json:"severity"`
+}
+
+type PolicySet struct {
+	// Policies to evaluate against the plan.
+	Policies []Policy `json:"policies"`
+}
+
+// Result of evaluating a single policy against a single resource.
+type PolicyResult struct {
+	// Name of the evaluated policy.
+	Policy string `json:"policy"`
+	// Address of the evaluated resource.
+	Address string `json:"address"`
+	// One of PolicyStatusPass, PolicyStatusWarn or PolicyStatusFail.
+	Status string `json:"status"`
+	// Description of the policy for violations.
+	Message string `json:"message,omitempty"`
+	// The changes of the resource for violations.
+	Diff EntityDiff `json:"diff,omitempty"`
+}
+
+// Result of calling EvaluatePolicies() on a Terraform plan.
+type PolicyOutput struct {
+	// Results of each policy against each matching resource.
+	Results []PolicyResult `json:"results"`
+	// Count of results by status.
+	Summary map[string]int `json:"summary"`
+}
+
+/*
+Checks if a PolicyOutput has no failures
+*/
+func (o *PolicyOutput) IsEmpty() bool {
+	return o.Summary[PolicyStatusFail] == 0
+}
+
+func policyEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("address", cel.StringType),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("diff", cel.MapType(cel.StringType, cel.MapType(cel.StringType, cel.StringType))),
+	)
+}
+
+/*
+Builds the CEL variables for a resource change.
+*/
+func policyVars(rChange *tfJson.ResourceChange, entityDiff EntityDiff) map[string]any {
+	actions := []string{}
+	var before, after, afterUnknown any
+	if rChange.Change != nil {
+		for _, a := range rChange.Change.Actions {
+			actions = append(actions, string(a))
+		}
+		before, after, afterUnknown = rChange.Change.Before, rChange.Change.After, rChange.Change.AfterUnknown
+	}
+
+	diff := map[string]map[string]string{}
+	for path, d := range entityDiff {
+		diff[path] = map[string]string{"before": d.Before, "after": d.After}
+	}
+
+	return map[string]any{
+		"address": rChange.Address,
+		"resource": map[string]any{
+			"address":        rChange.Address,
+			"module_address": rChange.ModuleAddress,
+			"mode":           string(rChange.Mode),
+			"type":           rChange.Type,
+			"name":           rChange.Name,
+			"provider_name":  rChange.ProviderName,
+			"actions":        actions,
+			"before":         before,
+			"after":          after,
+			"after_unknown":  afterUnknown,
+		},
+		"diff": diff,
+	}
+}
+
+/*
+Evaluates each policy in the set against each managed resource change in
+the plan it applies to.
+*/
+func (p *Plan) EvaluatePolicies(set *PolicySet) (*PolicyOutput, error) {
+	env, err := policyEnv()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create policy environment caused by: %w", err)
+	}
+
+	programs := make([]cel.Program, len(set.Policies))
+	for i, policy := range set.Policies {
+		switch policy.Severity {
+		case "", PolicyStatusWarn, PolicyStatusFail:
+		default:
+			return nil, fmt.Errorf("unknown severity %s for policy %s", policy.Severity, policy.Name)
+		}
+
+		ast, iss := env.Compile(policy.Expression)
+		if iss.Err() != nil {
+			return nil, fmt.Errorf("unable to compile policy %s caused by: %w", policy.Name, iss.Err())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("policy %s must evaluate to a bool", policy.Name)
+		}
+		programs[i], err = env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compile policy %s caused by: %w", policy.Name, err)
+		}
+	}
+
+	out := &PolicyOutput{
+		Results: []PolicyResult{},
+		Summary: map[string]int{PolicyStatusPass: 0, PolicyStatusWarn: 0, PolicyStatusFail: 0},
+	}
+
+	m := wildcard.NewMatcher()
+	for _, rChange := range p.ResourceChanges {
+		if strings.HasPrefix(rChange.Address, "data.") {
+			continue
+		}
+
+		entityDiff := EntityDiff{}
+		if rChange.Change != nil {
+			entityDiff = parseChange(rChange.Change)
+		}
+		vars := policyVars(rChange, entityDiff)
+
+		for i, policy := range set.Policies {
+			if len(policy.NamePattern) > 0 {
+				if match, err := policy.NamePattern.match(m, rChange.Address); err != nil {
+					return nil, fmt.Errorf("unable to match %s for policy %s caused by: %w", rChange.Address, policy.Name, err)
+				} else if !match {
+					continue
+				}
+			}
+
+			val, _, err := programs[i].Eval(vars)
+			if err != nil {
+				return nil, fmt.Errorf("unable to evaluate policy %s against %s caused by: %w", policy.Name, rChange.Address, err)
+			}
+			pass, ok := val.Value().(bool)
+			if !ok {
+				return nil, fmt.Errorf("policy %s must evaluate to a bool but got %v for %s", policy.Name, val.Value(), rChange.Address)
+			}
+
+			result := PolicyResult{
+				Policy:  policy.Name,
+				Address: rChange.Address,
+				Status:  PolicyStatusPass,
+			}
+			if !pass {
+				result.Status = PolicyStatusFail
+				if policy.Severity == PolicyStatusWarn {
+					result.Status = PolicyStatusWarn
+				}
+				result.Message = policy.Description
+				result.Diff = entityDiff
+			}
+
+			out.Summary[result.Status]++
+			out.Results = append(out.Results, result)
+		}
+	}
+
+	sort.SliceStable(out.Results, func(i, j int) bool {
+		if out.Results[i].Address != out.Results[j].Address {
+			return out.Results[i].Address < out.Results[j].Address
+		}
+		return out.Results[i].Policy < out.Results[j].Policy
+	})
+
+	return out, nil
+}
+
+/*
+Produces a slice of strings output which can be printed line by line
+to get a Terraform-style stdout report of the policy evaluation. Only
+violations are reported.
+*/
+func (o *PolicyOutput) Pretty() []string {
+	var out []string
+	out = append(out, "\tTerraform plan violated the following policies:\n")
+
+	for _, result := range o.Results {
+		if result.Status == PolicyStatusPass {
+			continue
+		}
+
+		out = append(out, fmt.Sprintf("\n\t\t%s policy %s\"%s\"%s on resource %s\"%s\"%s:\n", result.Status, colorBold, result.Policy, colorNone, colorBold, result.Address, colorNone))
+		if result.Message != "" {
+			out = append(out, fmt.Sprintf("\t\t\t%s\n", result.Message))
+		}
+
+		maxWidth := 0
+		paths := []string{}
+		for path := range result.Diff {
+			paths = append(paths, path)
+			if len(path) > maxWidth {
+				maxWidth = len(path)
+			}
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			diff := result.Diff[path]
+			out = append(out, fmt.Sprintf("\t\t\t%s:%s%s %s->%s %s\n", path, helpers.FillWithSpaces(path, maxWidth), diff.Before, colorOrange, colorNone, diff.After))
+		}
+	}
+
+	out = append(out, fmt.Sprintf("\n\tPolicies: %v passed, %v warnings, %v failures\n", o.Summary[PolicyStatusPass], o.Summary[PolicyStatusWarn], o.Summary[PolicyStatusFail]))
+	return out
+}
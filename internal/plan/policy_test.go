@@ -0,0 +1,171 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EvaluatePolicies(t *testing.T) {
+	tfplan := &Plan{
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: "aws_security_group_rule.ingress",
+				Type:    "aws_security_group_rule",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionUpdate},
+					Before:  map[string]any{"cidr_blocks": []any{"10.0.0.0/8"}},
+					After:   map[string]any{"cidr_blocks": []any{"0.0.0.0/0"}},
+				},
+			},
+			{
+				Address: "aws_s3_bucket.this",
+				Type:    "aws_s3_bucket",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionDelete},
+					Before:  map[string]any{"bucket": "foo"},
+					After:   nil,
+				},
+			},
+			{
+				Address: "data.aws_caller_identity.this",
+				Type:    "aws_caller_identity",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionRead},
+				},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		policies       *PolicySet
+		expectedOutput *PolicyOutput
+		expectedError  error
+	}{
+		"fail and warn": {
+			policies: &PolicySet{
+				Policies: []Policy{
+					{
+						Name:        "no-public-ingress",
+						Description: "ingress must not be open to the world",
+						NamePattern: Patterns{"aws_security_group_rule.*"},
+						Expression:  `!("0.0.0.0/0" in resource.after.cidr_blocks)`,
+					},
+					{
+						Name:       "no-delete",
+						Expression: `!("delete" in resource.actions)`,
+						Severity:   PolicyStatusWarn,
+					},
+				},
+			},
+			expectedOutput: &PolicyOutput{
+				Results: []PolicyResult{
+					{
+						Policy:  "no-delete",
+						Address: "aws_s3_bucket.this",
+						Status:  PolicyStatusWarn,
+						Diff: EntityDiff{
+							".bucket": {Before: "foo", After: "(empty)"},
+						},
+					},
+					{
+						Policy:  "no-delete",
+						Address: "aws_security_group_rule.ingress",
+						Status:  PolicyStatusPass,
+					},
+					{
+						Policy:  "no-public-ingress",
+						Address: "aws_security_group_rule.ingress",
+						Status:  PolicyStatusFail,
+						Message: "ingress must not be open to the world",
+						Diff: EntityDiff{
+							".cidr_blocks.[0]": {Before: "10.0.0.0/8", After: "0.0.0.0/0"},
+						},
+					},
+				},
+				Summary: map[string]int{PolicyStatusPass: 1, PolicyStatusWarn: 1, PolicyStatusFail: 1},
+			},
+		},
+		"diff variable": {
+			policies: &PolicySet{
+				Policies: []Policy{
+					{
+						Name:        "bucket-name-stable",
+						NamePattern: Patterns{"aws_s3_bucket.*"},
+						Expression:  `!(".bucket" in diff) || diff[".bucket"].after != "(empty)"`,
+					},
+				},
+			},
+			expectedOutput: &PolicyOutput{
+				Results: []PolicyResult{
+					{
+						Policy:  "bucket-name-stable",
+						Address: "aws_s3_bucket.this",
+						Status:  PolicyStatusFail,
+						Diff: EntityDiff{
+							".bucket": {Before: "foo", After: "(empty)"},
+						},
+					},
+				},
+				Summary: map[string]int{PolicyStatusPass: 0, PolicyStatusWarn: 0, PolicyStatusFail: 1},
+			},
+		},
+		"unknown severity": {
+			policies: &PolicySet{
+				Policies: []Policy{
+					{Name: "foo", Expression: "true", Severity: "info"},
+				},
+			},
+			expectedError: fmt.Errorf("unknown severity info for policy foo"),
+		},
+		"not a bool": {
+			policies: &PolicySet{
+				Policies: []Policy{
+					{Name: "foo", Expression: `"bar"`},
+				},
+			},
+			expectedError: fmt.Errorf("policy foo must evaluate to a bool"),
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := tfplan.EvaluatePolicies(tst.policies)
+
+			assert.Equal(t, tst.expectedError, gotError)
+			if tst.expectedOutput != nil {
+				diff.Check(t, tst.expectedOutput, gotOut)
+			}
+		})
+	}
+}
+
+func Test_PolicyPretty(t *testing.T) {
+	out := &PolicyOutput{
+		Results: []PolicyResult{
+			{Policy: "no-delete", Address: "aws_s3_bucket.this", Status: PolicyStatusPass},
+			{
+				Policy:  "no-public-ingress",
+				Address: "aws_security_group_rule.ingress",
+				Status:  PolicyStatusFail,
+				Message: "ingress must not be open to the world",
+				Diff: EntityDiff{
+					".cidr_blocks.[0]": {Before: "10.0.0.0/8", After: "0.0.0.0/0"},
+				},
+			},
+		},
+		Summary: map[string]int{PolicyStatusPass: 1, PolicyStatusWarn: 0, PolicyStatusFail: 1},
+	}
+
+	diff.Check(t, []string{
+		"\tTerraform plan violated the following policies:\n",
+		"\n\t\tfail policy \x1b[1m\"no-public-ingress\"\x1b[0m on resource \x1b[1m\"aws_security_group_rule.ingress\"\x1b[0m:\n",
+		"\t\t\tingress must not be open to the world\n",
+		"\t\t\t.cidr_blocks.[0]: 10.0.0.0/8 \x1b[33m->\x1b[0m 0.0.0.0/0\n",
+		"\n\tPolicies: 1 passed, 0 warnings, 1 failures\n",
+	}, out.Pretty())
+}
@@ -0,0 +1,342 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/jmespath/go-jmespath"
+)
+
+const (
+	// Render the query result as JSON.
+	QueryFormatJSON = "json"
+	// Render the query result as plain text lines.
+	QueryFormatText = "text"
+	// Render the query result as a Markdown table.
+	QueryFormatMarkdown = "markdown"
+)
+
+// A changed attribute of a QueryChange.
+type QueryAttribute struct {
+	// The flattened path of the attribute, e.g. .cidr_blocks.[0].
+	Path string `json:"path"`
+	// The value of the attribute before the planned change.
+	Before string `json:"before"`
+	// The value of the attribute after the planned change.
+	After string `json:"after"`
+}
+
+// A planned change of a resource, drift or output as exposed to queries.
+type QueryChange struct {
+	// The kind of entity. One of resource, output or drift.
+	Kind string `json:"kind"`
+	// The address of the resource or name of the output.
+	Address string `json:"address"`
+	// The resource type. Empty for outputs.
+	Type string `json:"type"`
+	// The module address of the resource. Empty for the root module and
+	// outputs.
+	Module string `json:"module"`
+	// The provider name of the resource. Empty for outputs.
+	Provider string `json:"provider"`
+	// The planned actions, e.g. ["delete", "create"].
+	Actions []string `json:"actions"`
+	// The changed attributes sorted by path.
+	Attributes []QueryAttribute `json:"attributes"`
+	// The changed attributes keyed by path.
+	Diff EntityDiff `json:"diff"`
+}
+
+type QueryInput struct {
+	// JMESPath expression evaluated against an object holding the plan
+	// (plan) and its changes (changes).
+	Expression string
+	// Optional filter to apply to the changes before querying.
+	Filter *InspectFilter
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string
+	// Optional context which stops the operation early when it ends.
+	Context context.Context
+}
+
+// Result of calling Query() to query a plan.
+type QueryOutput struct {
+	// The result of the expression.
+	Result any `json:"result"`
+}
+
+/*
+Checks if a QueryOutput is empty
+*/
+func (q *QueryOutput) IsEmpty() bool {
+	switch r := q.Result.(type) {
+	case nil:
+		return true
+	case []any:
+		return len(r) == 0
+	case map[string]any:
+		return len(r) == 0
+	case bool:
+		return !r
+	}
+	return false
+}
+
+func queryChanges(kind string, diffs map[string]EntityDiff, changes map[string]*tfJson.ResourceChange, outputs map[string]*tfJson.Change) []QueryChange {
+	out := []QueryChange{}
+	for _, address := range sortedKeys(diffs) {
+		change := QueryChange{
+			Kind:       kind,
+			Address:    address,
+			Actions:    []string{},
+			Attributes: []QueryAttribute{},
+			Diff:       diffs[address],
+		}
+
+		var actions tfJson.Actions
+		if rChange, ok := changes[address]; ok {
+			change.Type = rChange.Type
+			change.Module = rChange.ModuleAddress
+			change.Provider = rChange.ProviderName
+			if rChange.Change != nil {
+				actions = rChange.Change.Actions
+			}
+		}
+		if oChange, ok := outputs[address]; ok && oChange != nil {
+			actions = oChange.Actions
+		}
+		for _, a := range actions {
+			change.Actions = append(change.Actions, string(a))
+		}
+
+		for _, path := range sortedKeys(diffs[address]) {
+			diff := diffs[address][path]
+			change.Attributes = append(change.Attributes, QueryAttribute{Path: path, Before: diff.Before, After: diff.After})
+		}
+		out = append(out, change)
+	}
+	return out
+}
+
+/*
+Converts a value into the maps, slices and scalars JMESPath works with.
+*/
+func toJMESPathValue(v any) (any, error) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(bytes, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+/*
+Replaces the parts of value marked as sensitive, either the whole value or
+the keys and elements of its maps and lists, with "(sensitive value)" as
+inspect does.
+*/
+func redactSensitive(value, sensitive any) any {
+	switch s := sensitive.(type) {
+	case bool:
+		if s && value != nil {
+			return "(sensitive value)"
+		}
+	case map[string]any:
+		if v, ok := value.(map[string]any); ok {
+			for k := range s {
+				if _, ok := v[k]; ok {
+					v[k] = redactSensitive(v[k], s[k])
+				}
+			}
+		}
+	case []any:
+		if v, ok := value.([]any); ok {
+			for i := range s {
+				if i < len(v) {
+					v[i] = redactSensitive(v[i], s[i])
+				}
+			}
+		}
+	}
+	return value
+}
+
+/*
+Redacts the sensitive values of the JMESPath value of a plan in place: the
+before and after values of its changes and the values of its prior state
+and planned values.
+*/
+func redactQueryPlan(plan any) {
+	p, ok := plan.(map[string]any)
+	if !ok {
+		return
+	}
+
+	redactChange := func(c any) {
+		if change, ok := c.(map[string]any); ok {
+			change["before"] = redactSensitive(change["before"], change["before_sensitive"])
+			change["after"] = redactSensitive(change["after"], change["after_sensitive"])
+		}
+	}
+	for _, key := range []string{"resource_changes", "resource_drift"} {
+		changes, _ := p[key].([]any)
+		for _, c := range changes {
+			if rChange, ok := c.(map[string]any); ok {
+				redactChange(rChange["change"])
+			}
+		}
+	}
+	outputs, _ := p["output_changes"].(map[string]any)
+	for _, c := range outputs {
+		redactChange(c)
+	}
+
+	var redactModule func(m any)
+	redactModule = func(m any) {
+		module, ok := m.(map[string]any)
+		if !ok {
+			return
+		}
+		resources, _ := module["resources"].([]any)
+		for _, r := range resources {
+			if resource, ok := r.(map[string]any); ok {
+				resource["values"] = redactSensitive(resource["values"], resource["sensitive_values"])
+			}
+		}
+		children, _ := module["child_modules"].([]any)
+		for _, child := range children {
+			redactModule(child)
+		}
+	}
+	redactValues := func(v any) {
+		values, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		redactModule(values["root_module"])
+		outputs, _ := values["outputs"].(map[string]any)
+		for _, o := range outputs {
+			if output, ok := o.(map[string]any); ok {
+				output["value"] = redactSensitive(output["value"], output["sensitive"])
+			}
+		}
+	}
+	redactValues(p["planned_values"])
+	if state, ok := p["prior_state"].(map[string]any); ok {
+		redactValues(state["values"])
+	}
+}
+
+/*
+Evaluates the JMESPath expression against the plan and its changes. The
+expression is evaluated against an object holding the plan JSON as plan and
+the changes not filtered out by the filter as changes. See QueryChange for
+the shape of each change. Sensitive values are redacted as in inspect, so
+they are not part of the result in any format.
+*/
+func (p *Plan) Query(params *QueryInput) (*QueryOutput, error) {
+	expression, err := jmespath.Compile(params.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query expression caused by: %w", err)
+	}
+
+	inspect, err := p.Inspect(&InspectInput{Filter: params.Filter, Set: params.Set, Context: params.Context})
+	if err != nil {
+		return nil, err
+	}
+
+	resources := map[string]*tfJson.ResourceChange{}
+	for _, rChange := range p.ResourceChanges {
+		resources[rChange.Address] = rChange
+	}
+	drifts := map[string]*tfJson.ResourceChange{}
+	for _, dChange := range p.ResourceDrift {
+		drifts[dChange.Address] = dChange
+	}
+
+	changes := queryChanges("resource", inspect.Diff.Resources, resources, nil)
+	changes = append(changes, queryChanges("drift", inspect.Diff.ResourceDrifts, drifts, nil)...)
+	changes = append(changes, queryChanges("output", inspect.Diff.Outputs, nil, p.OutputChanges)...)
+
+	data, err := toJMESPathValue(map[string]any{
+		"plan":    p,
+		"changes": changes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare plan for query caused by: %w", err)
+	}
+	redactQueryPlan(data.(map[string]any)["plan"])
+
+	result, err := expression.Search(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to evaluate query expression caused by: %w", err)
+	}
+
+	return &QueryOutput{Result: result}, nil
+}
+
+/*
+Produces the lines of the result. A scalar or list of scalars is printed
+one value per line. Anything else is flattened into path: value lines.
+*/
+func (q *QueryOutput) Text() []string {
+	var out []string
+
+	isScalar := func(v any) bool {
+		switch v.(type) {
+		case map[string]any, []any:
+			return false
+		}
+		return true
+	}
+
+	switch r := q.Result.(type) {
+	case nil:
+		return out
+	case []any:
+		scalars := true
+		for _, v := range r {
+			scalars = scalars && isScalar(v)
+		}
+		if scalars {
+			for _, v := range r {
+				out = append(out, fmt.Sprintf("%v\n", v))
+			}
+			return out
+		}
+	default:
+		if isScalar(r) {
+			return append(out, fmt.Sprintf("%v\n", r))
+		}
+	}
+
+	kvPairs := map[string]string{}
+	flatten("", q.Result, kvPairs)
+	for _, path := range sortedKeys(kvPairs) {
+		out = append(out, fmt.Sprintf("%s: %s\n", path, kvPairs[path]))
+	}
+	return out
+}
+
+/*
+Produces the lines of a Markdown table of the flattened result.
+*/
+func (q *QueryOutput) Markdown() []string {
+	var out []string
+	out = append(out, "| Path | Value |\n")
+	out = append(out, "| --- | --- |\n")
+
+	kvPairs := map[string]string{}
+	flatten("", q.Result, kvPairs)
+	for _, path := range sortedKeys(kvPairs) {
+		value := strings.ReplaceAll(kvPairs[path], "|", "\\|")
+		out = append(out, fmt.Sprintf("| `%s` | %s |\n", path, value))
+	}
+	return out
+}
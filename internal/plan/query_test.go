@@ -0,0 +1,160 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Query(t *testing.T) {
+	p := &Plan{
+		FormatVersion: "1.2",
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address:      "aws_security_group_rule.open",
+				Type:         "aws_security_group_rule",
+				ProviderName: "registry.terraform.io/hashicorp/aws",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionUpdate},
+					Before:  map[string]any{"cidr_blocks": []any{"10.0.0.0/8"}},
+					After:   map[string]any{"cidr_blocks": []any{"10.0.0.0/8", "0.0.0.0/0"}},
+				},
+			},
+			{
+				Address:      "aws_security_group_rule.closed",
+				Type:         "aws_security_group_rule",
+				ProviderName: "registry.terraform.io/hashicorp/aws",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionUpdate},
+					Before:  map[string]any{"cidr_blocks": []any{"10.0.0.0/8"}},
+					After:   map[string]any{"cidr_blocks": []any{"192.168.0.0/16"}},
+				},
+			},
+			{
+				Address:      "aws_instance.this",
+				Type:         "aws_instance",
+				ProviderName: "registry.terraform.io/hashicorp/aws",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionCreate},
+					After:   map[string]any{"ami": "ami-1"},
+				},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		input          *QueryInput
+		expectedOutput *QueryOutput
+		expectedText   []string
+		expectedError  error
+	}{
+		"open security group rules": {
+			input: &QueryInput{
+				Expression: "changes[?type=='aws_security_group_rule' && attributes[?starts_with(path, '.cidr_blocks') && after=='0.0.0.0/0']].address",
+			},
+			expectedOutput: &QueryOutput{Result: []any{"aws_security_group_rule.open"}},
+			expectedText:   []string{"aws_security_group_rule.open\n"},
+		},
+		"values": {
+			input: &QueryInput{
+				Expression: "changes[?contains(actions, 'create')].{address: address, ami: diff.\".ami\".after}",
+			},
+			expectedOutput: &QueryOutput{Result: []any{map[string]any{"address": "aws_instance.this", "ami": "ami-1"}}},
+			expectedText:   []string{".[0].address: aws_instance.this\n", ".[0].ami: ami-1\n"},
+		},
+		"filtered changes": {
+			input: &QueryInput{
+				Expression: "length(changes)",
+				Filter: &InspectFilter{
+					ResourceChanges: []Filter{
+						{
+							NamePattern:  Patterns{"aws_security_group_rule.*"},
+							DiffPatterns: map[string][]DiffPattern{".cidr_blocks.*": {{Before: Patterns{"*"}, After: Patterns{"*"}}}},
+						},
+					},
+				},
+			},
+			expectedOutput: &QueryOutput{Result: float64(1)},
+			expectedText:   []string{"1\n"},
+		},
+		"plan": {
+			input:          &QueryInput{Expression: "plan.format_version"},
+			expectedOutput: &QueryOutput{Result: "1.2"},
+			expectedText:   []string{"1.2\n"},
+		},
+		"invalid expression": {
+			input:         &QueryInput{Expression: "changes[?"},
+			expectedError: fmt.Errorf("invalid query expression caused by: SyntaxError: Incomplete expression"),
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := p.Query(tst.input)
+
+			if tst.expectedError == nil {
+				assert.Nil(t, gotError)
+			} else {
+				assert.EqualError(t, gotError, tst.expectedError.Error())
+			}
+			diff.Check(t, tst.expectedOutput, gotOut)
+			if gotOut != nil {
+				diff.Check(t, tst.expectedText, gotOut.Text())
+			}
+		})
+	}
+}
+
+func Test_QuerySensitive(t *testing.T) {
+	p := &Plan{
+		FormatVersion: "1.2",
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: "aws_db_instance.this",
+				Type:    "aws_db_instance",
+				Change: &tfJson.Change{
+					Actions:         tfJson.Actions{tfJson.ActionUpdate},
+					Before:          map[string]any{"password": "old", "tags": map[string]any{"owner": "a", "token": "x"}},
+					After:           map[string]any{"password": "new", "tags": map[string]any{"owner": "b", "token": "y"}},
+					BeforeSensitive: map[string]any{"password": true, "tags": map[string]any{"token": true}},
+					AfterSensitive:  map[string]any{"password": true, "tags": map[string]any{"token": true}},
+				},
+			},
+		},
+		OutputChanges: map[string]*tfJson.Change{
+			"secret": {Actions: tfJson.Actions{tfJson.ActionCreate}, After: "s3cr3t", AfterSensitive: true},
+		},
+	}
+
+	out, err := p.Query(&QueryInput{Expression: "plan.resource_changes[0].change.{before: before, after: after}"})
+	assert.Nil(t, err)
+	diff.Check(t, []string{
+		".after.password: (sensitive value)\n",
+		".after.tags.owner: b\n",
+		".after.tags.token: (sensitive value)\n",
+		".before.password: (sensitive value)\n",
+		".before.tags.owner: a\n",
+		".before.tags.token: (sensitive value)\n",
+	}, out.Text())
+	diff.Check(t, []string{
+		"| Path | Value |\n",
+		"| --- | --- |\n",
+		"| `.after.password` | (sensitive value) |\n",
+		"| `.after.tags.owner` | b |\n",
+		"| `.after.tags.token` | (sensitive value) |\n",
+		"| `.before.password` | (sensitive value) |\n",
+		"| `.before.tags.owner` | a |\n",
+		"| `.before.tags.token` | (sensitive value) |\n",
+	}, out.Markdown())
+
+	out, err = p.Query(&QueryInput{Expression: "plan.output_changes.secret.after"})
+	assert.Nil(t, err)
+	diff.Check(t, &QueryOutput{Result: "(sensitive value)"}, out)
+
+	// The plan itself is left untouched
+	assert.Equal(t, "new", p.ResourceChanges[0].Change.After.(map[string]any)["password"])
+}
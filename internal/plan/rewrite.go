@@ -0,0 +1,182 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// The rewrite pattern is a regular expression. The default.
+	RewriteTypeRegex = "regex"
+	// The rewrite pattern is a wildcard pattern matching the whole string.
+	// Each * and ? is a capture group.
+	RewriteTypeWildcard = "wildcard"
+)
+
+// A rule rewriting strings matching a pattern.
+type RewriteRule struct {
+	// Pattern to match. A regular expression or a wildcard pattern depending
+	// on Type.
+	Pattern string `json:"pattern"`
+	// Replacement for matches. Capture groups are referenced as ${1},
+	// ${2}... Without braces a group followed by a name character is read
+	// as a named group, e.g. $1_x is the group named 1_x.
+	Replace string `json:"replace"`
+	// Optional type of the pattern, either RewriteTypeRegex (default) or
+	// RewriteTypeWildcard.
+	Type string `json:"type,omitempty"`
+}
+
+// Rules normalising inspected plans so that plans from different
+// environments can be compared.
+type RewriteRules struct {
+	// Rules rewriting resource addresses and output names.
+	Addresses []RewriteRule `json:"addresses"`
+	// Rules rewriting before and after values.
+	Values []RewriteRule `json:"values"`
+}
+
+/*
+Converts a wildcard pattern into an anchored regular expression where
+each wildcard is a capture group.
+*/
+func wildcardToRegex(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			b.WriteString("(.*)")
+		case '?':
+			b.WriteString("(.)")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+type compiledRewrite struct {
+	regex   *regexp.Regexp
+	replace string
+}
+
+func compileRewrites(rules []RewriteRule) ([]compiledRewrite, error) {
+	out := []compiledRewrite{}
+	for _, rule := range rules {
+		pattern := rule.Pattern
+		switch rule.Type {
+		case "", RewriteTypeRegex:
+		case RewriteTypeWildcard:
+			pattern = wildcardToRegex(pattern)
+		default:
+			return nil, fmt.Errorf("unknown rewrite type %s for pattern %s", rule.Type, rule.Pattern)
+		}
+
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compile rewrite pattern %s caused by: %w", rule.Pattern, err)
+		}
+		out = append(out, compiledRewrite{regex: regex, replace: rule.Replace})
+	}
+	return out, nil
+}
+
+func rewrite(s string, rules []compiledRewrite) string {
+	for _, rule := range rules {
+		s = rule.regex.ReplaceAllString(s, rule.replace)
+	}
+	return s
+}
+
+/*
+Rewrites the addresses and values of the entities. Entities rewritten to
+the same address are merged in sorted order of their original addresses.
+Errors if they hold different values at the same path.
+*/
+func rewriteEntityDiffs(m map[string]EntityDiff, addresses, values []compiledRewrite) (map[string]EntityDiff, error) {
+	out := map[string]EntityDiff{}
+	sources := map[string]string{}
+	for _, source := range sortedKeys(m) {
+		address := rewrite(source, addresses)
+		if _, ok := out[address]; !ok {
+			out[address] = EntityDiff{}
+			sources[address] = source
+		}
+
+		for path, diff := range m[source] {
+			rewritten := &Diff{
+				Before: rewrite(diff.Before, values),
+				After:  rewrite(diff.After, values),
+			}
+			if existing, ok := out[address][path]; ok && *existing != *rewritten {
+				return nil, fmt.Errorf("%s and %s both rewrite to %s with different values at %s", sources[address], source, address, path)
+			}
+			out[address][path] = rewritten
+		}
+	}
+	return out, nil
+}
+
+/*
+Produces a copy of the InspectOutput with the rewrite rules applied to
+every address and value. Rules are applied in order. Entities rewritten to
+the same address are merged, erroring if their values or actions conflict.
+*/
+func (o *InspectOutput) Rewrite(rules *RewriteRules) (*InspectOutput, error) {
+	if rules == nil {
+		return o, nil
+	}
+
+	addresses, err := compileRewrites(rules.Addresses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address rewrite rule caused by: %w", err)
+	}
+	values, err := compileRewrites(rules.Values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value rewrite rule caused by: %w", err)
+	}
+
+	var actions map[string][]string
+	if o.Diff.ResourceActions != nil {
+		actions = map[string][]string{}
+		sources := map[string]string{}
+		for _, source := range sortedKeys(o.Diff.ResourceActions) {
+			address := rewrite(source, addresses)
+			acts := o.Diff.ResourceActions[source]
+			if existing, ok := actions[address]; ok && !slices.Equal(existing, acts) {
+				return nil, fmt.Errorf("%s and %s both rewrite to %s with different actions", sources[address], source, address)
+			}
+			actions[address] = acts
+			sources[address] = source
+		}
+	}
+
+	out := &InspectDiff{ResourceActions: actions}
+	if out.Resources, err = rewriteEntityDiffs(o.Diff.Resources, addresses, values); err != nil {
+		return nil, fmt.Errorf("unable to rewrite resources caused by: %w", err)
+	}
+	if out.Outputs, err = rewriteEntityDiffs(o.Diff.Outputs, addresses, values); err != nil {
+		return nil, fmt.Errorf("unable to rewrite outputs caused by: %w", err)
+	}
+	if out.ResourceDrifts, err = rewriteEntityDiffs(o.Diff.ResourceDrifts, addresses, values); err != nil {
+		return nil, fmt.Errorf("unable to rewrite resource drifts caused by: %w", err)
+	}
+
+	if o.Diff.Sources != nil {
+		// Entities merged by the rewrite keep the location of the first
+		// address in order
+		out.Sources = map[string]SourceLocation{}
+		for _, source := range sortedKeys(o.Diff.Sources) {
+			address := rewrite(source, addresses)
+			if _, ok := out.Sources[address]; !ok {
+				out.Sources[address] = o.Diff.Sources[source]
+			}
+		}
+	}
+
+	return &InspectOutput{Diff: out}, nil
+}
@@ -0,0 +1,201 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InspectRewrite(t *testing.T) {
+	cases := map[string]struct {
+		inspectOutput  *InspectOutput
+		rules          *RewriteRules
+		expectedOutput *InspectOutput
+		expectedError  error
+	}{
+		"wildcard address and regex value": {
+			inspectOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						`module.app_staging.aws_s3_bucket.this["staging-logs"]`: {
+							".arn": {Before: "arn:aws:s3:::111111111111-staging-logs", After: "(known after apply)"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs: map[string]EntityDiff{
+						"staging_url": {
+							".": {Before: "(empty)", After: "https://staging.example.com"},
+						},
+					},
+				},
+			},
+			rules: &RewriteRules{
+				Addresses: []RewriteRule{
+					{Pattern: `module.app_*.aws_s3_bucket.this["*-logs"]`, Replace: `module.app_ENV.aws_s3_bucket.this["ENV-logs"]`, Type: RewriteTypeWildcard},
+					{Pattern: `^staging_`, Replace: "ENV_"},
+				},
+				Values: []RewriteRule{
+					{Pattern: `[0-9]{12}`, Replace: "ACCOUNT"},
+					{Pattern: `\bstaging\b`, Replace: "ENV"},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						`module.app_ENV.aws_s3_bucket.this["ENV-logs"]`: {
+							".arn": {Before: "arn:aws:s3:::ACCOUNT-ENV-logs", After: "(known after apply)"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs: map[string]EntityDiff{
+						"ENV_url": {
+							".": {Before: "(empty)", After: "https://ENV.example.com"},
+						},
+					},
+				},
+			},
+		},
+		"wildcard capture groups": {
+			inspectOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"module.app_prod.aws_instance.web": {
+							".ami": {Before: "ami-1", After: "ami-2"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+			rules: &RewriteRules{
+				Addresses: []RewriteRule{
+					{Pattern: "module.app_*.aws_*", Replace: "module.app.aws_${2}", Type: RewriteTypeWildcard},
+				},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"module.app.aws_instance.web": {
+							".ami": {Before: "ami-1", After: "ami-2"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+				},
+			},
+		},
+		"colliding addresses with equal values are merged": {
+			inspectOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.web_a": {".ami": {Before: "ami-1", After: "ami-2"}},
+						"aws_instance.web_b": {".ami": {Before: "ami-1", After: "ami-2"}, ".tags": {Before: "a", After: "b"}},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+					ResourceActions: map[string][]string{
+						"aws_instance.web_a": {"update"},
+						"aws_instance.web_b": {"update"},
+					},
+				},
+			},
+			rules: &RewriteRules{
+				Addresses: []RewriteRule{{Pattern: "aws_instance.web_?", Replace: "aws_instance.web", Type: RewriteTypeWildcard}},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.web": {".ami": {Before: "ami-1", After: "ami-2"}, ".tags": {Before: "a", After: "b"}},
+					},
+					ResourceDrifts:  map[string]EntityDiff{},
+					Outputs:         map[string]EntityDiff{},
+					ResourceActions: map[string][]string{"aws_instance.web": {"update"}},
+				},
+			},
+		},
+		"source locations follow rewritten addresses": {
+			inspectOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.web_a": {".ami": {Before: "ami-1", After: "ami-2"}},
+						"aws_instance.web_b": {".ami": {Before: "ami-1", After: "ami-2"}},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+					Sources: map[string]SourceLocation{
+						"aws_instance.web_a": {File: "a.tf", Line: 3},
+						"aws_instance.web_b": {File: "b.tf", Line: 7},
+					},
+				},
+			},
+			rules: &RewriteRules{
+				Addresses: []RewriteRule{{Pattern: "aws_instance.web_?", Replace: "aws_instance.web", Type: RewriteTypeWildcard}},
+			},
+			expectedOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.web": {".ami": {Before: "ami-1", After: "ami-2"}},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+					Outputs:        map[string]EntityDiff{},
+					Sources: map[string]SourceLocation{
+						"aws_instance.web": {File: "a.tf", Line: 3},
+					},
+				},
+			},
+		},
+		"colliding addresses with conflicting values": {
+			inspectOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.web_a": {".ami": {Before: "ami-1", After: "ami-2"}},
+						"aws_instance.web_b": {".ami": {Before: "ami-1", After: "ami-3"}},
+					},
+				},
+			},
+			rules: &RewriteRules{
+				Addresses: []RewriteRule{{Pattern: "aws_instance.web_?", Replace: "aws_instance.web", Type: RewriteTypeWildcard}},
+			},
+			expectedError: fmt.Errorf("unable to rewrite resources caused by: aws_instance.web_a and aws_instance.web_b both rewrite to aws_instance.web with different values at .ami"),
+		},
+		"colliding addresses with conflicting actions": {
+			inspectOutput: &InspectOutput{
+				Diff: &InspectDiff{
+					ResourceActions: map[string][]string{
+						"aws_instance.web_a": {"update"},
+						"aws_instance.web_b": {"delete", "create"},
+					},
+				},
+			},
+			rules: &RewriteRules{
+				Addresses: []RewriteRule{{Pattern: "aws_instance.web_?", Replace: "aws_instance.web", Type: RewriteTypeWildcard}},
+			},
+			expectedError: fmt.Errorf("aws_instance.web_a and aws_instance.web_b both rewrite to aws_instance.web with different actions"),
+		},
+		"invalid regex": {
+			inspectOutput: &InspectOutput{Diff: &InspectDiff{}},
+			rules: &RewriteRules{
+				Values: []RewriteRule{{Pattern: "("}},
+			},
+			expectedError: fmt.Errorf("invalid value rewrite rule caused by: unable to compile rewrite pattern ( caused by: error parsing regexp: missing closing ): `(`"),
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := tst.inspectOutput.Rewrite(tst.rules)
+
+			if tst.expectedError == nil {
+				assert.Nil(t, gotError)
+			} else {
+				assert.EqualError(t, gotError, tst.expectedError.Error())
+			}
+			if tst.expectedOutput != nil {
+				diff.Check(t, tst.expectedOutput, gotOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Compare the un-filtered changes of the plans as well.
+	CompareScopeRaw = "raw"
+	// Compare the variables of the plans.
+	CompareScopeVariables = "variables"
+	// Compare the provider configuration of the plans.
+	CompareScopeProviders = "providers"
+	// Compare the metadata of the plans such as the Terraform version.
+	CompareScopeMetadata = "metadata"
+)
+
+// Plan metadata compared with the CompareScopeMetadata scope. Applyable
+// and errored are not held by Plan so are parsed from the plan JSON with
+// ParsePlanMetadata.
+type PlanMetadata struct {
+	FormatVersion    string `json:"format_version"`
+	TerraformVersion string `json:"terraform_version"`
+	Applyable        *bool  `json:"applyable"`
+	Complete         *bool  `json:"complete"`
+	Errored          *bool  `json:"errored"`
+}
+
+/*
+Builds the metadata of the plan from the fields held by Plan.
+*/
+func (p *Plan) metadata() *PlanMetadata {
+	return &PlanMetadata{
+		FormatVersion:    p.FormatVersion,
+		TerraformVersion: p.TerraformVersion,
+		Complete:         p.Complete,
+	}
+}
+
+/*
+Flattens any JSON-marshallable value into paths and values prefixed with
+the key. A scalar is keyed by the key alone.
+*/
+func flattenWithKey(key string, v any, out map[string]string) error {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s caused by: %w", key, err)
+	}
+
+	var a any
+	if err := json.Unmarshal(bytes, &a); err != nil {
+		return fmt.Errorf("unable to unmarshal %s caused by: %w", key, err)
+	}
+
+	kvPairs := map[string]string{}
+	flatten("", a, kvPairs)
+	for path, val := range kvPairs {
+		if path == "." {
+			out[key] = val
+			continue
+		}
+		out[key+path] = val
+	}
+	return nil
+}
+
+func variableValues(p *Plan) (map[string]string, error) {
+	out := map[string]string{}
+	for name, v := range p.Variables {
+		if v == nil {
+			continue
+		}
+		if err := flattenWithKey(name, v.Value, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+/*
+Finds the root module variables marked as sensitive in the configuration.
+Terraform does not mark them in the plan's variables.
+*/
+func sensitiveVariables(p *Plan) map[string]bool {
+	out := map[string]bool{}
+	if p.Config == nil || p.Config.RootModule == nil {
+		return out
+	}
+	for name, v := range p.Config.RootModule.Variables {
+		if v != nil && v.Sensitive {
+			out[name] = true
+		}
+	}
+	return out
+}
+
+/*
+Replaces the values of the sensitive variables and their nested paths
+with (sensitive value). Redacting after comparing still reports a changed
+sensitive variable, just not its values.
+*/
+func redactVariables(diff map[string]CompareValue, sensitive map[string]bool) map[string]CompareValue {
+	redact := func(v string) string {
+		if v == "(empty)" {
+			return v
+		}
+		return "(sensitive value)"
+	}
+
+	for key, v := range diff {
+		name, _, _ := strings.Cut(key, ".")
+		name, _, _ = strings.Cut(name, "[")
+		if sensitive[name] {
+			diff[key] = CompareValue{PlanA: redact(v.PlanA), PlanB: redact(v.PlanB)}
+		}
+	}
+	return diff
+}
+
+func providerValues(p *Plan) (map[string]string, error) {
+	out := map[string]string{}
+	if p.Config == nil {
+		return out, nil
+	}
+	for key, provider := range p.Config.ProviderConfigs {
+		if err := flattenWithKey(key, provider, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func metadataValues(m *PlanMetadata) (map[string]string, error) {
+	out := map[string]string{}
+	if err := flattenWithKey("", m, out); err != nil {
+		return nil, err
+	}
+
+	// Keys are prefixed with the path separator by flatten. Metadata keys
+	// read better without it.
+	trimmed := map[string]string{}
+	for k, v := range out {
+		trimmed[strings.TrimPrefix(k, ".")] = v
+	}
+	return trimmed, nil
+}
+
+/*
+Finds the keys whose values differ between a and b. Keys missing from one
+side are reported as (empty).
+*/
+func getValueDivergence(a, b map[string]string) map[string]CompareValue {
+	out := map[string]CompareValue{}
+	for k, aVal := range a {
+		bVal, ok := b[k]
+		if !ok {
+			bVal = "(empty)"
+		}
+		if aVal != bVal {
+			out[k] = CompareValue{PlanA: aVal, PlanB: bVal}
+		}
+	}
+	for k, bVal := range b {
+		if _, ok := a[k]; !ok {
+			out[k] = CompareValue{PlanA: "(empty)", PlanB: bVal}
+		}
+	}
+	return out
+}
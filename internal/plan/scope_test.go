@@ -0,0 +1,189 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ComparePlansScopes(t *testing.T) {
+	complete := true
+	a := &Plan{
+		FormatVersion:    "1.2",
+		TerraformVersion: "1.9.0",
+		Complete:         &complete,
+		Variables: map[string]*tfJson.PlanVariable{
+			"region": {Value: "eu-west-1"},
+			"tags":   {Value: map[string]any{"team": "a"}},
+		},
+		Config: &tfJson.Config{
+			ProviderConfigs: map[string]*tfJson.ProviderConfig{
+				"aws": {Name: "aws", VersionConstraint: "~> 5.0"},
+			},
+		},
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: "aws_instance.example",
+				Change: &tfJson.Change{
+					Before: map[string]any{"ami": "ami-1"},
+					After:  map[string]any{"ami": "ami-2"},
+				},
+			},
+		},
+	}
+	b := &Plan{
+		FormatVersion:    "1.2",
+		TerraformVersion: "1.10.0",
+		Variables: map[string]*tfJson.PlanVariable{
+			"region": {Value: "eu-west-1"},
+			"tags":   {Value: map[string]any{"team": "b"}},
+		},
+		Config: &tfJson.Config{
+			ProviderConfigs: map[string]*tfJson.ProviderConfig{
+				"aws": {Name: "aws", VersionConstraint: "~> 6.0"},
+			},
+		},
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: "aws_instance.example",
+				Change: &tfJson.Change{
+					Before: map[string]any{"ami": "ami-1"},
+					After:  map[string]any{"ami": "ami-3"},
+				},
+			},
+		},
+	}
+	filter := &InspectFilter{
+		ResourceChanges: []Filter{
+			{
+				NamePattern: Patterns{"*"},
+				DiffPatterns: map[string][]DiffPattern{
+					".ami": {{Before: Patterns{"*"}, After: Patterns{"*"}}},
+				},
+			},
+		},
+	}
+	emptyCompareDiff := func() *CompareDiff {
+		return &CompareDiff{
+			Resources:      map[string]CompareEntityDiff{},
+			Outputs:        map[string]CompareEntityDiff{},
+			ResourceDrifts: map[string]CompareEntityDiff{},
+		}
+	}
+
+	cases := map[string]struct {
+		input          *ComparePlansInput
+		expectedOutput *CompareInspectsOutput
+		expectedError  error
+	}{
+		"no scopes": {
+			input:          &ComparePlansInput{Filter: filter},
+			expectedOutput: &CompareInspectsOutput{Diff: emptyCompareDiff()},
+		},
+		"all scopes": {
+			input: &ComparePlansInput{
+				Filter: filter,
+				Scopes: []string{CompareScopeRaw, CompareScopeVariables, CompareScopeProviders, CompareScopeMetadata},
+			},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources:      map[string]CompareEntityDiff{},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+					Raw: &CompareDiff{
+						Resources: map[string]CompareEntityDiff{
+							"aws_instance.example": {
+								PlanA: EntityDiff{".ami": {Before: "ami-1", After: "ami-2"}},
+								PlanB: EntityDiff{".ami": {Before: "ami-1", After: "ami-3"}},
+							},
+						},
+						Outputs:        map[string]CompareEntityDiff{},
+						ResourceDrifts: map[string]CompareEntityDiff{},
+					},
+					Variables: map[string]CompareValue{
+						"tags.team": {PlanA: "a", PlanB: "b"},
+					},
+					Providers: map[string]CompareValue{
+						"aws.version_constraint": {PlanA: "~> 5.0", PlanB: "~> 6.0"},
+					},
+					Metadata: map[string]CompareValue{
+						"terraform_version": {PlanA: "1.9.0", PlanB: "1.10.0"},
+						"complete":          {PlanA: "true", PlanB: "(empty)"},
+					},
+				},
+			},
+		},
+		"metadata from input": {
+			input: &ComparePlansInput{
+				Scopes:    []string{CompareScopeMetadata},
+				Filter:    filter,
+				MetadataA: &PlanMetadata{TerraformVersion: "1.9.0"},
+				MetadataB: &PlanMetadata{TerraformVersion: "1.9.0", Errored: &complete},
+			},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources:      map[string]CompareEntityDiff{},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+					Metadata: map[string]CompareValue{
+						"errored": {PlanA: "(empty)", PlanB: "true"},
+					},
+				},
+			},
+		},
+		"unknown scope": {
+			input:         &ComparePlansInput{Scopes: []string{"foo"}},
+			expectedError: fmt.Errorf("unknown compare scope foo"),
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := ComparePlans(a, b, tst.input)
+
+			assert.Equal(t, tst.expectedError, gotError)
+			if tst.expectedOutput != nil {
+				diff.Check(t, tst.expectedOutput, gotOut)
+				assert.Equal(t, tst.expectedOutput.IsEmpty(), gotOut.IsEmpty())
+			}
+		})
+	}
+}
+
+func Test_ComparePlansSensitiveVariables(t *testing.T) {
+	config := &tfJson.Config{
+		RootModule: &tfJson.ConfigModule{
+			Variables: map[string]*tfJson.ConfigVariable{
+				"db_password": {Sensitive: true},
+				"db_settings": {Sensitive: true},
+				"region":      {},
+			},
+		},
+	}
+	a := &Plan{
+		Variables: map[string]*tfJson.PlanVariable{
+			"db_password": {Value: "hunter2"},
+			"db_settings": {Value: map[string]any{"user": "admin"}},
+			"region":      {Value: "eu-west-1"},
+		},
+		Config: config,
+	}
+	b := &Plan{
+		Variables: map[string]*tfJson.PlanVariable{
+			"db_password": {Value: "hunter3"},
+			"region":      {Value: "eu-west-2"},
+		},
+	}
+
+	out, err := ComparePlans(a, b, &ComparePlansInput{Scopes: []string{CompareScopeVariables}})
+	assert.Nil(t, err)
+	diff.Check(t, map[string]CompareValue{
+		"db_password":      {PlanA: "(sensitive value)", PlanB: "(sensitive value)"},
+		"db_settings.user": {PlanA: "(sensitive value)", PlanB: "(empty)"},
+		"region":           {PlanA: "eu-west-1", PlanB: "eu-west-2"},
+	}, out.Diff.Variables)
+}
@@ -0,0 +1,382 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vodkaslime/wildcard"
+)
+
+const (
+	// The difference was suppressed as one side is unknown.
+	SuppressedUnknown = "unknown"
+	// The difference was suppressed as the typed values are equal.
+	SuppressedTyped = "typed"
+	// The difference was suppressed as the lists are equal when unordered.
+	SuppressedUnordered = "unordered"
+	// The difference was suppressed as the path is ignored.
+	SuppressedIgnored = "ignored"
+)
+
+const (
+	// Compare the before and after values of each diff.
+	CompareModeFull = "full"
+	// Compare only the after values of each diff and the actions of
+	// resources changing in both plans. Attributes changing in only one
+	// plan of a resource changing in both are not compared as the other
+	// plan may already be at the after value.
+	CompareModeAfter = "after"
+	// Compare only the before values of each diff.
+	CompareModeBefore = "before"
+	// Compare only the planned actions of each resource.
+	CompareModeActions = "actions"
+)
+
+// Options making the compare tolerate equivalent values.
+type CompareOptions struct {
+	// Which side of each diff is compared. See the CompareMode constants.
+	// Defaults to CompareModeFull.
+	Mode string `json:"mode"`
+	// Treat (known after apply) as equal to any value.
+	UnknownAsWildcard bool `json:"unknownAsWildcard"`
+	// Compare values as numbers or booleans when both sides parse as one,
+	// e.g. "1" and "1.0" or "true" and "True" are equal.
+	TypedValues bool `json:"typedValues"`
+	// Wildcard-supported patterns of list paths, e.g. ".cidr_blocks", whose
+	// elements are compared regardless of order.
+	UnorderedLists Patterns `json:"unorderedLists"`
+	// Wildcard-supported patterns of paths to leave out of the compare.
+	IgnorePaths Patterns `json:"ignorePaths"`
+}
+
+// A difference between plan A and B suppressed by the CompareOptions.
+type SuppressedDiff struct {
+	// The kind of entity. One of resource, output or drift.
+	Kind string `json:"kind"`
+	// The address of the entity.
+	Address string `json:"address"`
+	// The path of the attribute.
+	Path string `json:"path"`
+	// Why the difference was suppressed. See the Suppressed constants.
+	Reason string `json:"reason"`
+	// The diff in plan A.
+	PlanA *Diff `json:"planA"`
+	// The diff in plan B.
+	PlanB *Diff `json:"planB"`
+}
+
+/*
+Checks if two values are equal with the options applied. Returns the
+reason when they are only equal because of the options.
+*/
+func (o *CompareOptions) valueEqual(a, b string) (bool, string) {
+	if a == b {
+		return true, ""
+	}
+	if o == nil {
+		return false, ""
+	}
+
+	if o.UnknownAsWildcard && (a == "(known after apply)" || b == "(known after apply)") {
+		return true, SuppressedUnknown
+	}
+
+	if o.TypedValues {
+		aNum, aErr := strconv.ParseFloat(a, 64)
+		bNum, bErr := strconv.ParseFloat(b, 64)
+		if aErr == nil && bErr == nil && aNum == bNum {
+			return true, SuppressedTyped
+		}
+
+		// strconv.ParseBool also accepts 1 and 0 which would make numbers
+		// equal to booleans
+		isBool := func(s string) bool {
+			return strings.EqualFold(s, "true") || strings.EqualFold(s, "false")
+		}
+		if isBool(a) && isBool(b) && strings.EqualFold(a, b) {
+			return true, SuppressedTyped
+		}
+	}
+
+	return false, ""
+}
+
+/*
+Checks if two diffs are equal with the options applied. Returns the
+reason when they are only equal because of the options.
+*/
+func (o *CompareOptions) equal(a, b *Diff) (bool, string) {
+	if o != nil && o.Mode == CompareModeAfter {
+		return o.valueEqual(a.After, b.After)
+	}
+	if o != nil && o.Mode == CompareModeBefore {
+		return o.valueEqual(a.Before, b.Before)
+	}
+
+	bEqual, bReason := o.valueEqual(a.Before, b.Before)
+	aEqual, aReason := o.valueEqual(a.After, b.After)
+	if !bEqual || !aEqual {
+		return false, ""
+	}
+
+	if bReason == SuppressedUnknown || aReason == SuppressedUnknown {
+		return true, SuppressedUnknown
+	}
+	if bReason != "" {
+		return true, bReason
+	}
+	return true, aReason
+}
+
+/*
+Checks the mode is one of the CompareMode constants.
+*/
+func (o *CompareOptions) validateMode() error {
+	if o == nil {
+		return nil
+	}
+	switch o.Mode {
+	case "", CompareModeFull, CompareModeAfter, CompareModeBefore, CompareModeActions:
+		return nil
+	}
+	return fmt.Errorf("unknown compare mode %s", o.Mode)
+}
+
+/*
+Finds the resources whose planned actions differ between a and b.
+*/
+func getActionDivergence(a, b map[string][]string) map[string]CompareValue {
+	join := func(m map[string][]string) map[string]string {
+		out := map[string]string{}
+		for address, actions := range m {
+			out[address] = strings.Join(actions, ",")
+		}
+		return out
+	}
+	return getValueDivergence(join(a), join(b))
+}
+
+/*
+Splits a flattened path at its first list index returning the list path,
+the index and the rest of the path. ok is false for paths without a list
+index.
+*/
+func splitListPath(path string) (list string, index int, rest string, ok bool) {
+	start := strings.Index(path, ".[")
+	if start == -1 {
+		return "", 0, "", false
+	}
+	end := strings.Index(path[start:], "]")
+	if end == -1 {
+		return "", 0, "", false
+	}
+
+	index, err := strconv.Atoi(path[start+2 : start+end])
+	if err != nil {
+		return "", 0, "", false
+	}
+	return path[:start], index, path[start+end+1:], true
+}
+
+/*
+Re-indexes the elements of the unordered lists in the entity diff so
+that equal elements get the same index regardless of their order.
+*/
+func (o *CompareOptions) sortUnorderedLists(m *wildcard.Matcher, e EntityDiff) (EntityDiff, error) {
+	if o == nil || len(o.UnorderedLists) == 0 {
+		return e, nil
+	}
+
+	out := EntityDiff{}
+	// List path -> element index -> rest of the path -> diff
+	lists := map[string]map[int]map[string]*Diff{}
+
+	for path, diff := range e {
+		list, index, rest, ok := splitListPath(path)
+		if ok {
+			match, err := o.UnorderedLists.match(m, list)
+			if err != nil {
+				return nil, err
+			}
+			ok = match
+		}
+		if !ok {
+			out[path] = diff
+			continue
+		}
+
+		if _, exists := lists[list]; !exists {
+			lists[list] = map[int]map[string]*Diff{}
+		}
+		if _, exists := lists[list][index]; !exists {
+			lists[list][index] = map[string]*Diff{}
+		}
+		lists[list][index][rest] = diff
+	}
+
+	for list, elements := range lists {
+		signatures := []string{}
+		bySignature := map[string][]map[string]*Diff{}
+		for _, element := range elements {
+			parts := []string{}
+			for rest, diff := range element {
+				parts = append(parts, fmt.Sprintf("%s=%s>%s", rest, diff.Before, diff.After))
+			}
+			sort.Strings(parts)
+			signature := strings.Join(parts, ";")
+
+			signatures = append(signatures, signature)
+			bySignature[signature] = append(bySignature[signature], element)
+		}
+		sort.Strings(signatures)
+
+		for i, signature := range signatures {
+			element := bySignature[signature][0]
+			bySignature[signature] = bySignature[signature][1:]
+			for rest, diff := range element {
+				out[fmt.Sprintf("%s.[%v]%s", list, i, rest)] = diff
+			}
+		}
+	}
+
+	return out, nil
+}
+
+/*
+Removes ignored paths and re-indexes unordered lists of each entity.
+Differences in ignored paths are reported as suppressed.
+*/
+func (o *CompareOptions) prepare(kind string, a, b map[string]EntityDiff) (map[string]EntityDiff, map[string]EntityDiff, []SuppressedDiff, error) {
+	m := wildcard.NewMatcher()
+	suppressed := []SuppressedDiff{}
+
+	ignored := func(path string) (bool, error) {
+		if o == nil || len(o.IgnorePaths) == 0 {
+			return false, nil
+		}
+		return o.IgnorePaths.match(m, path)
+	}
+
+	removeIgnored := func(in map[string]EntityDiff) (map[string]EntityDiff, error) {
+		out := map[string]EntityDiff{}
+		for address, entDiff := range in {
+			out[address] = EntityDiff{}
+			for path, diff := range entDiff {
+				if ignore, err := ignored(path); err != nil {
+					return nil, err
+				} else if !ignore {
+					out[address][path] = diff
+				}
+			}
+
+			var err error
+			if out[address], err = o.sortUnorderedLists(m, out[address]); err != nil {
+				return nil, err
+			}
+			if len(out[address]) == 0 {
+				delete(out, address)
+			}
+		}
+		return out, nil
+	}
+
+	aOut, err := removeIgnored(a)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bOut, err := removeIgnored(b)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Report the ignored paths that differ
+	empty := &Diff{Before: "(empty)", After: "(empty)"}
+	for address, entDiff := range a {
+		for path, aDiff := range entDiff {
+			if ignore, err := ignored(path); err != nil {
+				return nil, nil, nil, err
+			} else if !ignore {
+				continue
+			}
+			bDiff, ok := b[address][path]
+			if !ok {
+				bDiff = empty
+			}
+			if *aDiff != *bDiff {
+				suppressed = append(suppressed, SuppressedDiff{Kind: kind, Address: address, Path: path, Reason: SuppressedIgnored, PlanA: aDiff, PlanB: bDiff})
+			}
+		}
+	}
+	for address, entDiff := range b {
+		for path, bDiff := range entDiff {
+			if ignore, err := ignored(path); err != nil {
+				return nil, nil, nil, err
+			} else if !ignore {
+				continue
+			}
+			if _, ok := a[address][path]; !ok {
+				suppressed = append(suppressed, SuppressedDiff{Kind: kind, Address: address, Path: path, Reason: SuppressedIgnored, PlanA: empty, PlanB: bDiff})
+			}
+		}
+	}
+
+	return aOut, bOut, suppressed, nil
+}
+
+/*
+Compares the entity diffs with the options applied, returning the
+divergence and the suppressed differences.
+*/
+func compareEntityDiffs(kind string, a, b map[string]EntityDiff, opts *CompareOptions) (map[string]CompareEntityDiff, []SuppressedDiff, error) {
+	aPrep, bPrep, suppressed, err := opts.prepare(kind, a, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out, equalSuppressed := getEntityDiffDivergence(kind, aPrep, bPrep, opts)
+	suppressed = append(suppressed, equalSuppressed...)
+
+	if opts != nil && len(opts.UnorderedLists) > 0 {
+		// Report the list differences that only went away once unordered
+		rawOpts := *opts
+		rawOpts.UnorderedLists = nil
+		aRaw, bRaw, _, err := rawOpts.prepare(kind, a, b)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw, _ := getEntityDiffDivergence(kind, aRaw, bRaw, &rawOpts)
+
+		for address, compEntDiff := range raw {
+			for path, aDiff := range compEntDiff.PlanA {
+				list, _, _, ok := splitListPath(path)
+				if !ok || listDiverges(out[address], list) {
+					continue
+				}
+				suppressed = append(suppressed, SuppressedDiff{Kind: kind, Address: address, Path: path, Reason: SuppressedUnordered, PlanA: aDiff, PlanB: compEntDiff.PlanB[path]})
+			}
+		}
+	}
+
+	sort.SliceStable(suppressed, func(i, j int) bool {
+		if suppressed[i].Address != suppressed[j].Address {
+			return suppressed[i].Address < suppressed[j].Address
+		}
+		return suppressed[i].Path < suppressed[j].Path
+	})
+
+	return out, suppressed, nil
+}
+
+/*
+Checks if any path of the list diverges.
+*/
+func listDiverges(c CompareEntityDiff, list string) bool {
+	for path := range c.PlanA {
+		if l, _, _, ok := splitListPath(path); ok && l == list {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,490 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CompareInspectsWithOptions(t *testing.T) {
+	cases := map[string]struct {
+		a              *InspectOutput
+		b              *InspectOutput
+		options        *CompareOptions
+		expectedOutput *CompareInspectsOutput
+	}{
+		"unknown as wildcard": {
+			a: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".id":  {Before: "(empty)", After: "(known after apply)"},
+							".ami": {Before: "(empty)", After: "ami-1"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			b: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".id":  {Before: "(empty)", After: "i-123"},
+							".ami": {Before: "(empty)", After: "ami-2"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			options: &CompareOptions{UnknownAsWildcard: true},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources: map[string]CompareEntityDiff{
+						"aws_instance.this": {
+							PlanA: EntityDiff{".ami": {Before: "(empty)", After: "ami-1"}},
+							PlanB: EntityDiff{".ami": {Before: "(empty)", After: "ami-2"}},
+						},
+					},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+					Suppressed: []SuppressedDiff{
+						{
+							Kind:    "resource",
+							Address: "aws_instance.this",
+							Path:    ".id",
+							Reason:  SuppressedUnknown,
+							PlanA:   &Diff{Before: "(empty)", After: "(known after apply)"},
+							PlanB:   &Diff{Before: "(empty)", After: "i-123"},
+						},
+					},
+				},
+			},
+		},
+		"typed values and ignored paths": {
+			a: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".count":   {Before: "1", After: "2"},
+							".enabled": {Before: "false", After: "true"},
+						},
+					},
+					Outputs: map[string]EntityDiff{
+						"timestamp": {
+							".": {Before: "2024-01-01", After: "2024-01-02"},
+						},
+					},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			b: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".count":   {Before: "1.0", After: "2"},
+							".enabled": {Before: "False", After: "1"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			options: &CompareOptions{TypedValues: true, IgnorePaths: Patterns{"."}},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources: map[string]CompareEntityDiff{
+						"aws_instance.this": {
+							PlanA: EntityDiff{".enabled": {Before: "false", After: "true"}},
+							PlanB: EntityDiff{".enabled": {Before: "False", After: "1"}},
+						},
+					},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+					Suppressed: []SuppressedDiff{
+						{
+							Kind:    "resource",
+							Address: "aws_instance.this",
+							Path:    ".count",
+							Reason:  SuppressedTyped,
+							PlanA:   &Diff{Before: "1", After: "2"},
+							PlanB:   &Diff{Before: "1.0", After: "2"},
+						},
+						{
+							Kind:    "output",
+							Address: "timestamp",
+							Path:    ".",
+							Reason:  SuppressedIgnored,
+							PlanA:   &Diff{Before: "2024-01-01", After: "2024-01-02"},
+							PlanB:   &Diff{Before: "(empty)", After: "(empty)"},
+						},
+					},
+				},
+			},
+		},
+		"after mode": {
+			a: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".ami":  {Before: "ami-1", After: "ami-3"},
+							".type": {Before: "t3.micro", After: "t3.large"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			b: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".ami":  {Before: "ami-2", After: "ami-3"},
+							".type": {Before: "t3.micro", After: "t3.small"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			options: &CompareOptions{Mode: CompareModeAfter},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources: map[string]CompareEntityDiff{
+						"aws_instance.this": {
+							PlanA: EntityDiff{".type": {Before: "t3.micro", After: "t3.large"}},
+							PlanB: EntityDiff{".type": {Before: "t3.micro", After: "t3.small"}},
+						},
+					},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+				},
+			},
+		},
+		"after mode with a plan already at the target": {
+			a: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".ami":  {Before: "ami-1", After: "ami-3"},
+							".type": {Before: "t3.micro", After: "t3.large"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			b: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".type": {Before: "t3.micro", After: "t3.large"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			options: &CompareOptions{Mode: CompareModeAfter},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources:      map[string]CompareEntityDiff{},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+				},
+			},
+		},
+		"after mode with a resource created in one plan": {
+			a: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".type": {Before: "t3.micro", After: "t3.large"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+					ResourceActions: map[string][]string{
+						"aws_instance.this": {"update"},
+					},
+				},
+			},
+			b: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".type": {Before: "t3.micro", After: "t3.large"},
+						},
+						"aws_instance.that": {
+							".ami": {Before: "(empty)", After: "ami-3"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+					ResourceActions: map[string][]string{
+						"aws_instance.this": {"update"},
+						"aws_instance.that": {"create"},
+					},
+				},
+			},
+			options: &CompareOptions{Mode: CompareModeAfter},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources: map[string]CompareEntityDiff{
+						"aws_instance.that": {
+							PlanA: EntityDiff{".ami": {Before: "(empty)", After: "(empty)"}},
+							PlanB: EntityDiff{".ami": {Before: "(empty)", After: "ami-3"}},
+						},
+					},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+				},
+			},
+		},
+		"after mode with differing actions": {
+			a: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".ami": {Before: "ami-1", After: "ami-3"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+					ResourceActions: map[string][]string{
+						"aws_instance.this": {"update"},
+					},
+				},
+			},
+			b: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".ami": {Before: "ami-2", After: "ami-3"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+					ResourceActions: map[string][]string{
+						"aws_instance.this": {"delete", "create"},
+					},
+				},
+			},
+			options: &CompareOptions{Mode: CompareModeAfter},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources:      map[string]CompareEntityDiff{},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+					ResourceActions: map[string]CompareValue{
+						"aws_instance.this": {PlanA: "update", PlanB: "delete,create"},
+					},
+				},
+			},
+		},
+		"before mode": {
+			a: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".ami":  {Before: "ami-1", After: "ami-3"},
+							".type": {Before: "t3.micro", After: "t3.large"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			b: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {
+							".ami":  {Before: "ami-2", After: "ami-3"},
+							".type": {Before: "t3.micro", After: "t3.small"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			options: &CompareOptions{Mode: CompareModeBefore},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources: map[string]CompareEntityDiff{
+						"aws_instance.this": {
+							PlanA: EntityDiff{".ami": {Before: "ami-1", After: "ami-3"}},
+							PlanB: EntityDiff{".ami": {Before: "ami-2", After: "ami-3"}},
+						},
+					},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+				},
+			},
+		},
+		"actions mode": {
+			a: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {".ami": {Before: "ami-1", After: "ami-2"}},
+						"aws_instance.that": {".ami": {Before: "ami-1", After: "(empty)"}},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+					ResourceActions: map[string][]string{
+						"aws_instance.this": {"update"},
+						"aws_instance.that": {"delete"},
+					},
+				},
+			},
+			b: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_instance.this": {".ami": {Before: "ami-5", After: "ami-6"}},
+						"aws_instance.that": {".ami": {Before: "ami-1", After: "ami-2"}},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+					ResourceActions: map[string][]string{
+						"aws_instance.this": {"update"},
+						"aws_instance.that": {"delete", "create"},
+					},
+				},
+			},
+			options: &CompareOptions{Mode: CompareModeActions},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources:      map[string]CompareEntityDiff{},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+					ResourceActions: map[string]CompareValue{
+						"aws_instance.that": {PlanA: "delete", PlanB: "delete,create"},
+					},
+				},
+			},
+		},
+		"unordered lists": {
+			a: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_security_group.this": {
+							".cidr_blocks.[0]": {Before: "(empty)", After: "10.0.0.0/8"},
+							".cidr_blocks.[1]": {Before: "(empty)", After: "192.168.0.0/16"},
+							".ports.[0]":       {Before: "(empty)", After: "80"},
+							".ports.[1]":       {Before: "(empty)", After: "443"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			b: &InspectOutput{
+				Diff: &InspectDiff{
+					Resources: map[string]EntityDiff{
+						"aws_security_group.this": {
+							".cidr_blocks.[0]": {Before: "(empty)", After: "192.168.0.0/16"},
+							".cidr_blocks.[1]": {Before: "(empty)", After: "10.0.0.0/8"},
+							".ports.[0]":       {Before: "(empty)", After: "443"},
+							".ports.[1]":       {Before: "(empty)", After: "80"},
+						},
+					},
+					Outputs:        map[string]EntityDiff{},
+					ResourceDrifts: map[string]EntityDiff{},
+				},
+			},
+			options: &CompareOptions{UnorderedLists: Patterns{".cidr_blocks"}},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources: map[string]CompareEntityDiff{
+						"aws_security_group.this": {
+							PlanA: EntityDiff{
+								".ports.[0]": {Before: "(empty)", After: "80"},
+								".ports.[1]": {Before: "(empty)", After: "443"},
+							},
+							PlanB: EntityDiff{
+								".ports.[0]": {Before: "(empty)", After: "443"},
+								".ports.[1]": {Before: "(empty)", After: "80"},
+							},
+						},
+					},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+					Suppressed: []SuppressedDiff{
+						{
+							Kind:    "resource",
+							Address: "aws_security_group.this",
+							Path:    ".cidr_blocks.[0]",
+							Reason:  SuppressedUnordered,
+							PlanA:   &Diff{Before: "(empty)", After: "10.0.0.0/8"},
+							PlanB:   &Diff{Before: "(empty)", After: "192.168.0.0/16"},
+						},
+						{
+							Kind:    "resource",
+							Address: "aws_security_group.this",
+							Path:    ".cidr_blocks.[1]",
+							Reason:  SuppressedUnordered,
+							PlanA:   &Diff{Before: "(empty)", After: "192.168.0.0/16"},
+							PlanB:   &Diff{Before: "(empty)", After: "10.0.0.0/8"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := CompareInspectsWithOptions(tst.a, tst.b, tst.options)
+
+			assert.Nil(t, gotError)
+			diff.Check(t, tst.expectedOutput, gotOut)
+		})
+	}
+}
+
+func Test_CompareInspectsWithOptionsUnknownMode(t *testing.T) {
+	_, err := CompareInspectsWithOptions(&InspectOutput{}, &InspectOutput{}, &CompareOptions{Mode: "foo"})
+
+	assert.Equal(t, fmt.Errorf("unknown compare mode foo"), err)
+}
+
+func Test_InspectResourceActions(t *testing.T) {
+	p := &Plan{
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: "aws_instance.this",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionDelete, tfJson.ActionCreate},
+					Before:  map[string]any{"ami": "ami-1"},
+					After:   map[string]any{"ami": "ami-2"},
+				},
+			},
+			{
+				Address: "aws_instance.filtered",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionUpdate},
+					Before:  map[string]any{"ami": "ami-1"},
+					After:   map[string]any{"ami": "ami-2"},
+				},
+			},
+		},
+	}
+
+	out, err := p.Inspect(&InspectInput{
+		Filter: &InspectFilter{
+			ResourceChanges: []Filter{
+				{
+					NamePattern:  Patterns{"aws_instance.filtered"},
+					DiffPatterns: map[string][]DiffPattern{".ami": {{Before: Patterns{"*"}, After: Patterns{"*"}}}},
+				},
+			},
+		},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{"aws_instance.this": {"delete", "create"}}, out.Diff.ResourceActions)
+}
@@ -0,0 +1,144 @@
+package plan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// The version of the snapshot format.
+const SnapshotVersion = 2
+
+// The content of a snapshot covered by its hash.
+type SnapshotContent struct {
+	// The version of the snapshot format.
+	Version int `json:"version"`
+	// The filter the plan was inspected with.
+	Filter *InspectFilter `json:"filter"`
+	// Values for ${set:name} placeholders in the filter.
+	Set map[string]string `json:"set"`
+	// The rewrite rules redacting the inspected plan.
+	Rewrites *RewriteRules `json:"rewrites"`
+	// The inspected and redacted plan diff.
+	Diff *InspectDiff `json:"diff"`
+}
+
+// A canonical record of an approved inspected plan which a later plan
+// can be verified against.
+type Snapshot struct {
+	SnapshotContent
+	// The sha256 hash of the canonical JSON of the content.
+	Hash string `json:"hash"`
+}
+
+type SaveSnapshotInput struct {
+	// Optional filter to inspect the plan with.
+	Filter *InspectFilter
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string
+	// Optional rewrite rules to redact the inspected plan with.
+	Rewrites *RewriteRules
+	// Optional context which stops the operation early when it ends.
+	Context context.Context
+}
+
+/*
+Produces the canonical JSON of a JSON object leaving out the key, e.g. the
+hash of a snapshot. The values are kept as stored and the keys sorted, so
+the canonical JSON only changes when the content does.
+*/
+func canonicalObject(data []byte, leaveOut string) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal object caused by: %w", err)
+	}
+	delete(fields, leaveOut)
+
+	bytes, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal object caused by: %w", err)
+	}
+	return bytes, nil
+}
+
+/*
+Computes the hash of the stored JSON of a snapshot, leaving out the hash
+itself.
+*/
+func hashSnapshot(data []byte) (string, error) {
+	bytes, err := canonicalObject(data, "hash")
+	if err != nil {
+		return "", fmt.Errorf("unable to canonicalise snapshot content caused by: %w", err)
+	}
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+/*
+Computes the hash of the snapshot content as it is stored.
+*/
+func (c *SnapshotContent) hash() (string, error) {
+	bytes, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal snapshot content caused by: %w", err)
+	}
+	return hashSnapshot(bytes)
+}
+
+/*
+Inspects and redacts the plan producing a snapshot of it.
+*/
+func (p *Plan) Snapshot(params *SaveSnapshotInput) (*Snapshot, error) {
+	out, err := p.Inspect(&InspectInput{
+		Filter:  params.Filter,
+		Set:     params.Set,
+		Context: params.Context,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err = out.Rewrite(params.Rewrites)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{
+		SnapshotContent: SnapshotContent{
+			Version:  SnapshotVersion,
+			Filter:   params.Filter,
+			Set:      params.Set,
+			Rewrites: params.Rewrites,
+			Diff:     out.Diff,
+		},
+	}
+	s.Hash, err = s.SnapshotContent.hash()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+/*
+Inspects the plan with the snapshot's filter and rewrite rules and
+compares it with the snapshot. The output is empty when the plan matches
+the snapshot. Plan A of the output is the snapshot and plan B the plan.
+*/
+func (s *Snapshot) Verify(p *Plan) (*CompareInspectsOutput, error) {
+	out, err := p.Inspect(&InspectInput{
+		Filter: s.Filter,
+		Set:    s.Set,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err = out.Rewrite(s.Rewrites)
+	if err != nil {
+		return nil, err
+	}
+
+	return CompareInspects(&InspectOutput{Diff: s.Diff}, out), nil
+}
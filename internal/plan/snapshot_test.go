@@ -0,0 +1,164 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SnapshotVerify(t *testing.T) {
+	approved := &Plan{
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: "aws_instance.example",
+				Change: &tfJson.Change{
+					Before: map[string]any{"ami": "ami-0397850", "role_arn": "arn:aws:iam::123456789012:role/foo"},
+					After:  map[string]any{"ami": "ami-12345678", "role_arn": "arn:aws:iam::123456789012:role/foo"},
+				},
+			},
+		},
+	}
+
+	input := &SaveSnapshotInput{
+		Filter: &InspectFilter{},
+		Rewrites: &RewriteRules{
+			Values: []RewriteRule{{Pattern: "[0-9]{12}", Replace: "ACCOUNT"}},
+		},
+	}
+
+	snapshot, err := approved.Snapshot(input)
+	assert.Nil(t, err)
+
+	// Round trip the snapshot through JSON as it would be stored
+	bytes, err := json.Marshal(snapshot)
+	assert.Nil(t, err)
+	snapshot, err = ParseSnapshot(bytes)
+	assert.Nil(t, err)
+
+	cases := map[string]struct {
+		plan           *Plan
+		expectedOutput *CompareInspectsOutput
+	}{
+		"matches": {
+			plan: approved,
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources:      map[string]CompareEntityDiff{},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+				},
+			},
+		},
+		"diverges": {
+			plan: &Plan{
+				ResourceChanges: []*tfJson.ResourceChange{
+					{
+						Address: "aws_instance.example",
+						Change: &tfJson.Change{
+							Before: map[string]any{"ami": "ami-0397850"},
+							After:  map[string]any{"ami": "ami-99999999"},
+						},
+					},
+				},
+			},
+			expectedOutput: &CompareInspectsOutput{
+				Diff: &CompareDiff{
+					Resources: map[string]CompareEntityDiff{
+						"aws_instance.example": {
+							PlanA: EntityDiff{
+								".ami": {Before: "ami-0397850", After: "ami-12345678"},
+							},
+							PlanB: EntityDiff{
+								".ami": {Before: "ami-0397850", After: "ami-99999999"},
+							},
+						},
+					},
+					Outputs:        map[string]CompareEntityDiff{},
+					ResourceDrifts: map[string]CompareEntityDiff{},
+				},
+			},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := snapshot.Verify(tst.plan)
+
+			assert.Nil(t, gotError)
+			diff.Check(t, tst.expectedOutput, gotOut)
+		})
+	}
+}
+
+func Test_ParseSnapshot(t *testing.T) {
+	snapshot, err := (&Plan{}).Snapshot(&SaveSnapshotInput{})
+	assert.Nil(t, err)
+	bytes, err := json.Marshal(snapshot)
+	assert.Nil(t, err)
+
+	cases := map[string]struct {
+		jsonSnapshot  []byte
+		expectedError error
+	}{
+		"valid": {
+			jsonSnapshot: bytes,
+		},
+		"tampered": {
+			jsonSnapshot:  []byte(strings.Replace(string(bytes), `"resources":{}`, `"resources":{"aws_instance.example":{}}`, 1)),
+			expectedError: fmt.Errorf("snapshot hash %s does not match its content", snapshot.Hash),
+		},
+		"unsupported version": {
+			jsonSnapshot:  []byte(`{"version": 1}`),
+			expectedError: fmt.Errorf("unsupported snapshot version 1"),
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, gotError := ParseSnapshot(tst.jsonSnapshot)
+
+			assert.Equal(t, tst.expectedError, gotError)
+		})
+	}
+}
+
+func Test_SnapshotRoundTrip(t *testing.T) {
+	p := &Plan{
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: "aws_instance.example",
+				Change: &tfJson.Change{
+					Before: map[string]any{"ami": "ami-0397850", "tags": map[string]any{"a": "1"}},
+					After:  map[string]any{"ami": "ami-12345678"},
+				},
+			},
+		},
+	}
+
+	// The rule leaves out after, which is kept left out through the round trip
+	filter, err := ParseInspectFilter([]byte(`{"resourceChanges": [{"namePattern": "aws_instance.*", "diffPatterns": {".tags.a": [{"before": "*"}]}}]}`))
+	assert.Nil(t, err)
+
+	snapshot, err := p.Snapshot(&SaveSnapshotInput{Filter: filter})
+	assert.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		bytes, err := json.Marshal(snapshot)
+		assert.Nil(t, err)
+		snapshot, err = ParseSnapshot(bytes)
+		if !assert.Nil(t, err) {
+			return
+		}
+	}
+
+	out, err := snapshot.Verify(p)
+	assert.Nil(t, err)
+	assert.True(t, out.IsEmpty())
+}
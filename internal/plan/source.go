@@ -0,0 +1,340 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	tfJson "github.com/hashicorp/terraform-json"
+)
+
+// The location of a block declaring a resource or output in the configuration.
+type SourceLocation struct {
+	// The path of the .tf or .tf.json file within the configuration directory.
+	File string `json:"file"`
+	// The line of the block in the file.
+	Line int `json:"line"`
+}
+
+/*
+Formats the location as file:line.
+*/
+func (s SourceLocation) String() string {
+	return fmt.Sprintf("%s:%v", s.File, s.Line)
+}
+
+// Blocks of the configuration holding the planned changes.
+var sourceSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+	},
+}
+
+// The module manifest written by terraform init at .terraform/modules/modules.json
+type moduleManifest struct {
+	Modules []struct {
+		Key string `json:"Key"`
+		Dir string `json:"Dir"`
+	} `json:"Modules"`
+}
+
+// Finds the source locations of the blocks declared in a configuration directory.
+type sourceLocator struct {
+	parser *hclparse.Parser
+	// Module key, e.g. app.network -> the directory of the module
+	moduleDirs map[string]string
+	// Module key -> block address, e.g. aws_instance.this -> its location
+	blocks map[string]map[string]SourceLocation
+}
+
+func newSourceLocator(configDir string, config *tfJson.Config) (*sourceLocator, error) {
+	s := &sourceLocator{
+		parser:     hclparse.NewParser(),
+		moduleDirs: map[string]string{"": configDir},
+		blocks:     map[string]map[string]SourceLocation{},
+	}
+
+	// Modules installed by terraform init, including remote ones, are listed in the manifest
+	bytes, err := os.ReadFile(filepath.Join(configDir, ".terraform", "modules", "modules.json"))
+	if err == nil {
+		manifest := &moduleManifest{}
+		if err := json.Unmarshal(bytes, manifest); err != nil {
+			return nil, fmt.Errorf("unable to parse module manifest caused by: %w", err)
+		}
+		for _, module := range manifest.Modules {
+			if module.Key != "" {
+				s.moduleDirs[module.Key] = filepath.Join(configDir, module.Dir)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("unable to read module manifest caused by: %w", err)
+	}
+
+	// Otherwise local module sources are resolved from the configuration in the plan
+	var walk func(key, dir string, m *tfJson.ConfigModule)
+	walk = func(key, dir string, m *tfJson.ConfigModule) {
+		for name, call := range m.ModuleCalls {
+			childKey := name
+			if key != "" {
+				childKey = key + "." + name
+			}
+			childDir, ok := s.moduleDirs[childKey]
+			if !ok && (strings.HasPrefix(call.Source, "./") || strings.HasPrefix(call.Source, "../")) {
+				childDir = filepath.Join(dir, call.Source)
+				s.moduleDirs[childKey] = childDir
+			}
+			if call.Module != nil && childDir != "" {
+				walk(childKey, childDir, call.Module)
+			}
+		}
+	}
+	if config != nil && config.RootModule != nil {
+		walk("", configDir, config.RootModule)
+	}
+
+	return s, nil
+}
+
+/*
+Parses the .tf and .tf.json files of the module with the key, once.
+*/
+func (s *sourceLocator) module(key string) (map[string]SourceLocation, error) {
+	if blocks, ok := s.blocks[key]; ok {
+		return blocks, nil
+	}
+
+	blocks := map[string]SourceLocation{}
+	s.blocks[key] = blocks
+
+	dir, ok := s.moduleDirs[key]
+	if !ok {
+		// Remote modules not installed can't be located
+		return blocks, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return blocks, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read configuration directory %s caused by: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		filename := filepath.Join(dir, entry.Name())
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		switch {
+		case strings.HasSuffix(filename, ".tf"):
+			file, diags = s.parser.ParseHCLFile(filename)
+		case strings.HasSuffix(filename, ".tf.json"):
+			file, diags = s.parser.ParseJSONFile(filename)
+		default:
+			continue
+		}
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("unable to parse configuration file %s caused by: %w", filename, diags)
+		}
+
+		content, _, diags := file.Body.PartialContent(sourceSchema)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("unable to parse configuration file %s caused by: %w", filename, diags)
+		}
+		for _, block := range content.Blocks {
+			address := strings.Join(block.Labels, ".")
+			switch block.Type {
+			case "data":
+				address = "data." + address
+			case "output":
+				address = "output." + address
+			}
+			blocks[address] = SourceLocation{File: filename, Line: block.DefRange.Start.Line}
+		}
+	}
+
+	return blocks, nil
+}
+
+/*
+Finds the location of the block declaring the resource change.
+*/
+func (s *sourceLocator) resource(rChange *tfJson.ResourceChange) (SourceLocation, bool, error) {
+	key := []string{}
+	for _, part := range strings.Split(stripInstanceKeys(rChange.ModuleAddress), ".") {
+		if part != "" && part != "module" {
+			key = append(key, part)
+		}
+	}
+
+	blocks, err := s.module(strings.Join(key, "."))
+	if err != nil {
+		return SourceLocation{}, false, err
+	}
+
+	address := rChange.Type + "." + rChange.Name
+	if rChange.Mode == tfJson.DataResourceMode {
+		address = "data." + address
+	}
+	location, ok := blocks[address]
+	return location, ok, nil
+}
+
+/*
+Attaches the source locations of the resources, resource drifts and outputs
+of the diff from the configuration in the directory. Resources declared in
+modules that can't be found, e.g. remote modules not installed, are skipped.
+*/
+func (p *Plan) locateSources(configDir string, diff *InspectDiff) error {
+	s, err := newSourceLocator(configDir, p.Config)
+	if err != nil {
+		return err
+	}
+
+	locate := func(changes []*tfJson.ResourceChange, diffs map[string]EntityDiff) error {
+		for _, rChange := range changes {
+			if _, ok := diffs[rChange.Address]; !ok {
+				continue
+			}
+			location, ok, err := s.resource(rChange)
+			if err != nil {
+				return err
+			}
+			if ok {
+				if diff.Sources == nil {
+					diff.Sources = map[string]SourceLocation{}
+				}
+				diff.Sources[rChange.Address] = location
+			}
+		}
+		return nil
+	}
+
+	if err := locate(p.ResourceChanges, diff.Resources); err != nil {
+		return err
+	}
+	if err := locate(p.ResourceDrift, diff.ResourceDrifts); err != nil {
+		return err
+	}
+
+	blocks, err := s.module("")
+	if err != nil {
+		return err
+	}
+	for name := range diff.Outputs {
+		if location, ok := blocks["output."+name]; ok {
+			if diff.Sources == nil {
+				diff.Sources = map[string]SourceLocation{}
+			}
+			diff.Sources[name] = location
+		}
+	}
+
+	return nil
+}
+
+/*
+Escapes the data of a GitHub workflow command.
+*/
+func escapeAnnotationData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+/*
+Escapes a property value of a GitHub workflow command.
+*/
+func escapeAnnotationProperty(s string) string {
+	s = escapeAnnotationData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
+
+// An un-filtered resource, resource drift or output of an InspectOutput.
+type inspectEntry struct {
+	// Either resource, drift or output.
+	kind string
+	// The resource address or output.name.
+	address string
+	// The key of the entity in its InspectDiff map.
+	key string
+	// The changed attribute paths, sorted.
+	paths []string
+}
+
+/*
+Lists the un-filtered resources, resource drifts then outputs sorted by
+address.
+*/
+func (o *InspectOutput) entries() []inspectEntry {
+	var out []inspectEntry
+
+	add := func(kind, address, key string, diffs EntityDiff) {
+		entry := inspectEntry{kind: kind, address: address, key: key, paths: []string{}}
+		for _, path := range sortedKeys(diffs) {
+			if path != "" {
+				entry.paths = append(entry.paths, path)
+			}
+		}
+		out = append(out, entry)
+	}
+
+	for _, address := range sortedKeys(o.Diff.Resources) {
+		add("resource", address, address, o.Diff.Resources[address])
+	}
+	for _, address := range sortedKeys(o.Diff.ResourceDrifts) {
+		add("drift", address, address, o.Diff.ResourceDrifts[address])
+	}
+	for _, name := range sortedKeys(o.Diff.Outputs) {
+		add("output", "output."+name, name, o.Diff.Outputs[name])
+	}
+	return out
+}
+
+/*
+Describes the entry in a single line, e.g. aws_vpc.main changes: .cidr_block
+*/
+func (e inspectEntry) message() string {
+	message := e.address + " changes"
+	if e.kind == "drift" {
+		message = e.address + " drift"
+	}
+	if len(e.paths) > 0 {
+		message += ": " + strings.Join(e.paths, ", ")
+	}
+	return message
+}
+
+/*
+Produces a GitHub workflow command of the level, e.g. warning or error, for
+the entry pointing at the file and line declaring it when known.
+*/
+func (o *InspectOutput) annotation(level string, e inspectEntry) string {
+	command := "::" + level
+	if location, ok := o.Diff.Sources[e.key]; ok {
+		command += fmt.Sprintf(" file=%s,line=%v", escapeAnnotationProperty(location.File), location.Line)
+	}
+	return fmt.Sprintf("%s::%s\n", command, escapeAnnotationData(e.message()))
+}
+
+/*
+Produces GitHub workflow command annotations, one per un-filtered resource,
+resource drift and output, pointing at the file and line declaring it when
+its source location is known.
+*/
+func (o *InspectOutput) Annotations() []string {
+	var out []string
+	for _, entry := range o.entries() {
+		out = append(out, o.annotation("warning", entry))
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package plan
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+const sourcePlan = `{
+	"format_version": "1.2",
+	"resource_changes": [
+		{
+			"address": "aws_vpc.main",
+			"mode": "managed",
+			"type": "aws_vpc",
+			"name": "main",
+			"change": {"actions": ["update"], "before": {"cidr_block": "10.0.0.0/16"}, "after": {"cidr_block": "10.1.0.0/16"}}
+		},
+		{
+			"address": "module.app[0].aws_iam_role.this",
+			"module_address": "module.app[0]",
+			"mode": "managed",
+			"type": "aws_iam_role",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"name": "a"}, "after": {"name": "b"}}
+		},
+		{
+			"address": "module.remote.aws_s3_bucket.this",
+			"module_address": "module.remote",
+			"mode": "managed",
+			"type": "aws_s3_bucket",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"bucket": "a"}, "after": {"bucket": "b"}}
+		}
+	],
+	"output_changes": {
+		"vpc_id": {"actions": ["update"], "before": "a", "after": "b"}
+	},
+	"configuration": {
+		"root_module": {
+			"module_calls": {
+				"app": {"source": "./modules/app", "module": {}},
+				"remote": {"source": "terraform-aws-modules/s3-bucket/aws", "module": {}}
+			}
+		}
+	}
+}`
+
+func writeSourceFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func Test_InspectSources(t *testing.T) {
+	p := &Plan{}
+	assert.Nil(t, json.Unmarshal([]byte(sourcePlan), p))
+
+	dir := t.TempDir()
+	writeSourceFiles(t, dir, map[string]string{
+		"main.tf": "resource \"aws_vpc\" \"main\" {\n  cidr_block = \"10.1.0.0/16\"\n}\n\nmodule \"app\" {\n  source = \"./modules/app\"\n  count  = 1\n}\n",
+		"outputs.tf.json": `{
+  "output": {
+    "vpc_id": {"value": "${aws_vpc.main.id}"}
+  }
+}`,
+		"modules/app/iam.tf": "\n\nresource \"aws_iam_role\" \"this\" {\n  name = \"b\"\n}\n",
+	})
+
+	out, err := p.Inspect(&InspectInput{ConfigDir: dir})
+	assert.Nil(t, err)
+	diff.Check(t, map[string]SourceLocation{
+		"aws_vpc.main":                    {File: filepath.Join(dir, "main.tf"), Line: 1},
+		"module.app[0].aws_iam_role.this": {File: filepath.Join(dir, "modules/app/iam.tf"), Line: 3},
+		"vpc_id":                          {File: filepath.Join(dir, "outputs.tf.json"), Line: 3},
+	}, out.Diff.Sources)
+
+	t.Run("installed modules", func(t *testing.T) {
+		writeSourceFiles(t, dir, map[string]string{
+			".terraform/modules/modules.json":   `{"Modules": [{"Key": "", "Dir": "."}, {"Key": "remote", "Dir": ".terraform/modules/remote"}]}`,
+			".terraform/modules/remote/main.tf": "resource \"aws_s3_bucket\" \"this\" {\n}\n",
+		})
+
+		out, err := p.Inspect(&InspectInput{ConfigDir: dir})
+		assert.Nil(t, err)
+		diff.Check(t, SourceLocation{File: filepath.Join(dir, ".terraform/modules/remote/main.tf"), Line: 1}, out.Diff.Sources["module.remote.aws_s3_bucket.this"])
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		invalid := t.TempDir()
+		writeSourceFiles(t, invalid, map[string]string{"main.tf": "resource \"aws_vpc\" {\n"})
+
+		_, err := p.Inspect(&InspectInput{ConfigDir: invalid})
+		assert.ErrorContains(t, err, "failed to locate sources caused by: unable to parse configuration file")
+	})
+}
+
+func Test_Annotations(t *testing.T) {
+	out := &InspectOutput{
+		Diff: &InspectDiff{
+			Resources: map[string]EntityDiff{
+				"aws_vpc.main":          {".cidr_block": {Before: "10.0.0.0/16", After: "10.1.0.0/16"}, ".tags.Name": {Before: "a", After: "b"}},
+				"aws_s3_bucket.logs[0]": {".bucket": {Before: "a", After: "b"}},
+			},
+			ResourceDrifts: map[string]EntityDiff{
+				"aws_vpc.main": {".tags.Owner": {Before: "a", After: "b"}},
+			},
+			Outputs: map[string]EntityDiff{
+				"vpc_id": {"": {Before: "a", After: "b"}},
+			},
+			Sources: map[string]SourceLocation{
+				"aws_vpc.main": {File: "infra/main,1.tf", Line: 12},
+				"vpc_id":       {File: "infra/outputs.tf", Line: 3},
+			},
+		},
+	}
+
+	diff.Check(t, []string{
+		"::warning::aws_s3_bucket.logs[0] changes: .bucket\n",
+		"::warning file=infra/main%2C1.tf,line=12::aws_vpc.main changes: .cidr_block, .tags.Name\n",
+		"::warning file=infra/main%2C1.tf,line=12::aws_vpc.main drift: .tags.Owner\n",
+		"::warning file=infra/outputs.tf,line=3::output.vpc_id changes\n",
+	}, out.Annotations())
+}
@@ -0,0 +1,222 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/helpers"
+)
+
+const (
+	// Render the summary as a plain text table.
+	SummaryFormatTable = "table"
+	// Render the summary as JSON.
+	SummaryFormatJSON = "json"
+	// Render the summary as Markdown tables.
+	SummaryFormatMarkdown = "markdown"
+
+	// Module name of resources in the root module.
+	rootModuleName = "(root)"
+)
+
+// Counts of planned resource changes by grouping.
+type SummaryCounts struct {
+	// Number of resources with a planned change.
+	Total int `json:"total"`
+	// Counts keyed by action, e.g. create, update, delete or replace.
+	ByAction map[string]int `json:"byAction"`
+	// Counts keyed by resource type.
+	ByType map[string]int `json:"byType"`
+	// Counts keyed by module address. Resources of the root module are
+	// keyed by (root).
+	ByModule map[string]int `json:"byModule"`
+	// Counts keyed by provider name.
+	ByProvider map[string]int `json:"byProvider"`
+	// Counts keyed by action and resource type, e.g. "replace aws_instance".
+	ByActionAndType map[string]int `json:"byActionAndType"`
+}
+
+// Result of calling Summary() to count the changes of a plan.
+type SummaryOutput struct {
+	// Counts of every planned resource change.
+	All *SummaryCounts `json:"all"`
+	// Counts of the planned resource changes remaining after the filter.
+	Remaining *SummaryCounts `json:"remaining"`
+	// Number of resources without a planned change.
+	Unchanged int `json:"unchanged"`
+}
+
+type SummaryInput struct {
+	// Optional filter to apply to the plan for the remaining counts.
+	Filter *InspectFilter
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string
+	// Optional context which stops the operation early when it ends.
+	Context context.Context
+}
+
+func newSummaryCounts() *SummaryCounts {
+	return &SummaryCounts{
+		ByAction:        map[string]int{},
+		ByType:          map[string]int{},
+		ByModule:        map[string]int{},
+		ByProvider:      map[string]int{},
+		ByActionAndType: map[string]int{},
+	}
+}
+
+func (s *SummaryCounts) add(rChange *tfJson.ResourceChange, action string) {
+	module := rChange.ModuleAddress
+	if module == "" {
+		module = rootModuleName
+	}
+
+	s.Total++
+	s.ByAction[action]++
+	s.ByType[rChange.Type]++
+	s.ByModule[module]++
+	s.ByProvider[rChange.ProviderName]++
+	s.ByActionAndType[action+" "+rChange.Type]++
+}
+
+/*
+Names the planned actions of a change. Returns an empty string for changes
+without actions or with no-op actions.
+*/
+func actionName(actions tfJson.Actions) string {
+	switch {
+	case len(actions) == 0 || actions.NoOp():
+		return ""
+	case actions.Replace():
+		return "replace"
+	}
+
+	names := []string{}
+	for _, a := range actions {
+		names = append(names, string(a))
+	}
+	return strings.Join(names, "-")
+}
+
+/*
+Counts the planned resource changes of the plan by action, type, module and
+provider. Resources only moved to a new address are counted with the move
+action. The remaining counts leave out resources whose changes are all
+filtered out by the filter.
+*/
+func (p *Plan) Summary(params *SummaryInput) (*SummaryOutput, error) {
+	all, err := p.Inspect(&InspectInput{Set: params.Set, Context: params.Context})
+	if err != nil {
+		return nil, err
+	}
+	visible, err := p.Inspect(&InspectInput{Filter: params.Filter, Set: params.Set, Context: params.Context})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &SummaryOutput{
+		All:       newSummaryCounts(),
+		Remaining: newSummaryCounts(),
+	}
+
+	for _, rChange := range p.ResourceChanges {
+		if strings.HasPrefix(rChange.Address, "data.") || rChange.Change == nil {
+			continue
+		}
+		action := actionName(rChange.Change.Actions)
+		if action == "" && rChange.PreviousAddress != "" && rChange.PreviousAddress != rChange.Address {
+			action = graphActionMove
+		}
+		if action == "" {
+			out.Unchanged++
+			continue
+		}
+
+		out.All.add(rChange, action)
+
+		_, changed := all.Diff.Resources[rChange.Address]
+		_, remaining := visible.Diff.Resources[rChange.Address]
+		if changed && !remaining {
+			// Every change of the resource is filtered out
+
+			continue
+		}
+		out.Remaining.add(rChange, action)
+	}
+
+	return out, nil
+}
+
+type summaryGroup struct {
+	name      string
+	all       map[string]int
+	remaining map[string]int
+}
+
+func (s *SummaryOutput) groups() []summaryGroup {
+	return []summaryGroup{
+		{"Action", s.All.ByAction, s.Remaining.ByAction},
+		{"Type", s.All.ByType, s.Remaining.ByType},
+		{"Module", s.All.ByModule, s.Remaining.ByModule},
+		{"Provider", s.All.ByProvider, s.Remaining.ByProvider},
+		{"Action and type", s.All.ByActionAndType, s.Remaining.ByActionAndType},
+	}
+}
+
+/*
+Produces a slice of strings output which can be printed line by line
+to get a plain text table of the summary.
+*/
+func (s *SummaryOutput) Table() []string {
+	var out []string
+	out = append(out, fmt.Sprintf("\tResource changes: %v in total, %v remaining after filtering, %v resources unchanged\n", s.All.Total, s.Remaining.Total, s.Unchanged))
+
+	for _, group := range s.groups() {
+		keys := sortedKeys(group.all)
+		if len(keys) == 0 {
+			continue
+		}
+
+		maxWidth := len(group.name)
+		for _, k := range keys {
+			if len(k) > maxWidth {
+				maxWidth = len(k)
+			}
+		}
+
+		header := strings.ToUpper(group.name)
+		out = append(out, fmt.Sprintf("\n\t%s%s%s%sALL  REMAINING\n", colorBold, header, colorNone, helpers.FillWithSpaces(header, maxWidth+1)))
+		for _, k := range keys {
+			all := fmt.Sprint(group.all[k])
+			out = append(out, fmt.Sprintf("\t%s%s%s%s%v\n", k, helpers.FillWithSpaces(k, maxWidth+1), all, helpers.FillWithSpaces(all, len("ALL")+1), group.remaining[k]))
+		}
+	}
+	return out
+}
+
+/*
+Produces a slice of strings output which can be printed line by line
+to get Markdown tables of the summary.
+*/
+func (s *SummaryOutput) Markdown() []string {
+	var out []string
+	out = append(out, "## Plan summary\n")
+	out = append(out, fmt.Sprintf("\n%v resource changes, %v remaining after filtering. %v resources unchanged.\n", s.All.Total, s.Remaining.Total, s.Unchanged))
+
+	for _, group := range s.groups() {
+		keys := sortedKeys(group.all)
+		if len(keys) == 0 {
+			continue
+		}
+
+		out = append(out, fmt.Sprintf("\n### By %s\n\n", strings.ToLower(group.name)))
+		out = append(out, fmt.Sprintf("| %s | All | Remaining |\n", group.name))
+		out = append(out, "| --- | ---: | ---: |\n")
+		for _, k := range keys {
+			out = append(out, fmt.Sprintf("| `%s` | %v | %v |\n", k, group.all[k], group.remaining[k]))
+		}
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package plan
+
+import (
+	"testing"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Summary(t *testing.T) {
+	p := &Plan{
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address:      "aws_instance.this",
+				Type:         "aws_instance",
+				ProviderName: "registry.terraform.io/hashicorp/aws",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionDelete, tfJson.ActionCreate},
+					Before:  map[string]any{"ami": "ami-1"},
+					After:   map[string]any{"ami": "ami-2"},
+				},
+			},
+			{
+				Address:       "module.app.aws_instance.this",
+				ModuleAddress: "module.app",
+				Type:          "aws_instance",
+				ProviderName:  "registry.terraform.io/hashicorp/aws",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionUpdate},
+					Before:  map[string]any{"tags": map[string]any{"a": "1"}},
+					After:   map[string]any{"tags": map[string]any{"a": "2"}},
+				},
+			},
+			{
+				Address:       "module.app.random_id.this",
+				ModuleAddress: "module.app",
+				Type:          "random_id",
+				ProviderName:  "registry.terraform.io/hashicorp/random",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionCreate},
+					After:   map[string]any{"byte_length": 8},
+				},
+			},
+			{
+				Address: "aws_s3_bucket.this",
+				Type:    "aws_s3_bucket",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionNoop},
+					Before:  map[string]any{"bucket": "a"},
+					After:   map[string]any{"bucket": "a"},
+				},
+			},
+			{
+				Address: "data.aws_ami.this",
+				Type:    "aws_ami",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionRead},
+				},
+			},
+			{
+				Address:         "aws_sqs_queue.new",
+				PreviousAddress: "aws_sqs_queue.old",
+				Type:            "aws_sqs_queue",
+				ProviderName:    "registry.terraform.io/hashicorp/aws",
+				Change: &tfJson.Change{
+					Actions: tfJson.Actions{tfJson.ActionNoop},
+					Before:  map[string]any{"name": "a"},
+					After:   map[string]any{"name": "a"},
+				},
+			},
+		},
+	}
+
+	out, err := p.Summary(&SummaryInput{
+		Filter: &InspectFilter{
+			ResourceChanges: []Filter{
+				{
+					NamePattern:  Patterns{"*"},
+					DiffPatterns: map[string][]DiffPattern{".tags.*": {{Before: Patterns{"*"}, After: Patterns{"*"}}}},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	diff.Check(t, &SummaryOutput{
+		All: &SummaryCounts{
+			Total:    4,
+			ByAction: map[string]int{"replace": 1, "update": 1, "create": 1, "move": 1},
+			ByType:   map[string]int{"aws_instance": 2, "random_id": 1, "aws_sqs_queue": 1},
+			ByModule: map[string]int{"(root)": 2, "module.app": 2},
+			ByProvider: map[string]int{
+				"registry.terraform.io/hashicorp/aws":    3,
+				"registry.terraform.io/hashicorp/random": 1,
+			},
+			ByActionAndType: map[string]int{"replace aws_instance": 1, "update aws_instance": 1, "create random_id": 1, "move aws_sqs_queue": 1},
+		},
+		Remaining: &SummaryCounts{
+			Total:    3,
+			ByAction: map[string]int{"replace": 1, "create": 1, "move": 1},
+			ByType:   map[string]int{"aws_instance": 1, "random_id": 1, "aws_sqs_queue": 1},
+			ByModule: map[string]int{"(root)": 2, "module.app": 1},
+			ByProvider: map[string]int{
+				"registry.terraform.io/hashicorp/aws":    2,
+				"registry.terraform.io/hashicorp/random": 1,
+			},
+			ByActionAndType: map[string]int{"replace aws_instance": 1, "create random_id": 1, "move aws_sqs_queue": 1},
+		},
+		Unchanged: 1,
+	}, out)
+
+	diff.Check(t, []string{
+		"\tResource changes: 4 in total, 3 remaining after filtering, 1 resources unchanged\n",
+		"\n\t\033[1mACTION\033[0m   ALL  REMAINING\n",
+		"\tcreate   1    1\n",
+		"\tmove     1    1\n",
+		"\treplace  1    1\n",
+		"\tupdate   1    0\n",
+	}, out.Table()[:6])
+
+	diff.Check(t, []string{
+		"## Plan summary\n",
+		"\n4 resource changes, 3 remaining after filtering. 1 resources unchanged.\n",
+		"\n### By action\n\n",
+		"| Action | All | Remaining |\n",
+		"| --- | ---: | ---: |\n",
+		"| `create` | 1 | 1 |\n",
+		"| `move` | 1 | 1 |\n",
+		"| `replace` | 1 | 1 |\n",
+		"| `update` | 1 | 0 |\n",
+	}, out.Markdown()[:9])
+}
@@ -0,0 +1,184 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	tfJson "github.com/hashicorp/terraform-json"
+)
+
+var placeholderRegex = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// Values available to placeholders in a filter.
+type filterVars struct {
+	// Variables from the plan's variables block. Referenced as ${var.name}.
+	plan map[string]*tfJson.PlanVariable
+	// Values set by the caller. Referenced as ${set:name}.
+	set map[string]string
+}
+
+/*
+Replaces placeholders in s with their values. Supported placeholders are
+${env:NAME} for environment variables, ${var.name} for plan variables and
+${set:name} for values set by the caller. $${ escapes a placeholder.
+Resolved values are escaped with escape so they are taken literally.
+Errors if a placeholder cannot be resolved.
+*/
+func (v *filterVars) substitute(s string, escape func(string) string) (string, error) {
+	var err error
+	out := placeholderRegex.ReplaceAllStringFunc(s, func(placeholder string) string {
+		if strings.HasPrefix(placeholder, "$$") {
+			return placeholder[1:]
+		}
+
+		key := placeholderRegex.FindStringSubmatch(placeholder)[1]
+		switch {
+		case strings.HasPrefix(key, "env:"):
+			if val, ok := os.LookupEnv(strings.TrimPrefix(key, "env:")); ok {
+				return escape(val)
+			}
+		case strings.HasPrefix(key, "var."):
+			if val, ok := v.plan[strings.TrimPrefix(key, "var.")]; ok && val != nil {
+				if str, ok := val.Value.(string); ok {
+					return escape(str)
+				}
+				bytes, jErr := json.Marshal(val.Value)
+				if jErr == nil {
+					return escape(string(bytes))
+				}
+			}
+		case strings.HasPrefix(key, "set:"):
+			if val, ok := v.set[strings.TrimPrefix(key, "set:")]; ok {
+				return escape(val)
+			}
+		}
+
+		if err == nil {
+			err = &Error{Kind: ErrInvalidFilter, Message: fmt.Sprintf("unable to resolve placeholder %s", placeholder)}
+		}
+		return placeholder
+	})
+	return out, err
+}
+
+func (v *filterVars) substitutePatterns(p Patterns) (Patterns, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	out := Patterns{}
+	for _, pattern := range p {
+		s, err := v.substitute(pattern, escapeWildcards)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (v *filterVars) substituteFilters(filters []Filter) ([]Filter, error) {
+	if filters == nil {
+		return nil, nil
+	}
+
+	out := []Filter{}
+	for i, filter := range filters {
+		var err error
+
+		resolved := filter
+		if resolved.NamePattern, err = v.substitutePatterns(filter.NamePattern); err != nil {
+			return nil, fmt.Errorf("filter %d name pattern: %w", i, err)
+		}
+		if resolved.Condition, err = v.substitute(filter.Condition, escapeStringLiteral); err != nil {
+			return nil, fmt.Errorf("filter %d condition: %w", i, err)
+		}
+
+		if filter.DiffPatterns != nil {
+			resolved.DiffPatterns = map[string][]DiffPattern{}
+		}
+		for pathPattern, diffPatterns := range filter.DiffPatterns {
+			path, err := v.substitute(pathPattern, escapeWildcards)
+			if err != nil {
+				return nil, fmt.Errorf("filter %d path pattern: %w", i, err)
+			}
+
+			for _, diffPattern := range diffPatterns {
+				// The unresolved path is reported to keep resolved values
+				// out of errors
+				before, err := v.substitutePatterns(diffPattern.Before)
+				if err != nil {
+					return nil, fmt.Errorf("filter %d path pattern %s before: %w", i, pathPattern, err)
+				}
+				after, err := v.substitutePatterns(diffPattern.After)
+				if err != nil {
+					return nil, fmt.Errorf("filter %d path pattern %s after: %w", i, pathPattern, err)
+				}
+				resolved.DiffPatterns[path] = append(resolved.DiffPatterns[path], DiffPattern{Before: before, After: after})
+			}
+		}
+
+		out = append(out, resolved)
+	}
+	return out, nil
+}
+
+/*
+Produces a copy of the filter with all placeholders resolved.
+*/
+func (i *InspectFilter) resolve(vars *filterVars) (*InspectFilter, error) {
+	if i == nil {
+		return &InspectFilter{}, nil
+	}
+
+	var err error
+	out := &InspectFilter{}
+	if out.OutputChanges, err = vars.substituteFilters(i.OutputChanges); err != nil {
+		return nil, fmt.Errorf("unable to resolve output changes caused by: %w", err)
+	}
+	if out.ResourceChanges, err = vars.substituteFilters(i.ResourceChanges); err != nil {
+		return nil, fmt.Errorf("unable to resolve resource changes caused by: %w", err)
+	}
+	if out.DriftChanges, err = vars.substituteFilters(i.DriftChanges); err != nil {
+		return nil, fmt.Errorf("unable to resolve drift changes caused by: %w", err)
+	}
+	return out, nil
+}
+
+/*
+Lists the ${env:NAME} placeholders of the filter, e.g. to reject filters
+from untrusted sources which could read the environment.
+*/
+func (i *InspectFilter) EnvPlaceholders() []string {
+	out := []string{}
+	if i == nil {
+		return out
+	}
+
+	find := func(values ...string) {
+		for _, v := range values {
+			for _, match := range placeholderRegex.FindAllStringSubmatch(v, -1) {
+				if !strings.HasPrefix(match[0], "$$") && strings.HasPrefix(match[1], "env:") {
+					out = append(out, match[0])
+				}
+			}
+		}
+	}
+	for _, filters := range [][]Filter{i.OutputChanges, i.ResourceChanges, i.DriftChanges} {
+		for _, filter := range filters {
+			find(filter.NamePattern...)
+			find(filter.Condition)
+			for _, pathPattern := range sortedKeys(filter.DiffPatterns) {
+				find(pathPattern)
+				for _, diffPattern := range filter.DiffPatterns[pathPattern] {
+					find(diffPattern.Before...)
+					find(diffPattern.After...)
+				}
+			}
+		}
+	}
+	return out
+}
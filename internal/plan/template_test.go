@@ -0,0 +1,165 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+	"github.com/vodkaslime/wildcard"
+)
+
+func Test_ResolveFilter(t *testing.T) {
+	t.Setenv("TFPLAN_TEST_ACCOUNT_ID", "123456789012")
+
+	vars := &filterVars{
+		plan: map[string]*tfJson.PlanVariable{
+			"region": {Value: "eu-west-1"},
+			"azs":    {Value: []any{"a", "b"}},
+		},
+		set: map[string]string{
+			"env": "prod",
+		},
+	}
+
+	cases := map[string]struct {
+		filter         *InspectFilter
+		expectedOutput *InspectFilter
+		expectedError  error
+	}{
+		"all placeholders": {
+			filter: &InspectFilter{
+				ResourceChanges: []Filter{
+					{
+						NamePattern: Patterns{"module.app_${set:env}.*"},
+						Condition:   `after(".region") == "${var.region}"`,
+						DiffPatterns: map[string][]DiffPattern{
+							".role_arn": {
+								{
+									Before: Patterns{"arn:aws:iam::${env:TFPLAN_TEST_ACCOUNT_ID}:role/*"},
+									After:  Patterns{"${var.azs}", "$${literal}"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: &InspectFilter{
+				ResourceChanges: []Filter{
+					{
+						NamePattern: Patterns{"module.app_prod.*"},
+						Condition:   `after(".region") == "eu-west-1"`,
+						DiffPatterns: map[string][]DiffPattern{
+							".role_arn": {
+								{
+									Before: Patterns{"arn:aws:iam::123456789012:role/*"},
+									After:  Patterns{`\["a"\,"b"]`, "${literal}"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"nil filter": {
+			filter:         nil,
+			expectedOutput: &InspectFilter{},
+		},
+		"unresolved placeholder": {
+			filter: &InspectFilter{
+				OutputChanges: []Filter{
+					{NamePattern: Patterns{"${var.missing}"}},
+				},
+			},
+			expectedError: fmt.Errorf("unable to resolve output changes caused by: filter 0 name pattern: unable to resolve placeholder ${var.missing}"),
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			gotOut, gotError := tst.filter.resolve(vars)
+
+			if tst.expectedError == nil {
+				assert.Nil(t, gotError)
+			} else {
+				assert.EqualError(t, gotError, tst.expectedError.Error())
+				assert.ErrorIs(t, gotError, ErrInvalidFilter)
+			}
+			if tst.expectedOutput != nil {
+				diff.Check(t, tst.expectedOutput, gotOut)
+			}
+		})
+	}
+}
+
+func Test_ResolveFilterEscapesValues(t *testing.T) {
+	vars := &filterVars{
+		set: map[string]string{
+			"dir":      `C:\x`,
+			"greeting": `say "hi"`,
+			"glob":     `!a*,b`,
+		},
+	}
+
+	filter := &InspectFilter{
+		ResourceChanges: []Filter{
+			{
+				NamePattern: Patterns{"${set:dir}", "${set:glob}"},
+				Condition:   `after(".name") == "${set:greeting}"`,
+				DiffPatterns: map[string][]DiffPattern{
+					".${set:glob}": {{After: Patterns{"${set:dir}"}}},
+				},
+			},
+		},
+	}
+
+	got, err := filter.resolve(vars)
+	assert.Nil(t, err)
+	diff.Check(t, &InspectFilter{
+		ResourceChanges: []Filter{
+			{
+				NamePattern: Patterns{`C:\\x`, `\!a\*\,b`},
+				Condition:   `after(".name") == "say \"hi\""`,
+				DiffPatterns: map[string][]DiffPattern{
+					`.\!a\*\,b`: {{After: Patterns{`C:\\x`}}},
+				},
+			},
+		},
+	}, got)
+
+	m := wildcard.NewMatcher()
+	for pattern, s := range map[string]string{
+		got.ResourceChanges[0].NamePattern[0]: `C:\x`,
+		got.ResourceChanges[0].NamePattern[1]: `!a*,b`,
+	} {
+		match, err := matchPattern(m, pattern, s)
+		assert.Nil(t, err)
+		assert.True(t, match, pattern)
+	}
+	assert.Equal(t, Patterns{`.\!a\*\,b`}, pathPatterns(`.\!a\*\,b`))
+
+	match, err := evalCondition(got.ResourceChanges[0].Condition, &conditionEnv{
+		self: EntityDiff{".name": {Before: "(empty)", After: `say "hi"`}},
+	})
+	assert.Nil(t, err)
+	assert.True(t, match)
+}
+
+func Test_EnvPlaceholders(t *testing.T) {
+	filter := &InspectFilter{
+		OutputChanges: []Filter{{NamePattern: Patterns{"${set:name}", "$${env:LITERAL}"}}},
+		ResourceChanges: []Filter{
+			{
+				NamePattern: Patterns{"aws_iam_role.*"},
+				Condition:   `after(".name") == "${env:ROLE}"`,
+				DiffPatterns: map[string][]DiffPattern{
+					".tags.${env:TAG}": {{Before: Patterns{"*"}, After: Patterns{"arn:aws:iam::${env:ACCOUNT_ID}:role/*"}}},
+				},
+			},
+		},
+	}
+
+	diff.Check(t, []string{"${env:ROLE}", "${env:TAG}", "${env:ACCOUNT_ID}"}, filter.EnvPlaceholders())
+	diff.Check(t, []string{}, (*InspectFilter)(nil).EnvPlaceholders())
+}
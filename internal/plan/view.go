@@ -0,0 +1,144 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/orange-car/tfplan/internal/helpers"
+)
+
+const (
+	// Plan A and Plan B printed one after the other.
+	CompareViewBlocks = "blocks"
+	// Each diverging path printed once with the Plan A and B changes next
+	// to each other.
+	CompareViewSideBySide = "side-by-side"
+	// Each diverging path printed once followed by a - line for Plan A and
+	// a + line for Plan B.
+	CompareViewUnified = "unified"
+)
+
+// Options for printing a compare in a human readable format.
+type PrettyOptions struct {
+	// How diverging entities are printed. See the CompareView constants.
+	// Defaults to CompareViewBlocks.
+	View string
+	// Print plain text without colour.
+	NoColor bool
+}
+
+// Prints the diverging paths of an entity.
+type compareView func(c CompareEntityDiff) []string
+
+var colorRegex = regexp.MustCompile("\033\\[[0-9;]*m")
+
+/*
+Removes the colour escape codes from each line.
+*/
+func stripColors(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = colorRegex.ReplaceAllString(line, "")
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+Checks if the diff is the placeholder used when the path is not changed
+by one of the plans.
+*/
+func isAbsent(d *Diff) bool {
+	return d == nil || (d.Before == "(empty)" && d.After == "(empty)")
+}
+
+func maxPathWidth(e EntityDiff) int {
+	maxWidth := 0
+	for path := range e {
+		if len(path) > maxWidth {
+			maxWidth = len(path)
+		}
+	}
+	return maxWidth
+}
+
+func prettyBlocks(c CompareEntityDiff) []string {
+	var out []string
+
+	for _, side := range []struct {
+		name string
+		diff EntityDiff
+	}{{"Plan A", c.PlanA}, {"Plan B", c.PlanB}} {
+		out = append(out, fmt.Sprintf("\n\t\t\t%s%s:%s\n", colorBold, side.name, colorNone))
+
+		maxWidth := maxPathWidth(side.diff)
+		for _, path := range sortedKeys(side.diff) {
+			diff := side.diff[path]
+			out = append(out, fmt.Sprintf("\t\t\t\t%s:%s%s %s->%s %s\n", path, helpers.FillWithSpaces(path, maxWidth), diff.Before, colorOrange, colorNone, diff.After))
+		}
+	}
+	return out
+}
+
+func prettySideBySide(c CompareEntityDiff) []string {
+	var out []string
+
+	paths := sortedKeys(c.PlanA)
+	maxWidth := maxPathWidth(c.PlanA)
+	change := func(d *Diff) string {
+		if isAbsent(d) {
+			return "(no change)"
+		}
+		return fmt.Sprintf("%s -> %s", d.Before, d.After)
+	}
+
+	aWidth := len("Plan A")
+	for _, path := range paths {
+		if l := len(change(c.PlanA[path])); l > aWidth {
+			aWidth = l
+		}
+	}
+
+	out = append(out, fmt.Sprintf("\n\t\t\t%s %sPlan A%s%s| %sPlan B%s\n", helpers.FillWithSpaces("", maxWidth), colorBold, colorNone, helpers.FillWithSpaces("Plan A", aWidth), colorBold, colorNone))
+	for _, path := range paths {
+		aDiff, bDiff := c.PlanA[path], c.PlanB[path]
+		aChange, bChange := change(aDiff), change(bDiff)
+
+		aColor, bColor := colorRed, colorGreen
+		if isAbsent(aDiff) {
+			aColor = colorNone
+		}
+		if isAbsent(bDiff) {
+			bColor = colorNone
+		}
+
+		out = append(out, fmt.Sprintf("\t\t\t%s:%s%s%s%s%s| %s%s%s\n", path, helpers.FillWithSpaces(path, maxWidth), aColor, aChange, colorNone, helpers.FillWithSpaces(aChange, aWidth), bColor, bChange, colorNone))
+	}
+	return out
+}
+
+func prettyUnified(c CompareEntityDiff) []string {
+	var out []string
+
+	for _, path := range sortedKeys(c.PlanA) {
+		aDiff, bDiff := c.PlanA[path], c.PlanB[path]
+
+		out = append(out, fmt.Sprintf("\t\t\t%s:\n", path))
+		if !isAbsent(aDiff) {
+			out = append(out, fmt.Sprintf("\t\t\t%s- Plan A: %s -> %s%s\n", colorRed, aDiff.Before, aDiff.After, colorNone))
+		}
+		if !isAbsent(bDiff) {
+			out = append(out, fmt.Sprintf("\t\t\t%s+ Plan B: %s -> %s%s\n", colorGreen, bDiff.Before, bDiff.After, colorNone))
+		}
+	}
+	return out
+}
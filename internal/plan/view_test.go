@@ -0,0 +1,102 @@
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CompareInspectsOutputPrettyWithOptions(t *testing.T) {
+	compare := &CompareInspectsOutput{
+		Diff: &CompareDiff{
+			Resources: map[string]CompareEntityDiff{
+				"aws_instance.this": {
+					PlanA: EntityDiff{
+						".ami":           {Before: "ami-1", After: "ami-2"},
+						".instance_type": {Before: "(empty)", After: "(empty)"},
+					},
+					PlanB: EntityDiff{
+						".ami":           {Before: "ami-1", After: "ami-3"},
+						".instance_type": {Before: "t3.micro", After: "t3.large"},
+					},
+				},
+			},
+			Outputs:        map[string]CompareEntityDiff{},
+			ResourceDrifts: map[string]CompareEntityDiff{},
+		},
+	}
+
+	cases := map[string]struct {
+		options        *PrettyOptions
+		expectedOutput []string
+		expectedError  error
+	}{
+		"blocks": {
+			options: &PrettyOptions{NoColor: true},
+			expectedOutput: []string{
+				"\tTerraform plans differ at the following un-filtered changes:\n",
+				"\n\t\tresource \"aws_instance.this\" changes:\n",
+				"\n\t\t\tPlan A:\n",
+				"\t\t\t\t.ami:           ami-1 -> ami-2\n",
+				"\t\t\t\t.instance_type: (empty) -> (empty)\n",
+				"\n\t\t\tPlan B:\n",
+				"\t\t\t\t.ami:           ami-1 -> ami-3\n",
+				"\t\t\t\t.instance_type: t3.micro -> t3.large\n",
+				"\n\tChanges: 1 resources, 0 resource drifts, 0 outputs\n",
+			},
+		},
+		"side by side": {
+			options: &PrettyOptions{View: CompareViewSideBySide, NoColor: true},
+			expectedOutput: []string{
+				"\tTerraform plans differ at the following un-filtered changes:\n",
+				"\n\t\tresource \"aws_instance.this\" changes:\n",
+				"\n\t\t\t                Plan A         | Plan B\n",
+				"\t\t\t.ami:           ami-1 -> ami-2 | ami-1 -> ami-3\n",
+				"\t\t\t.instance_type: (no change)    | t3.micro -> t3.large\n",
+				"\n\tChanges: 1 resources, 0 resource drifts, 0 outputs\n",
+			},
+		},
+		"unified": {
+			options: &PrettyOptions{View: CompareViewUnified, NoColor: true},
+			expectedOutput: []string{
+				"\tTerraform plans differ at the following un-filtered changes:\n",
+				"\n\t\tresource \"aws_instance.this\" changes:\n",
+				"\t\t\t.ami:\n",
+				"\t\t\t- Plan A: ami-1 -> ami-2\n",
+				"\t\t\t+ Plan B: ami-1 -> ami-3\n",
+				"\t\t\t.instance_type:\n",
+				"\t\t\t+ Plan B: t3.micro -> t3.large\n",
+				"\n\tChanges: 1 resources, 0 resource drifts, 0 outputs\n",
+			},
+		},
+		"unified with colour": {
+			options: &PrettyOptions{View: CompareViewUnified},
+			expectedOutput: []string{
+				"\tTerraform plans differ at the following un-filtered changes:\n",
+				"\n\t\tresource \033[1m\"aws_instance.this\"\033[0m changes:\n",
+				"\t\t\t.ami:\n",
+				"\t\t\t\033[31m- Plan A: ami-1 -> ami-2\033[0m\n",
+				"\t\t\t\033[32m+ Plan B: ami-1 -> ami-3\033[0m\n",
+				"\t\t\t.instance_type:\n",
+				"\t\t\t\033[32m+ Plan B: t3.micro -> t3.large\033[0m\n",
+				"\n\tChanges: 1 resources, 0 resource drifts, 0 outputs\n",
+			},
+		},
+		"unknown view": {
+			options:       &PrettyOptions{View: "foo"},
+			expectedError: fmt.Errorf("unknown compare view foo"),
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gotOut, gotError := compare.PrettyWithOptions(tst.options)
+
+			assert.Equal(t, tst.expectedError, gotError)
+			diff.Check(t, tst.expectedOutput, gotOut)
+		})
+	}
+}
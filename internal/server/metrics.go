@@ -0,0 +1,120 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Upper bounds in seconds of the request duration histogram buckets.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// The request duration histogram of a single route.
+type histogram struct {
+	// Cumulative counts of requests at or under each of durationBuckets.
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// Request counts and latencies per route, written in the Prometheus text
+// exposition format.
+type metrics struct {
+	mu sync.Mutex
+	// Request counts keyed by route then status code.
+	requests map[string]map[int]uint64
+	// Request durations keyed by route.
+	durations map[string]*histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:  map[string]map[int]uint64{},
+		durations: map[string]*histogram{},
+	}
+}
+
+func (m *metrics) observe(route string, code int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.requests[route] == nil {
+		m.requests[route] = map[int]uint64{}
+	}
+	m.requests[route][code]++
+
+	h, ok := m.durations[route]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(durationBuckets))}
+		m.durations[route] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+/*
+Writes the metrics in the Prometheus text exposition format, ordered by
+route and status code so scrapes are stable.
+*/
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	routes := make([]string, 0, len(m.requests))
+	for route := range m.requests {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	lines := []string{
+		"# HELP tfplan_http_requests_total Number of HTTP requests by route and status code.\n",
+		"# TYPE tfplan_http_requests_total counter\n",
+	}
+	for _, route := range routes {
+		codes := make([]int, 0, len(m.requests[route]))
+		for code := range m.requests[route] {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+
+		for _, code := range codes {
+			lines = append(lines, fmt.Sprintf("tfplan_http_requests_total{route=%q,code=\"%d\"} %d\n", route, code, m.requests[route][code]))
+		}
+	}
+
+	lines = append(lines,
+		"# HELP tfplan_http_request_duration_seconds Latency of HTTP requests by route.\n",
+		"# TYPE tfplan_http_request_duration_seconds histogram\n",
+	)
+	for _, route := range routes {
+		h := m.durations[route]
+		for i, bound := range durationBuckets {
+			lines = append(lines, fmt.Sprintf("tfplan_http_request_duration_seconds_bucket{route=%q,le=%q} %d\n", route, formatFloat(bound), h.buckets[i]))
+		}
+		lines = append(lines,
+			fmt.Sprintf("tfplan_http_request_duration_seconds_bucket{route=%q,le=\"+Inf\"} %d\n", route, h.count),
+			fmt.Sprintf("tfplan_http_request_duration_seconds_sum{route=%q} %s\n", route, formatFloat(h.sum)),
+			fmt.Sprintf("tfplan_http_request_duration_seconds_count{route=%q} %d\n", route, h.count),
+		)
+	}
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Metrics(t *testing.T) {
+	m := newMetrics()
+	m.observe("POST /inspect", 200, 20*time.Millisecond)
+	m.observe("POST /inspect", 400, 2*time.Second)
+	m.observe("GET /healthz", 200, time.Millisecond)
+
+	out := &strings.Builder{}
+	assert.Nil(t, m.write(out))
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, []string{
+		"# HELP tfplan_http_requests_total Number of HTTP requests by route and status code.",
+		"# TYPE tfplan_http_requests_total counter",
+		`tfplan_http_requests_total{route="GET /healthz",code="200"} 1`,
+		`tfplan_http_requests_total{route="POST /inspect",code="200"} 1`,
+		`tfplan_http_requests_total{route="POST /inspect",code="400"} 1`,
+		"# HELP tfplan_http_request_duration_seconds Latency of HTTP requests by route.",
+		"# TYPE tfplan_http_request_duration_seconds histogram",
+	}, lines[:7])
+
+	assert.Contains(t, lines, `tfplan_http_request_duration_seconds_bucket{route="POST /inspect",le="0.01"} 0`)
+	assert.Contains(t, lines, `tfplan_http_request_duration_seconds_bucket{route="POST /inspect",le="0.025"} 1`)
+	assert.Contains(t, lines, `tfplan_http_request_duration_seconds_bucket{route="POST /inspect",le="2.5"} 2`)
+	assert.Contains(t, lines, `tfplan_http_request_duration_seconds_bucket{route="POST /inspect",le="+Inf"} 2`)
+	assert.Contains(t, lines, `tfplan_http_request_duration_seconds_sum{route="POST /inspect"} 2.02`)
+	assert.Contains(t, lines, `tfplan_http_request_duration_seconds_count{route="POST /inspect"} 2`)
+}
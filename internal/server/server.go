@@ -0,0 +1,288 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+)
+
+const (
+	// Default maximum size of a request body.
+	DefaultMaxBodyBytes = 10 << 20
+	// Default maximum time to handle a request.
+	DefaultTimeout = 30 * time.Second
+)
+
+type Options struct {
+	// Maximum size in bytes of a request body. Defaults to
+	// DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// Maximum time to handle a request. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// Maximum number of requests handled at once. Defaults to the number of
+	// CPUs.
+	MaxConcurrent int
+}
+
+// Body of POST /inspect and POST /summary.
+type InspectRequest struct {
+	// The JSON Terraform plan.
+	Plan *tfplan.Plan `json:"plan"`
+	// Optional filter to apply to the plan. ${env:NAME} placeholders are
+	// not allowed.
+	Filter *tfplan.InspectFilter `json:"filter"`
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string `json:"set"`
+}
+
+// Body of POST /compare.
+type CompareRequest struct {
+	// The JSON Terraform plans to compare.
+	PlanA *tfplan.Plan `json:"planA"`
+	PlanB *tfplan.Plan `json:"planB"`
+	// Optional filter to apply to both plans. ${env:NAME} placeholders are
+	// not allowed.
+	Filter *tfplan.InspectFilter `json:"filter"`
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string `json:"set"`
+	// Optional options making the compare tolerate equivalent values.
+	Options *tfplan.CompareOptions `json:"options"`
+}
+
+// Body of POST /filter/lint.
+type LintRequest struct {
+	// The filter to lint.
+	Filter *tfplan.InspectFilter `json:"filter"`
+}
+
+// Body of every error response.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Serves the inspect, compare, summary and filter lint JSON API along with
+// a health check and Prometheus metrics.
+type Server struct {
+	opts    *Options
+	mux     *http.ServeMux
+	metrics *metrics
+	// Holds a slot per request being handled.
+	slots chan struct{}
+}
+
+func New(opts *Options) *Server {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxBodyBytes <= 0 {
+		o.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.MaxConcurrent <= 0 {
+		o.MaxConcurrent = runtime.NumCPU()
+	}
+
+	s := &Server{opts: &o, mux: http.NewServeMux(), metrics: newMetrics(), slots: make(chan struct{}, o.MaxConcurrent)}
+	s.handle("POST /inspect", s.limit(s.inspect))
+	s.handle("POST /compare", s.limit(s.compare))
+	s.handle("POST /summary", s.limit(s.summary))
+	s.handle("POST /filter/lint", s.limit(s.lint))
+	s.handle("GET /healthz", s.health)
+	s.mux.HandleFunc("GET /metrics", s.writeMetrics)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Records the status code written by a handler for the metrics.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+/*
+Limits the handler to MaxConcurrent requests at once. Requests wait for a
+slot until they time out. The operations stop when the request times out
+and the handler holds its slot until they have.
+*/
+func (s *Server) limit(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case s.slots <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		defer func() { <-s.slots }()
+		handler(w, r)
+	}
+}
+
+/*
+Registers the handler under the pattern with the body size limit, the
+timeout and request metrics applied.
+*/
+func (s *Server) handle(pattern string, handler http.HandlerFunc) {
+	timeoutBody, _ := json.Marshal(&ErrorResponse{Error: "request timed out"})
+	timed := http.TimeoutHandler(handler, s.opts.Timeout, string(timeoutBody))
+
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		// Kept by the timeout response, handlers set their own
+		rec.Header().Set("Content-Type", "application/json")
+
+		r.Body = http.MaxBytesReader(rec, r.Body, s.opts.MaxBodyBytes)
+		timed.ServeHTTP(rec, r)
+
+		s.metrics.observe(pattern, rec.code, time.Since(start))
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		code = http.StatusInternalServerError
+		bytes, _ = json.Marshal(&ErrorResponse{Error: fmt.Sprintf("failed to json marshal response caused by: %v", err)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(append(bytes, '\n'))
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, &ErrorResponse{Error: err.Error()})
+}
+
+/*
+Decodes the JSON request body into v, writing an error response and
+returning false when it cannot.
+*/
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", maxBytesErr.Limit))
+			return false
+		}
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unable to decode request body caused by: %w", err))
+		return false
+	}
+	return true
+}
+
+/*
+Rejects filters with ${env:NAME} placeholders, writing an error response and
+returning false, as they would read the environment of the server.
+*/
+func checkFilter(w http.ResponseWriter, filter *tfplan.InspectFilter) bool {
+	if placeholders := filter.EnvPlaceholders(); len(placeholders) > 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("env placeholders are not allowed in requests, found %s", strings.Join(placeholders, ", ")))
+		return false
+	}
+	return true
+}
+
+func (s *Server) inspect(w http.ResponseWriter, r *http.Request) {
+	req := &InspectRequest{}
+	if !decode(w, r, req) {
+		return
+	}
+	if req.Plan == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("plan cannot be empty"))
+		return
+	}
+	if !checkFilter(w, req.Filter) {
+		return
+	}
+
+	out, err := tfplan.Inspect(r.Context(), req.Plan, tfplan.WithFilter(req.Filter), tfplan.WithSet(req.Set))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) compare(w http.ResponseWriter, r *http.Request) {
+	req := &CompareRequest{}
+	if !decode(w, r, req) {
+		return
+	}
+	if req.PlanA == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("planA cannot be empty"))
+		return
+	}
+	if req.PlanB == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("planB cannot be empty"))
+		return
+	}
+	if !checkFilter(w, req.Filter) {
+		return
+	}
+
+	out, err := tfplan.Compare(r.Context(), req.PlanA, req.PlanB, tfplan.WithFilter(req.Filter), tfplan.WithSet(req.Set), tfplan.WithCompareOptions(req.Options))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) summary(w http.ResponseWriter, r *http.Request) {
+	req := &InspectRequest{}
+	if !decode(w, r, req) {
+		return
+	}
+	if req.Plan == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("plan cannot be empty"))
+		return
+	}
+	if !checkFilter(w, req.Filter) {
+		return
+	}
+
+	out, err := tfplan.Summary(r.Context(), req.Plan, tfplan.WithFilter(req.Filter), tfplan.WithSet(req.Set))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) lint(w http.ResponseWriter, r *http.Request) {
+	req := &LintRequest{}
+	if !decode(w, r, req) {
+		return
+	}
+	if req.Filter == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("filter cannot be empty"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tfplan.LintFilter(req.Filter))
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) writeMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.write(w)
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPlan = `{
+	"format_version": "1.2",
+	"resource_changes": [
+		{
+			"address": "aws_lambda_function.this",
+			"mode": "managed",
+			"type": "aws_lambda_function",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"source_code_hash": "a"}, "after": {"source_code_hash": "b"}}
+		}
+	]
+}`
+
+const testFilter = `{"resourceChanges": [{"namePattern": "aws_lambda_function.*", "diffPatterns": {".source_code_hash": [{"before": "*", "after": "*"}]}}]}`
+
+func Test_Server(t *testing.T) {
+	cases := map[string]struct {
+		method       string
+		path         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		"inspect": {
+			method:       "POST",
+			path:         "/inspect",
+			body:         `{"plan": ` + testPlan + `}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"diff":{"resources":{"aws_lambda_function.this":{".source_code_hash":{"before":"a","after":"b"}}},"outputs":{},"resourceDrifts":{},"resourceActions":{"aws_lambda_function.this":["update"]}}}`,
+		},
+		"inspect filtered": {
+			method:       "POST",
+			path:         "/inspect",
+			body:         `{"plan": ` + testPlan + `, "filter": ` + testFilter + `}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"diff":{"resources":{},"outputs":{},"resourceDrifts":{}}}`,
+		},
+		"inspect without plan": {
+			method:       "POST",
+			path:         "/inspect",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"plan cannot be empty"}`,
+		},
+		"inspect unresolved placeholder": {
+			method:       "POST",
+			path:         "/inspect",
+			body:         `{"plan": ` + testPlan + `, "filter": {"resourceChanges": [{"namePattern": "${set:name}"}]}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"failed to resolve filter caused by: unable to resolve resource changes caused by: filter 0 name pattern: unable to resolve placeholder ${set:name}"}`,
+		},
+		"inspect env placeholder": {
+			method:       "POST",
+			path:         "/inspect",
+			body:         `{"plan": ` + testPlan + `, "filter": {"resourceChanges": [{"namePattern": "*", "condition": "after(\".name\") == \"${env:PATH}\""}]}}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"env placeholders are not allowed in requests, found ${env:PATH}"}`,
+		},
+		"summary env placeholder": {
+			method:       "POST",
+			path:         "/summary",
+			body:         `{"plan": ` + testPlan + `, "filter": {"resourceChanges": [{"namePattern": "${env:HOME}"}]}}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"env placeholders are not allowed in requests, found ${env:HOME}"}`,
+		},
+		"compare": {
+			method:       "POST",
+			path:         "/compare",
+			body:         `{"planA": ` + testPlan + `, "planB": ` + testPlan + `}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"diff":{"resources":{},"outputs":{},"resourceDrifts":{}}}`,
+		},
+		"compare without plan b": {
+			method:       "POST",
+			path:         "/compare",
+			body:         `{"planA": ` + testPlan + `}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"planB cannot be empty"}`,
+		},
+		"compare env placeholder": {
+			method:       "POST",
+			path:         "/compare",
+			body:         `{"planA": ` + testPlan + `, "planB": ` + testPlan + `, "filter": {"driftChanges": [{"namePattern": "*", "diffPatterns": {".tags.${env:PATH}": [{"before": "*", "after": "*"}]}}]}}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"env placeholders are not allowed in requests, found ${env:PATH}"}`,
+		},
+		"lint": {
+			method:       "POST",
+			path:         "/filter/lint",
+			body:         `{"filter": {"outputChanges": [{"namePattern": "*", "diffPatterns": {"": [{"before": "*", "after": "*"}]}, "mode": "any"}]}}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"issues":[{"severity":"error","kind":"outputChanges","index":0,"field":"mode","message":"unknown filter mode any"}]}`,
+		},
+		"malformed body": {
+			method:       "POST",
+			path:         "/summary",
+			body:         `{`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"unable to decode request body caused by: unexpected EOF"}`,
+		},
+		"body too large": {
+			method:       "POST",
+			path:         "/inspect",
+			body:         `{"plan": ` + testPlan + `, "set": {"padding": "` + strings.Repeat("x", 1024) + `"}}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: `{"error":"request body exceeds 1024 bytes"}`,
+		},
+		"health": {
+			method:       "GET",
+			path:         "/healthz",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"ok"}`,
+		},
+		"wrong method": {
+			method:       "GET",
+			path:         "/inspect",
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := New(&Options{MaxBodyBytes: 1024})
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(tst.method, tst.path, strings.NewReader(tst.body)))
+
+			assert.Equal(t, tst.expectedCode, rec.Code)
+			if tst.expectedBody != "" {
+				assert.Equal(t, tst.expectedBody+"\n", rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_Server_Summary(t *testing.T) {
+	s := New(nil)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/summary", strings.NewReader(`{"plan": `+testPlan+`, "filter": `+testFilter+`}`)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `"all":{"total":1`)
+	assert.Contains(t, rec.Body.String(), `"remaining":{"total":0`)
+}
+
+func Test_Server_Timeout(t *testing.T) {
+	s := New(&Options{Timeout: 10 * time.Millisecond})
+	s.handle("POST /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/slow", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"error":"request timed out"}`, rec.Body.String())
+}
+
+func Test_Server_MaxConcurrent(t *testing.T) {
+	s := New(&Options{Timeout: 10 * time.Millisecond, MaxConcurrent: 1})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	s.handle("POST /slow", s.limit(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		// Keeps running after the request times out, e.g. an operation
+		// finishing the step it is in
+		<-release
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/slow", nil))
+	<-started
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// The slot is held until the slow handler returns
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/inspect", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// Health checks are not limited
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("POST", "/inspect", strings.NewReader(`{}`)))
+		return rec.Code == http.StatusBadRequest
+	}, time.Second, 5*time.Millisecond)
+}
+
+func Test_Server_Metrics(t *testing.T) {
+	s := New(nil)
+	for _, path := range []string{"/healthz", "/healthz"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/inspect", strings.NewReader(`{}`)))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `tfplan_http_requests_total{route="GET /healthz",code="200"} 2`)
+	assert.Contains(t, rec.Body.String(), `tfplan_http_requests_total{route="POST /inspect",code="400"} 1`)
+	assert.Contains(t, rec.Body.String(), `tfplan_http_request_duration_seconds_count{route="GET /healthz"} 2`)
+}
@@ -0,0 +1,395 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/orange-car/tfplan/internal/plan"
+)
+
+const help = "↑↓ move  ←→ collapse  / search  f filtered  a allow  q quit"
+
+// Input of the terminal UI.
+type Input struct {
+	// The plan to browse.
+	Plan *plan.Plan
+	// Optional filter to apply to the plan.
+	Filter *plan.InspectFilter
+	// Optional values for ${set:name} placeholders in the filter.
+	Set map[string]string
+	// Optional file the allow key writes the filter to with the new rule.
+	// The allow key is disabled when empty.
+	FilterFile string
+}
+
+// Terminal UI browsing the changes of a plan as a tree of modules, then
+// resources, then attributes.
+type App struct {
+	screen tcell.Screen
+	in     *Input
+	filter *plan.InspectFilter
+
+	tree      []*node
+	rows      []row
+	collapsed map[string]bool
+
+	showFiltered bool
+	// The search query and whether it is being typed.
+	query     string
+	searching bool
+
+	cursor int
+	offset int
+	status string
+
+	// Number of attribute changes and how many of them are filtered.
+	total    int
+	filtered int
+}
+
+/*
+Creates the terminal UI drawing to the screen. The screen must be
+initialised by the caller.
+*/
+func New(screen tcell.Screen, in *Input) (*App, error) {
+	if in.Plan == nil {
+		return nil, fmt.Errorf("plan cannot be empty")
+	}
+
+	filter := in.Filter
+	if filter == nil {
+		filter = &plan.InspectFilter{}
+	}
+
+	a := &App{
+		screen:    screen,
+		in:        in,
+		filter:    filter,
+		collapsed: map[string]bool{},
+	}
+	if err := a.rebuild(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+/*
+Inspects the plan with and without the filter and rebuilds the tree.
+*/
+func (a *App) rebuild() error {
+	all, err := a.in.Plan.Inspect(&plan.InspectInput{Set: a.in.Set})
+	if err != nil {
+		return err
+	}
+	visible, err := a.in.Plan.Inspect(&plan.InspectInput{Filter: a.filter, Set: a.in.Set})
+	if err != nil {
+		return err
+	}
+
+	a.tree = buildTree(all.Diff, visible.Diff)
+
+	a.total, a.filtered = 0, 0
+	for _, modNode := range a.tree {
+		for _, entNode := range modNode.children {
+			for _, attrNode := range entNode.children {
+				a.total++
+				if attrNode.filtered {
+					a.filtered++
+				}
+			}
+		}
+	}
+
+	a.refresh()
+	return nil
+}
+
+/*
+Recomputes the visible rows keeping the cursor within them.
+*/
+func (a *App) refresh() {
+	a.rows = flatten(a.tree, a.collapsed, a.showFiltered, a.query)
+	a.move(0)
+}
+
+func (a *App) move(delta int) {
+	a.cursor += delta
+	if a.cursor >= len(a.rows) {
+		a.cursor = len(a.rows) - 1
+	}
+	if a.cursor < 0 {
+		a.cursor = 0
+	}
+}
+
+func (a *App) selected() *node {
+	if len(a.rows) == 0 {
+		return nil
+	}
+	return a.rows[a.cursor].node
+}
+
+/*
+Collapses the selected node, or moves to its parent when it has nothing
+to collapse.
+*/
+func (a *App) collapse() {
+	n := a.selected()
+	if n == nil {
+		return
+	}
+	if len(n.children) > 0 && !a.collapsed[n.key] && a.query == "" {
+		a.collapsed[n.key] = true
+		a.refresh()
+		return
+	}
+
+	depth := a.rows[a.cursor].depth
+	for i := a.cursor - 1; i >= 0; i-- {
+		if a.rows[i].depth < depth {
+			a.cursor = i
+			return
+		}
+	}
+}
+
+func (a *App) expand() {
+	if n := a.selected(); n != nil && a.collapsed[n.key] {
+		delete(a.collapsed, n.key)
+		a.refresh()
+	}
+}
+
+/*
+Adds a rule allowing the change of the selected attribute to the filter
+and writes the filter to the filter file.
+*/
+func (a *App) allow() error {
+	n := a.selected()
+	if n == nil || n.kind != nodeAttribute {
+		return fmt.Errorf("select an attribute to allow")
+	}
+	if a.in.FilterFile == "" {
+		return fmt.Errorf("no filter file to write the rule to")
+	}
+	if n.filtered {
+		return fmt.Errorf("%s%s is already filtered", n.address, n.path)
+	}
+
+	// Escaped so wildcards, exclusions, commas and placeholders in the
+	// values are matched literally
+	rule := plan.Filter{
+		NamePattern: plan.Patterns{plan.EscapePattern(n.address)},
+		DiffPatterns: map[string][]plan.DiffPattern{
+			plan.EscapePattern(n.path): {{Before: plan.Patterns{plan.EscapePattern(n.diff.Before)}, After: plan.Patterns{plan.EscapePattern(n.diff.After)}}},
+		},
+	}
+
+	switch n.entity {
+	case entityOutput:
+		a.filter.OutputChanges = append(a.filter.OutputChanges, rule)
+	case entityDrift:
+		a.filter.DriftChanges = append(a.filter.DriftChanges, rule)
+	default:
+		a.filter.ResourceChanges = append(a.filter.ResourceChanges, rule)
+	}
+
+	bytes, err := json.MarshalIndent(a.filter, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal filter caused by: %w", err)
+	}
+	if err := os.WriteFile(a.in.FilterFile, bytes, 0644); err != nil {
+		return fmt.Errorf("unable to write filter file caused by: %w", err)
+	}
+
+	if err := a.rebuild(); err != nil {
+		return err
+	}
+	a.status = fmt.Sprintf("allowed %s%s in %s", n.address, n.path, a.in.FilterFile)
+	return nil
+}
+
+/*
+Handles a key press. Returns true when the UI should quit.
+*/
+func (a *App) handleKey(ev *tcell.EventKey) bool {
+	a.status = ""
+
+	if a.searching {
+		switch ev.Key() {
+		case tcell.KeyEnter:
+			a.searching = false
+		case tcell.KeyEscape:
+			a.searching = false
+			a.query = ""
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if len(a.query) > 0 {
+				runes := []rune(a.query)
+				a.query = string(runes[:len(runes)-1])
+			}
+		case tcell.KeyRune:
+			a.query += string(ev.Rune())
+		}
+		a.cursor = 0
+		a.refresh()
+		return false
+	}
+
+	_, height := a.screen.Size()
+	page := height - 2
+
+	switch ev.Key() {
+	case tcell.KeyCtrlC:
+		return true
+	case tcell.KeyUp:
+		a.move(-1)
+	case tcell.KeyDown:
+		a.move(1)
+	case tcell.KeyPgUp:
+		a.move(-page)
+	case tcell.KeyPgDn:
+		a.move(page)
+	case tcell.KeyHome:
+		a.move(-len(a.rows))
+	case tcell.KeyEnd:
+		a.move(len(a.rows))
+	case tcell.KeyLeft:
+		a.collapse()
+	case tcell.KeyRight:
+		a.expand()
+	case tcell.KeyEnter:
+		if n := a.selected(); n != nil && a.collapsed[n.key] {
+			a.expand()
+		} else {
+			a.collapse()
+		}
+	case tcell.KeyEscape:
+		a.query = ""
+		a.refresh()
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q':
+			return true
+		case 'k':
+			a.move(-1)
+		case 'j':
+			a.move(1)
+		case 'h':
+			a.collapse()
+		case 'l':
+			a.expand()
+		case '/':
+			a.searching = true
+			a.query = ""
+			a.refresh()
+		case 'f':
+			a.showFiltered = !a.showFiltered
+			a.refresh()
+		case 'a':
+			if err := a.allow(); err != nil {
+				a.status = err.Error()
+			}
+		}
+	}
+	return false
+}
+
+func (a *App) drawText(x, y int, style tcell.Style, text string) {
+	width, _ := a.screen.Size()
+	for _, r := range text {
+		if x >= width {
+			return
+		}
+		a.screen.SetContent(x, y, r, nil, style)
+		x++
+	}
+}
+
+func (a *App) draw() {
+	a.screen.Clear()
+	width, height := a.screen.Size()
+	bold := tcell.StyleDefault.Bold(true)
+
+	shown := "hidden"
+	if a.showFiltered {
+		shown = "shown"
+	}
+	a.drawText(0, 0, bold, fmt.Sprintf("tfplan inspect: %v changes, %v filtered (%s)", a.total-a.filtered, a.filtered, shown))
+
+	// Keep the cursor on screen
+	page := height - 2
+	if a.cursor < a.offset {
+		a.offset = a.cursor
+	}
+	if page > 0 && a.cursor >= a.offset+page {
+		a.offset = a.cursor - page + 1
+	}
+
+	for i := 0; i < page && a.offset+i < len(a.rows); i++ {
+		r := a.rows[a.offset+i]
+
+		marker := "  "
+		if len(r.node.children) > 0 {
+			marker = "- "
+			if a.collapsed[r.node.key] && a.query == "" {
+				marker = "+ "
+			}
+		}
+
+		style := tcell.StyleDefault
+		if r.node.kind == nodeModule {
+			style = bold
+		}
+		if r.node.filtered {
+			style = style.Dim(true)
+		}
+		if a.offset+i == a.cursor {
+			style = style.Reverse(true)
+		}
+
+		text := strings.Repeat("  ", r.depth) + marker + r.node.label
+		if l := utf8.RuneCountInString(text); a.offset+i == a.cursor && l < width {
+			// Highlight the whole row
+			text += strings.Repeat(" ", width-l)
+		}
+		a.drawText(0, i+1, style, text)
+	}
+
+	footer := help
+	switch {
+	case a.searching:
+		footer = "/" + a.query
+	case a.status != "":
+		footer = a.status
+	case a.query != "":
+		footer = fmt.Sprintf("search: %s (esc to clear)", a.query)
+	}
+	a.drawText(0, height-1, tcell.StyleDefault, footer)
+
+	a.screen.Show()
+}
+
+/*
+Draws the UI and handles key presses until the user quits.
+*/
+func (a *App) Run() error {
+	for {
+		a.draw()
+
+		switch ev := a.screen.PollEvent().(type) {
+		case nil:
+			// The screen was finalised
+			return nil
+		case *tcell.EventResize:
+			a.screen.Sync()
+		case *tcell.EventKey:
+			if a.handleKey(ev) {
+				return nil
+			}
+		}
+	}
+}
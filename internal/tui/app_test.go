@@ -0,0 +1,239 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	tfJson "github.com/hashicorp/terraform-json"
+	"github.com/orange-car/tfplan/internal/plan"
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Reads the lines of the virtual terminal with trailing spaces trimmed.
+*/
+func screenLines(s tcell.SimulationScreen) []string {
+	cells, width, height := s.GetContents()
+	out := []string{}
+	for y := 0; y < height; y++ {
+		line := ""
+		for x := 0; x < width; x++ {
+			runes := cells[y*width+x].Runes
+			if len(runes) == 0 {
+				line += " "
+				continue
+			}
+			line += string(runes)
+		}
+		out = append(out, strings.TrimRight(line, " "))
+	}
+	return out
+}
+
+func Test_App(t *testing.T) {
+	tfplan := &plan.Plan{
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: "module.app.aws_instance.this",
+				Change: &tfJson.Change{
+					Before: map[string]any{"ami": "ami-1", "type": "t3.micro"},
+					After:  map[string]any{"ami": "ami-2", "type": "t3.large"},
+				},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		keys           []*tcell.EventKey
+		expectedOutput []string
+		expectedFilter string
+	}{
+		"tree": {
+			expectedOutput: []string{
+				"tfplan inspect: 2 changes, 0 filtered (hidden)",
+				"- module.app",
+				"  - module.app.aws_instance.this",
+				"      .ami: ami-1 -> ami-2",
+				"      .type: t3.micro -> t3.large",
+				"",
+				"",
+				help,
+			},
+		},
+		"collapse": {
+			keys: []*tcell.EventKey{
+				tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone),
+				tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone),
+			},
+			expectedOutput: []string{
+				"tfplan inspect: 2 changes, 0 filtered (hidden)",
+				"- module.app",
+				"  + module.app.aws_instance.this",
+				"",
+				"",
+				"",
+				"",
+				help,
+			},
+		},
+		"search": {
+			keys: []*tcell.EventKey{
+				tcell.NewEventKey(tcell.KeyRune, '/', tcell.ModNone),
+				tcell.NewEventKey(tcell.KeyRune, 't', tcell.ModNone),
+				tcell.NewEventKey(tcell.KeyRune, '3', tcell.ModNone),
+				tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone),
+			},
+			expectedOutput: []string{
+				"tfplan inspect: 2 changes, 0 filtered (hidden)",
+				"- module.app",
+				"  - module.app.aws_instance.this",
+				"      .type: t3.micro -> t3.large",
+				"",
+				"",
+				"",
+				"search: t3 (esc to clear)",
+			},
+		},
+		"allow and show filtered": {
+			keys: []*tcell.EventKey{
+				tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone),
+				tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone),
+				tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+				tcell.NewEventKey(tcell.KeyRune, 'f', tcell.ModNone),
+			},
+			expectedOutput: []string{
+				"tfplan inspect: 1 changes, 1 filtered (shown)",
+				"- module.app",
+				"  - module.app.aws_instance.this",
+				"      .ami: ami-1 -> ami-2",
+				"      .type: t3.micro -> t3.large",
+				"",
+				"",
+				help,
+			},
+			expectedFilter: `{
+  "outputChanges": null,
+  "resourceChanges": [
+    {
+      "namePattern": [
+        "module.app.aws_instance.this"
+      ],
+      "diffPatterns": {
+        ".ami": [
+          {
+            "before": [
+              "ami-1"
+            ],
+            "after": [
+              "ami-2"
+            ]
+          }
+        ]
+      }
+    }
+  ],
+  "driftChanges": null
+}`,
+		},
+		"allow without an attribute": {
+			keys: []*tcell.EventKey{
+				tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+			},
+			expectedOutput: []string{
+				"tfplan inspect: 2 changes, 0 filtered (hidden)",
+				"- module.app",
+				"  - module.app.aws_instance.this",
+				"      .ami: ami-1 -> ami-2",
+				"      .type: t3.micro -> t3.large",
+				"",
+				"",
+				"select an attribute to allow",
+			},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			filterFile := filepath.Join(t.TempDir(), "filter.json")
+
+			screen := tcell.NewSimulationScreen("UTF-8")
+			assert.Nil(t, screen.Init())
+			defer screen.Fini()
+			screen.SetSize(60, 8)
+
+			app, err := New(screen, &Input{Plan: tfplan, FilterFile: filterFile})
+			assert.Nil(t, err)
+
+			for _, key := range tst.keys {
+				screen.InjectKey(key.Key(), key.Rune(), key.Modifiers())
+			}
+			screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+			assert.Nil(t, app.Run())
+
+			diff.Check(t, tst.expectedOutput, screenLines(screen))
+
+			if tst.expectedFilter != "" {
+				bytes, err := os.ReadFile(filterFile)
+				assert.Nil(t, err)
+				diff.Check(t, tst.expectedFilter, string(bytes))
+			}
+		})
+	}
+}
+
+func Test_AppAllowEscapes(t *testing.T) {
+	tfplan := &plan.Plan{
+		ResourceChanges: []*tfJson.ResourceChange{
+			{
+				Address: `aws_s3_object.this["*"]`,
+				Change: &tfJson.Change{
+					Before: map[string]any{"tags": map[string]any{"a,b": "!old"}},
+					After:  map[string]any{"tags": map[string]any{"a,b": "${new}*"}},
+				},
+			},
+		},
+	}
+	filterFile := filepath.Join(t.TempDir(), "filter.json")
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	assert.Nil(t, screen.Init())
+	defer screen.Fini()
+	screen.SetSize(60, 8)
+
+	app, err := New(screen, &Input{Plan: tfplan, FilterFile: filterFile})
+	assert.Nil(t, err)
+
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'a', tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	assert.Nil(t, app.Run())
+
+	bytes, err := os.ReadFile(filterFile)
+	assert.Nil(t, err)
+	filter, err := plan.ParseInspectFilter(bytes)
+	assert.Nil(t, err)
+	diff.Check(t, []plan.Filter{
+		{
+			NamePattern: plan.Patterns{`aws_s3_object.this\["\*"]`},
+			DiffPatterns: map[string][]plan.DiffPattern{
+				`.tags.a\,b`: {{Before: plan.Patterns{`\!old`}, After: plan.Patterns{`$${new}\*`}}},
+			},
+		},
+	}, filter.ResourceChanges)
+
+	// The rule only filters out the exact change it was added for
+	out, err := tfplan.Inspect(&plan.InspectInput{Filter: filter})
+	assert.Nil(t, err)
+	assert.True(t, out.IsEmpty())
+
+	tfplan.ResourceChanges[0].Change.After = map[string]any{"tags": map[string]any{"a,b": "${new}x"}}
+	out, err = tfplan.Inspect(&plan.InspectInput{Filter: filter})
+	assert.Nil(t, err)
+	assert.False(t, out.IsEmpty())
+}
@@ -0,0 +1,271 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/orange-car/tfplan/internal/plan"
+)
+
+const (
+	nodeModule    = "module"
+	nodeEntity    = "entity"
+	nodeAttribute = "attribute"
+
+	// Entity kinds matching the InspectFilter criteria they are filtered by.
+	entityResource = "resource"
+	entityDrift    = "drift"
+	entityOutput   = "output"
+
+	rootModule   = "(root)"
+	outputsGroup = "(outputs)"
+)
+
+// A node of the modules -> resources -> attributes tree.
+type node struct {
+	kind  string
+	label string
+	// Unique key of the node used to remember its collapsed state across
+	// rebuilds of the tree.
+	key string
+
+	// Set for entity and attribute nodes.
+	entity  string
+	address string
+	// Set for attribute nodes.
+	path string
+	diff *plan.Diff
+
+	// The change is filtered out by the filter. Entities and modules are
+	// filtered when all of their children are.
+	filtered bool
+	children []*node
+}
+
+// A visible row of the flattened tree.
+type row struct {
+	node  *node
+	depth int
+}
+
+/*
+Splits an address at the dots outside of index brackets, e.g.
+module.a["x.y"].aws_instance.this -> module, a["x.y"], aws_instance, this.
+*/
+func splitAddress(address string) []string {
+	parts := []string{}
+	depth := 0
+	inQuote := false
+	start := 0
+	for i, c := range address {
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '.' && depth == 0:
+			parts = append(parts, address[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, address[start:])
+}
+
+/*
+Returns the module path of a resource address, e.g.
+module.a.module.b.aws_instance.this -> module.a.module.b. Resources of the
+root module return rootModule.
+*/
+func moduleOf(address string) string {
+	parts := splitAddress(address)
+	modules := []string{}
+	for i := 0; i+1 < len(parts) && parts[i] == "module"; i += 2 {
+		modules = append(modules, "module."+parts[i+1])
+	}
+	if len(modules) == 0 {
+		return rootModule
+	}
+	return strings.Join(modules, ".")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+Builds the tree from the un-filtered and filtered inspects of the plan.
+Changes missing from the filtered inspect are marked as filtered.
+*/
+func buildTree(all, visible *plan.InspectDiff) []*node {
+	modules := map[string]*node{}
+	moduleOrder := []string{}
+
+	moduleNode := func(name string) *node {
+		if _, ok := modules[name]; !ok {
+			modules[name] = &node{kind: nodeModule, label: name, key: "module/" + name, filtered: true}
+			moduleOrder = append(moduleOrder, name)
+		}
+		return modules[name]
+	}
+
+	add := func(entity string, all, visible map[string]plan.EntityDiff) {
+		for _, address := range sortedKeys(all) {
+			module := moduleOf(address)
+			label := address
+			switch entity {
+			case entityOutput:
+				module = outputsGroup
+				label = "output." + address
+			case entityDrift:
+				label = address + " (drift)"
+			}
+
+			entNode := &node{
+				kind:     nodeEntity,
+				label:    label,
+				key:      entity + "/" + address,
+				entity:   entity,
+				address:  address,
+				filtered: true,
+			}
+
+			for _, path := range sortedKeys(all[address]) {
+				diff := all[address][path]
+				_, ok := visible[address][path]
+
+				entNode.children = append(entNode.children, &node{
+					kind:     nodeAttribute,
+					label:    fmt.Sprintf("%s: %s -> %s", path, diff.Before, diff.After),
+					key:      entity + "/" + address + "/" + path,
+					entity:   entity,
+					address:  address,
+					path:     path,
+					diff:     diff,
+					filtered: !ok,
+				})
+				if ok {
+					entNode.filtered = false
+				}
+			}
+
+			modNode := moduleNode(module)
+			modNode.children = append(modNode.children, entNode)
+			if !entNode.filtered {
+				modNode.filtered = false
+			}
+		}
+	}
+
+	add(entityResource, all.Resources, visible.Resources)
+	add(entityDrift, all.ResourceDrifts, visible.ResourceDrifts)
+	add(entityOutput, all.Outputs, visible.Outputs)
+
+	sort.SliceStable(moduleOrder, func(i, j int) bool {
+		// The root module first and outputs last
+		rank := func(name string) int {
+			switch name {
+			case rootModule:
+				return 0
+			case outputsGroup:
+				return 2
+			}
+			return 1
+		}
+		if rank(moduleOrder[i]) != rank(moduleOrder[j]) {
+			return rank(moduleOrder[i]) < rank(moduleOrder[j])
+		}
+		return moduleOrder[i] < moduleOrder[j]
+	})
+
+	out := []*node{}
+	for _, name := range moduleOrder {
+		modNode := modules[name]
+		// Drifts and resources of the same module are listed by address
+		sort.SliceStable(modNode.children, func(i, j int) bool {
+			return modNode.children[i].label < modNode.children[j].label
+		})
+		out = append(out, modNode)
+	}
+	return out
+}
+
+/*
+Checks if the node or any of its descendants contains the query.
+*/
+func (n *node) matches(query string, showFiltered bool) bool {
+	if n.filtered && !showFiltered {
+		return false
+	}
+	if strings.Contains(strings.ToLower(n.label), query) {
+		return true
+	}
+	for _, child := range n.children {
+		if child.matches(query, showFiltered) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Flattens the tree into the visible rows. Filtered nodes are hidden unless
+showFiltered is set. With a search query only the matching nodes and their
+ancestors are visible and collapsed nodes are expanded.
+*/
+func flatten(nodes []*node, collapsed map[string]bool, showFiltered bool, query string) []row {
+	query = strings.ToLower(query)
+	out := []row{}
+
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		if n.filtered && !showFiltered {
+			return
+		}
+		if query != "" && !n.matches(query, showFiltered) {
+			return
+		}
+
+		out = append(out, row{node: n, depth: depth})
+		if collapsed[n.key] && query == "" {
+			return
+		}
+
+		for _, child := range n.children {
+			if query != "" && strings.Contains(strings.ToLower(n.label), query) {
+				// Every child of a matching node is visible
+				if !child.filtered || showFiltered {
+					walkAll(child, depth+1, showFiltered, &out)
+				}
+				continue
+			}
+			walk(child, depth+1)
+		}
+	}
+
+	for _, n := range nodes {
+		walk(n, 0)
+	}
+	return out
+}
+
+/*
+Appends the node and all of its descendants to the rows.
+*/
+func walkAll(n *node, depth int, showFiltered bool, out *[]row) {
+	*out = append(*out, row{node: n, depth: depth})
+	for _, child := range n.children {
+		if child.filtered && !showFiltered {
+			continue
+		}
+		walkAll(child, depth+1, showFiltered, out)
+	}
+}
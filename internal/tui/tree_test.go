@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/orange-car/tfplan/internal/plan"
+	"github.com/orange-car/tfplan/internal/testing/diff"
+)
+
+func Test_moduleOf(t *testing.T) {
+	cases := map[string]struct {
+		address        string
+		expectedOutput string
+	}{
+		"root": {
+			address:        "aws_instance.this",
+			expectedOutput: "(root)",
+		},
+		"nested modules": {
+			address:        "module.a.module.b[0].aws_instance.this",
+			expectedOutput: "module.a.module.b[0]",
+		},
+		"dots in keys": {
+			address:        `module.a["x.module.y"].aws_instance.this["a.b"]`,
+			expectedOutput: `module.a["x.module.y"]`,
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			diff.Check(t, tst.expectedOutput, moduleOf(tst.address))
+		})
+	}
+}
+
+func Test_flatten(t *testing.T) {
+	all := &plan.InspectDiff{
+		Resources: map[string]plan.EntityDiff{
+			"aws_instance.this": {
+				".ami":  {Before: "ami-1", After: "ami-2"},
+				".tags": {Before: "a", After: "b"},
+			},
+			"module.app.aws_s3_bucket.this": {
+				".acl": {Before: "private", After: "public"},
+			},
+		},
+		ResourceDrifts: map[string]plan.EntityDiff{},
+		Outputs: map[string]plan.EntityDiff{
+			"url": {".": {Before: "a", After: "b"}},
+		},
+	}
+	visible := &plan.InspectDiff{
+		Resources: map[string]plan.EntityDiff{
+			"aws_instance.this": {
+				".ami": {Before: "ami-1", After: "ami-2"},
+			},
+		},
+		ResourceDrifts: map[string]plan.EntityDiff{},
+		Outputs:        map[string]plan.EntityDiff{},
+	}
+	tree := buildTree(all, visible)
+
+	labels := func(rows []row) []string {
+		out := []string{}
+		for _, r := range rows {
+			out = append(out, r.node.label)
+		}
+		return out
+	}
+
+	cases := map[string]struct {
+		collapsed      map[string]bool
+		showFiltered   bool
+		query          string
+		expectedOutput []string
+	}{
+		"filtered hidden": {
+			expectedOutput: []string{"(root)", "aws_instance.this", ".ami: ami-1 -> ami-2"},
+		},
+		"filtered shown": {
+			showFiltered: true,
+			expectedOutput: []string{
+				"(root)", "aws_instance.this", ".ami: ami-1 -> ami-2", ".tags: a -> b",
+				"module.app", "module.app.aws_s3_bucket.this", ".acl: private -> public",
+				"(outputs)", "output.url", ".: a -> b",
+			},
+		},
+		"collapsed": {
+			collapsed:      map[string]bool{"resource/aws_instance.this": true},
+			showFiltered:   true,
+			expectedOutput: []string{"(root)", "aws_instance.this", "module.app", "module.app.aws_s3_bucket.this", ".acl: private -> public", "(outputs)", "output.url", ".: a -> b"},
+		},
+		"search expands collapsed matches": {
+			collapsed:      map[string]bool{"module/module.app": true},
+			showFiltered:   true,
+			query:          "PUBLIC",
+			expectedOutput: []string{"module.app", "module.app.aws_s3_bucket.this", ".acl: private -> public"},
+		},
+		"search matching an entity shows its attributes": {
+			showFiltered:   true,
+			query:          "aws_instance",
+			expectedOutput: []string{"(root)", "aws_instance.this", ".ami: ami-1 -> ami-2", ".tags: a -> b"},
+		},
+	}
+
+	for name, tst := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			collapsed := tst.collapsed
+			if collapsed == nil {
+				collapsed = map[string]bool{}
+			}
+			diff.Check(t, tst.expectedOutput, labels(flatten(tree, collapsed, tst.showFiltered, tst.query)))
+		})
+	}
+}
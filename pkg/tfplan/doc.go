@@ -0,0 +1,35 @@
+/*
+Package tfplan inspects, compares and reports on JSON Terraform plans. It is
+the library behind the tfplan CLI.
+
+Parse a plan produced by terraform show --json and a filter, then inspect the
+plan for the changes the filter does not rule out:
+
+	p, err := tfplan.ParsePlan(planJSON)
+	if err != nil {
+		return err
+	}
+	filter, err := tfplan.ParseFilter(filterJSON)
+	if err != nil {
+		return err
+	}
+
+	out, err := tfplan.Inspect(ctx, p, tfplan.WithFilter(filter))
+	if err != nil {
+		return err
+	}
+	if !out.IsEmpty() {
+		// The plan needs a review
+	}
+
+Operations take a context.Context and functional options. Each operation
+documents the options it applies and ignores the rest. When the context
+ends first the operation returns early with an error wrapping the context's
+error, although the work finishes in the background, so plans must not be
+modified while an operation is running.
+
+Errors parsing inputs are returned as *ParseError and errors of operations
+as *OperationError. Both support errors.As and errors.Is, e.g. with
+ErrNilPlan or context.DeadlineExceeded.
+*/
+package tfplan
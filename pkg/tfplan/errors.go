@@ -0,0 +1,88 @@
+package tfplan
+
+import (
+	"context"
+	"errors"
+
+	"github.com/orange-car/tfplan/internal/plan"
+)
+
+var (
+	// A nil plan was passed to an operation.
+	ErrNilPlan = errors.New("plan cannot be empty")
+	// Fewer than 2 plans were passed to CompareMany.
+	ErrTooFewPlans = errors.New("at least 2 plans are required to compare")
+	// Plans passed to CompareMany share a label.
+	ErrDuplicateLabel = errors.New("plan labels must be unique")
+)
+
+// Kinds of Error, matched with errors.Is.
+var (
+	ErrInvalidPlan              = plan.ErrInvalidPlan
+	ErrUnsupportedFormatVersion = plan.ErrUnsupportedFormatVersion
+	ErrInvalidFilter            = plan.ErrInvalidFilter
+	ErrInvalidPattern           = plan.ErrInvalidPattern
+	ErrFilterConflict           = plan.ErrFilterConflict
+)
+
+// An error located in the plan and filter, i.e. by the resource address,
+// attribute path and filter rule. Found in the chain of a ParseError or
+// OperationError with errors.As.
+type Error = plan.Error
+
+// An input such as a plan or filter could not be parsed.
+type ParseError struct {
+	// What was being parsed, e.g. "plan" or "filter".
+	Input string
+	// The underlying error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// An operation such as Inspect or Compare failed. Wraps context.Canceled
+// or context.DeadlineExceeded when the context ended first.
+type OperationError struct {
+	// The operation, e.g. "inspect" or "compare".
+	Op string
+	// The underlying error.
+	Err error
+}
+
+func (e *OperationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+/*
+Runs fn for the operation with the context, which fn passes on to the plan
+so it stops early when the context ends. fn has returned by the time run
+does, so no work is left running once the operation fails on the context.
+*/
+func run[T any](ctx context.Context, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return zero, &OperationError{Op: op, Err: err}
+	}
+
+	out, err := fn(ctx)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return zero, &OperationError{Op: op, Err: ctxErr}
+	}
+	if err != nil {
+		return zero, &OperationError{Op: op, Err: err}
+	}
+	return out, nil
+}
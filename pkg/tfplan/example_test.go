@@ -0,0 +1,141 @@
+package tfplan_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/orange-car/tfplan/pkg/tfplan"
+)
+
+const examplePlan = `{
+	"format_version": "1.2",
+	"resource_changes": [
+		{
+			"address": "aws_lambda_function.this",
+			"mode": "managed",
+			"type": "aws_lambda_function",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"source_code_hash": "a"}, "after": {"source_code_hash": "b"}}
+		},
+		{
+			"address": "aws_security_group.this",
+			"mode": "managed",
+			"type": "aws_security_group",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"description": "a"}, "after": {"description": "b"}}
+		}
+	]
+}`
+
+const exampleFilter = `{
+	"resourceChanges": [
+		{"namePattern": "aws_lambda_function.*", "diffPatterns": {".source_code_hash": [{"before": "*", "after": "*"}]}}
+	]
+}`
+
+func ExampleInspect() {
+	p, err := tfplan.ParsePlan([]byte(examplePlan))
+	if err != nil {
+		panic(err)
+	}
+	filter, err := tfplan.ParseFilter([]byte(exampleFilter))
+	if err != nil {
+		panic(err)
+	}
+
+	out, err := tfplan.Inspect(context.Background(), p, tfplan.WithFilter(filter))
+	if err != nil {
+		panic(err)
+	}
+	for address, changes := range out.Diff.Resources {
+		for path, diff := range changes {
+			fmt.Printf("%s%s: %s -> %s\n", address, path, diff.Before, diff.After)
+		}
+	}
+	// Output: aws_security_group.this.description: a -> b
+}
+
+func ExampleCompare() {
+	a, _ := tfplan.ParsePlan([]byte(examplePlan))
+	b, _ := tfplan.ParsePlan([]byte(examplePlan))
+
+	out, err := tfplan.Compare(context.Background(), a, b,
+		tfplan.WithCompareOptions(&tfplan.CompareOptions{IgnorePaths: tfplan.Patterns{".source_code_hash"}}),
+	)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("plans differ:", !out.IsEmpty())
+	// Output: plans differ: false
+}
+
+func ExampleSummary() {
+	p, _ := tfplan.ParsePlan([]byte(examplePlan))
+	filter, _ := tfplan.ParseFilter([]byte(exampleFilter))
+
+	out, err := tfplan.Summary(context.Background(), p, tfplan.WithFilter(filter))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%d changes, %d remaining\n", out.All.Total, out.Remaining.Total)
+	// Output: 2 changes, 1 remaining
+}
+
+func ExampleLintFilter() {
+	filter, _ := tfplan.ParseFilter([]byte(`{"resourceChanges": [{"namePattern": "*", "diffPatterns": {"*": []}, "mode": "any"}]}`))
+
+	for _, issue := range tfplan.LintFilter(filter).Issues {
+		fmt.Printf("%s %s[%d].%s: %s\n", issue.Severity, issue.Kind, issue.Index, issue.Field, issue.Message)
+	}
+	// Output:
+	// warning resourceChanges[0].diffPatterns[*]: no before and after patterns so the path never matches
+	// error resourceChanges[0].mode: unknown filter mode any
+}
+
+func ExampleOperationError() {
+	p, _ := tfplan.ParsePlan([]byte(examplePlan))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	cancel()
+
+	_, err := tfplan.Inspect(ctx, p)
+
+	var opErr *tfplan.OperationError
+	if errors.As(err, &opErr) {
+		fmt.Println(opErr.Op, "failed:", errors.Is(err, context.Canceled))
+	}
+	// Output: inspect failed: true
+}
+
+func ExampleError() {
+	p, _ := tfplan.ParsePlan([]byte(examplePlan))
+	filter, _ := tfplan.ParseFilter([]byte(`{"resourceChanges": [{"namePattern": ["aws_*", "!"]}]}`))
+
+	_, err := tfplan.Inspect(context.Background(), p, tfplan.WithFilter(filter))
+
+	var planErr *tfplan.Error
+	if errors.As(err, &planErr) {
+		fmt.Printf("%s[%d]: %s\n", planErr.Filter, planErr.Rule, planErr.Message)
+		fmt.Println("invalid pattern:", errors.Is(err, tfplan.ErrInvalidPattern))
+	}
+	// Output:
+	// resourceChanges[0]: pattern ! in namePattern excludes nothing
+	// invalid pattern: true
+}
+
+func ExampleFilterConflicts() {
+	filter, _ := tfplan.ParseFilter([]byte(`{"resourceChanges": [{"namePattern": ["aws_*", "!aws_*"]}]}`))
+
+	err := tfplan.FilterConflicts(filter)
+
+	var planErr *tfplan.Error
+	if errors.As(err, &planErr) {
+		fmt.Printf("%s[%d]: %s\n", planErr.Filter, planErr.Rule, planErr.Message)
+		fmt.Println("conflict:", errors.Is(err, tfplan.ErrFilterConflict))
+	}
+	// Output:
+	// resourceChanges[0]: pattern aws_* in namePattern is both included and excluded
+	// conflict: true
+}
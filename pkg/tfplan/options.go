@@ -0,0 +1,96 @@
+package tfplan
+
+import "time"
+
+// The settings an Option applies to an operation.
+type options struct {
+	filter         *InspectFilter
+	set            map[string]string
+	configDir      string
+	rewrites       *RewriteRules
+	scopes         []string
+	metadataA      *PlanMetadata
+	metadataB      *PlanMetadata
+	compare        *CompareOptions
+	unfilteredOnly bool
+	now            func() time.Time
+}
+
+// Configures an operation. Operations ignore options which do not apply
+// to them, e.g. WithScopes for Inspect.
+type Option func(o *options)
+
+func newOptions(opts []Option) *options {
+	o := &options{now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Filters out the changes matching the filter.
+func WithFilter(filter *InspectFilter) Option {
+	return func(o *options) {
+		o.filter = filter
+	}
+}
+
+// Provides the values of ${set:name} placeholders in the filter.
+func WithSet(set map[string]string) Option {
+	return func(o *options) {
+		o.set = set
+	}
+}
+
+// Attaches the configuration file and line of each inspected change, found
+// in the Terraform configuration in the directory.
+func WithConfigDir(dir string) Option {
+	return func(o *options) {
+		o.configDir = dir
+	}
+}
+
+// Rewrites the inspected addresses and values before they are compared or
+// snapshotted.
+func WithRewrites(rules *RewriteRules) Option {
+	return func(o *options) {
+		o.rewrites = rules
+	}
+}
+
+// Compares the additional scopes. See the CompareScope constants.
+func WithScopes(scopes ...string) Option {
+	return func(o *options) {
+		o.scopes = append(o.scopes, scopes...)
+	}
+}
+
+// Compares the metadata instead of the metadata held by the plans.
+func WithMetadata(a, b *PlanMetadata) Option {
+	return func(o *options) {
+		o.metadataA = a
+		o.metadataB = b
+	}
+}
+
+// Tolerates equivalent values when comparing. The IgnorePaths and
+// UnorderedLists also apply to Fingerprint.
+func WithCompareOptions(opts *CompareOptions) Option {
+	return func(o *options) {
+		o.compare = opts
+	}
+}
+
+// Leaves the filtered changes out of the graph instead of marking them.
+func WithUnfilteredOnly() Option {
+	return func(o *options) {
+		o.unfilteredOnly = true
+	}
+}
+
+// Sets the clock used to timestamp approvals. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
@@ -0,0 +1,75 @@
+package tfplan
+
+import (
+	"crypto/ed25519"
+
+	"github.com/orange-car/tfplan/internal/plan"
+)
+
+func parse[T any](input string, data []byte, fn func([]byte) (T, error)) (T, error) {
+	out, err := fn(data)
+	if err != nil {
+		var zero T
+		return zero, &ParseError{Input: input, Err: err}
+	}
+	return out, nil
+}
+
+// Parses a JSON Terraform plan, as produced by terraform show --json.
+func ParsePlan(data []byte) (*Plan, error) {
+	p, err := parse("plan", data, plan.ParsePlan)
+	return (*Plan)(p), err
+}
+
+// Parses the metadata of a JSON Terraform plan, such as the Terraform
+// version and whether it is applyable.
+func ParsePlanMetadata(data []byte) (*PlanMetadata, error) {
+	return parse("plan metadata", data, plan.ParsePlanMetadata)
+}
+
+// Parses a JSON filter.
+func ParseFilter(data []byte) (*InspectFilter, error) {
+	return parse("filter", data, plan.ParseInspectFilter)
+}
+
+// Parses JSON rewrite rules.
+func ParseRewriteRules(data []byte) (*RewriteRules, error) {
+	return parse("rewrite rules", data, plan.ParseRewriteRules)
+}
+
+// Parses a JSON policy set.
+func ParsePolicySet(data []byte) (*PolicySet, error) {
+	return parse("policy set", data, plan.ParsePolicySet)
+}
+
+// Parses a JSON snapshot, checking its version and hash.
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	s, err := parse("snapshot", data, plan.ParseSnapshot)
+	return (*Snapshot)(s), err
+}
+
+// Parses the JSON output of Inspect.
+func ParseInspectOutput(data []byte) (*InspectOutput, error) {
+	return parse("inspect output", data, plan.ParseInspectOutput)
+}
+
+// Parses the JSON output of Compare.
+func ParseCompareOutput(data []byte) (*CompareInspectsOutput, error) {
+	return parse("compare output", data, plan.ParseCompareInspectsOutput)
+}
+
+// Parses a JSON approval.
+func ParseApproval(data []byte) (*Approval, error) {
+	a, err := parse("approval", data, plan.ParseApproval)
+	return (*Approval)(a), err
+}
+
+// Parses a JSON list of allowed signers.
+func ParseAllowedSigners(data []byte) (*AllowedSigners, error) {
+	return parse("allowed signers", data, plan.ParseAllowedSigners)
+}
+
+// Parses a PEM encoded PKCS #8 ed25519 private key.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	return parse("private key", data, plan.ParsePrivateKey)
+}
@@ -0,0 +1,317 @@
+package tfplan
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/orange-car/tfplan/internal/plan"
+)
+
+func checkPlan(op string, plans ...*Plan) error {
+	for _, p := range plans {
+		if p == nil {
+			return &OperationError{Op: op, Err: ErrNilPlan}
+		}
+	}
+	return nil
+}
+
+/*
+Inspects the plan for changes to resources, resource drift and outputs not
+filtered out by the filter of WithFilter.
+
+Applies WithFilter, WithSet and WithConfigDir.
+*/
+func Inspect(ctx context.Context, p *Plan, opts ...Option) (*InspectOutput, error) {
+	if err := checkPlan("inspect", p); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "inspect", func(ctx context.Context) (*InspectOutput, error) {
+		return (*plan.Plan)(p).Inspect(&plan.InspectInput{Filter: o.filter, Set: o.set, ConfigDir: o.configDir, Context: ctx})
+	})
+}
+
+/*
+Inspects both plans and reports the differences between their changes. The
+output is empty when the plans plan the same changes.
+
+Applies WithFilter, WithSet, WithRewrites, WithScopes, WithMetadata and
+WithCompareOptions.
+*/
+func Compare(ctx context.Context, a, b *Plan, opts ...Option) (*CompareInspectsOutput, error) {
+	if err := checkPlan("compare", a, b); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "compare", func(ctx context.Context) (*CompareInspectsOutput, error) {
+		return plan.ComparePlans((*plan.Plan)(a), (*plan.Plan)(b), &plan.ComparePlansInput{
+			Filter:    o.filter,
+			Set:       o.set,
+			Rewrites:  o.rewrites,
+			Scopes:    o.scopes,
+			MetadataA: o.metadataA,
+			MetadataB: o.metadataB,
+			Options:   o.compare,
+			Context:   ctx,
+		})
+	})
+}
+
+/*
+Reports the differences between the outputs of two earlier inspections.
+
+Applies WithCompareOptions.
+*/
+func CompareInspects(ctx context.Context, a, b *InspectOutput, opts ...Option) (*CompareInspectsOutput, error) {
+	o := newOptions(opts)
+
+	return run(ctx, "compare", func(ctx context.Context) (*CompareInspectsOutput, error) {
+		if o.compare == nil {
+			return plan.CompareInspects(a, b), nil
+		}
+		return plan.CompareInspectsWithOptions(a, b, o.compare)
+	})
+}
+
+// A plan to compare with CompareMany.
+type LabelledPlan struct {
+	// The label the plan is reported under, e.g. the environment.
+	Label string
+	// The plan.
+	Plan *Plan
+}
+
+/*
+Inspects each plan and reports every diverging change against each plan,
+marking the plans differing from the majority as outliers.
+
+Applies WithFilter, WithSet and WithRewrites.
+*/
+func CompareMany(ctx context.Context, plans []LabelledPlan, opts ...Option) (*CompareMatrixOutput, error) {
+	if len(plans) < 2 {
+		return nil, &OperationError{Op: "compare", Err: ErrTooFewPlans}
+	}
+	labels := map[string]bool{}
+	for _, p := range plans {
+		if err := checkPlan("compare", p.Plan); err != nil {
+			return nil, err
+		}
+		if labels[p.Label] {
+			return nil, &OperationError{Op: "compare", Err: fmt.Errorf("%w, %s is used more than once", ErrDuplicateLabel, p.Label)}
+		}
+		labels[p.Label] = true
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "compare", func(ctx context.Context) (*CompareMatrixOutput, error) {
+		inspects := []plan.LabelledInspect{}
+		for _, p := range plans {
+			out, err := (*plan.Plan)(p.Plan).Inspect(&plan.InspectInput{Filter: o.filter, Set: o.set, Context: ctx})
+			if err != nil {
+				return nil, fmt.Errorf("failed to inspect plan %s caused by: %w", p.Label, err)
+			}
+
+			out, err = out.Rewrite(o.rewrites)
+			if err != nil {
+				return nil, err
+			}
+
+			inspects = append(inspects, plan.LabelledInspect{Label: p.Label, Output: out})
+		}
+		return plan.CompareManyInspects(inspects), nil
+	})
+}
+
+/*
+Counts the changes of the plan by action, resource type, module and
+provider, with and without the filter applied.
+
+Applies WithFilter and WithSet.
+*/
+func Summary(ctx context.Context, p *Plan, opts ...Option) (*SummaryOutput, error) {
+	if err := checkPlan("summary", p); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "summary", func(ctx context.Context) (*SummaryOutput, error) {
+		return (*plan.Plan)(p).Summary(&plan.SummaryInput{Filter: o.filter, Set: o.set, Context: ctx})
+	})
+}
+
+/*
+Evaluates the JMESPath expression against the changes of the plan not
+filtered out.
+
+Applies WithFilter and WithSet.
+*/
+func Query(ctx context.Context, p *Plan, expression string, opts ...Option) (*QueryOutput, error) {
+	if err := checkPlan("query", p); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "query", func(ctx context.Context) (*QueryOutput, error) {
+		return (*plan.Plan)(p).Query(&plan.QueryInput{Expression: expression, Filter: o.filter, Set: o.set, Context: ctx})
+	})
+}
+
+/*
+Reports the changes of the plan depending on the resources matching the
+addresses, directly or through other resources.
+
+Applies WithFilter and WithSet.
+*/
+func Impact(ctx context.Context, p *Plan, addresses Patterns, opts ...Option) (*ImpactOutput, error) {
+	if err := checkPlan("impact", p); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "impact", func(ctx context.Context) (*ImpactOutput, error) {
+		return (*plan.Plan)(p).Impact(&plan.ImpactInput{Addresses: addresses, Filter: o.filter, Set: o.set, Context: ctx})
+	})
+}
+
+/*
+Builds the dependency graph of the planned changes.
+
+Applies WithFilter, WithSet and WithUnfilteredOnly.
+*/
+func Graph(ctx context.Context, p *Plan, opts ...Option) (*GraphOutput, error) {
+	if err := checkPlan("graph", p); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "graph", func(ctx context.Context) (*GraphOutput, error) {
+		return (*plan.Plan)(p).Graph(&plan.GraphInput{Filter: o.filter, Set: o.set, UnfilteredOnly: o.unfilteredOnly, Context: ctx})
+	})
+}
+
+/*
+Computes stable fingerprints of the semantic change set of the plan, with
+and without the filter applied.
+
+Applies WithFilter, WithSet and the IgnorePaths and UnorderedLists of
+WithCompareOptions.
+*/
+func Fingerprint(ctx context.Context, p *Plan, opts ...Option) (*FingerprintOutput, error) {
+	if err := checkPlan("fingerprint", p); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	in := &plan.FingerprintInput{Filter: o.filter, Set: o.set}
+	if o.compare != nil {
+		in.IgnorePaths = o.compare.IgnorePaths
+		in.UnorderedLists = o.compare.UnorderedLists
+	}
+	return run(ctx, "fingerprint", func(ctx context.Context) (*FingerprintOutput, error) {
+		in.Context = ctx
+		return (*plan.Plan)(p).Fingerprint(in)
+	})
+}
+
+// Evaluates the CEL policies of the set against each planned resource change.
+func EvaluatePolicies(ctx context.Context, p *Plan, set *PolicySet) (*PolicyOutput, error) {
+	if err := checkPlan("policy", p); err != nil {
+		return nil, err
+	}
+
+	return run(ctx, "policy", func(ctx context.Context) (*PolicyOutput, error) {
+		return (*plan.Plan)(p).EvaluatePolicies(set)
+	})
+}
+
+/*
+Reports the inspection of the plan for CI systems, e.g. as GitHub workflow
+commands or a GitLab Code Quality report.
+
+Applies WithFilter, WithSet and WithConfigDir.
+*/
+func Report(ctx context.Context, p *Plan, opts ...Option) (*CIReport, error) {
+	if err := checkPlan("report", p); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "report", func(ctx context.Context) (*CIReport, error) {
+		return (*plan.Plan)(p).CIReport(&plan.InspectInput{Filter: o.filter, Set: o.set, ConfigDir: o.configDir, Context: ctx})
+	})
+}
+
+/*
+Inspects and redacts the plan producing a snapshot of it.
+
+Applies WithFilter, WithSet and WithRewrites.
+*/
+func SaveSnapshot(ctx context.Context, p *Plan, opts ...Option) (*Snapshot, error) {
+	if err := checkPlan("snapshot", p); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "snapshot", func(ctx context.Context) (*Snapshot, error) {
+		s, err := (*plan.Plan)(p).Snapshot(&plan.SaveSnapshotInput{Filter: o.filter, Set: o.set, Rewrites: o.rewrites, Context: ctx})
+		return (*Snapshot)(s), err
+	})
+}
+
+// Compares the plan with the snapshot. The output is empty when the plan
+// matches the snapshot.
+func VerifySnapshot(ctx context.Context, s *Snapshot, p *Plan) (*CompareInspectsOutput, error) {
+	if err := checkPlan("verify snapshot", p); err != nil {
+		return nil, err
+	}
+
+	return run(ctx, "verify snapshot", func(ctx context.Context) (*CompareInspectsOutput, error) {
+		return (*plan.Snapshot)(s).Verify((*plan.Plan)(p))
+	})
+}
+
+/*
+Signs an approval of the digest of the plan's changes and the filter the
+plan was inspected with.
+
+Applies WithFilter, WithSet and WithClock.
+*/
+func Approve(ctx context.Context, p *Plan, signer string, key ed25519.PrivateKey, opts ...Option) (*Approval, error) {
+	if err := checkPlan("approve", p); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	return run(ctx, "approve", func(ctx context.Context) (*Approval, error) {
+		a, err := (*plan.Plan)(p).Approve(&plan.ApproveInput{Filter: o.filter, Set: o.set, Signer: signer, Key: key, Now: o.now()})
+		return (*Approval)(a), err
+	})
+}
+
+// Verifies the approval is signed by one of the allowed signers and matches
+// the plan.
+func VerifyApproval(ctx context.Context, a *Approval, p *Plan, allowed *AllowedSigners) error {
+	if err := checkPlan("verify approval", p); err != nil {
+		return err
+	}
+
+	_, err := run(ctx, "verify approval", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, (*plan.Approval)(a).Verify((*plan.Plan)(p), allowed)
+	})
+	return err
+}
+
+// Checks the filter for mistakes without a plan.
+func LintFilter(filter *InspectFilter) *LintOutput {
+	return filter.Lint()
+}
+
+// Checks the filter for patterns which are both included and excluded.
+// Returns nil or an error matching ErrFilterConflict with errors.Is.
+func FilterConflicts(filter *InspectFilter) error {
+	return filter.Conflicts()
+}
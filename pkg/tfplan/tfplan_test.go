@@ -0,0 +1,193 @@
+package tfplan
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orange-car/tfplan/internal/testing/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPlan = `{
+	"format_version": "1.2",
+	"resource_changes": [
+		{
+			"address": "aws_lambda_function.this",
+			"mode": "managed",
+			"type": "aws_lambda_function",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"source_code_hash": "a"}, "after": {"source_code_hash": "b"}}
+		},
+		{
+			"address": "aws_security_group.this",
+			"mode": "managed",
+			"type": "aws_security_group",
+			"name": "this",
+			"change": {"actions": ["update"], "before": {"description": "a"}, "after": {"description": "b"}}
+		}
+	]
+}`
+
+var testFilter = &InspectFilter{
+	ResourceChanges: []Filter{
+		{
+			NamePattern:  Patterns{"aws_lambda_function.*"},
+			DiffPatterns: map[string][]DiffPattern{".source_code_hash": {{Before: Patterns{"*"}, After: Patterns{"${set:hash}"}}}},
+		},
+	},
+}
+
+func mustParsePlan(t *testing.T, data string) *Plan {
+	p, err := ParsePlan([]byte(data))
+	assert.Nil(t, err)
+	return p
+}
+
+func Test_Inspect(t *testing.T) {
+	p := mustParsePlan(t, testPlan)
+
+	out, err := Inspect(context.Background(), p, WithFilter(testFilter), WithSet(map[string]string{"hash": "b"}))
+	assert.Nil(t, err)
+	diff.Check(t, map[string]EntityDiff{"aws_security_group.this": {".description": {Before: "a", After: "b"}}}, out.Diff.Resources)
+
+	// Without the set value the placeholder cannot be resolved
+	_, err = Inspect(context.Background(), p, WithFilter(testFilter))
+	opErr := &OperationError{}
+	assert.True(t, errors.As(err, &opErr))
+	assert.Equal(t, "inspect", opErr.Op)
+	assert.ErrorContains(t, err, "unable to resolve placeholder ${set:hash}")
+}
+
+func Test_Compare(t *testing.T) {
+	a := mustParsePlan(t, testPlan)
+	b := mustParsePlan(t, strings.Replace(testPlan, `"after": {"description": "b"}`, `"after": {"description": "c"}`, 1))
+
+	out, err := Compare(context.Background(), a, b)
+	assert.Nil(t, err)
+	diff.Check(t, []string{"aws_security_group.this"}, addresses(out.Diff.Resources))
+
+	out, err = Compare(context.Background(), a, b, WithCompareOptions(&CompareOptions{IgnorePaths: Patterns{".description"}}))
+	assert.Nil(t, err)
+	assert.True(t, out.IsEmpty())
+
+	inspectA, err := Inspect(context.Background(), a)
+	assert.Nil(t, err)
+	inspectB, err := Inspect(context.Background(), b)
+	assert.Nil(t, err)
+	out, err = CompareInspects(context.Background(), inspectA, inspectB)
+	assert.Nil(t, err)
+	diff.Check(t, []string{"aws_security_group.this"}, addresses(out.Diff.Resources))
+
+	matrix, err := CompareMany(context.Background(), []LabelledPlan{{Label: "a", Plan: a}, {Label: "b", Plan: b}, {Label: "c", Plan: a}})
+	assert.Nil(t, err)
+	assert.False(t, matrix.IsEmpty())
+
+	_, err = CompareMany(context.Background(), []LabelledPlan{{Label: "a", Plan: a}})
+	assert.ErrorIs(t, err, ErrTooFewPlans)
+
+	_, err = CompareMany(context.Background(), []LabelledPlan{{Label: "a", Plan: a}, {Label: "b", Plan: b}, {Label: "a", Plan: b}})
+	assert.ErrorIs(t, err, ErrDuplicateLabel)
+	assert.EqualError(t, err, "plan labels must be unique, a is used more than once")
+}
+
+func addresses[V any](m map[string]V) []string {
+	out := []string{}
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func Test_Operations(t *testing.T) {
+	p := mustParsePlan(t, testPlan)
+	ctx := context.Background()
+	opts := []Option{WithFilter(testFilter), WithSet(map[string]string{"hash": "b"})}
+
+	summary, err := Summary(ctx, p, opts...)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, summary.All.Total)
+	assert.Equal(t, 1, summary.Remaining.Total)
+
+	query, err := Query(ctx, p, "length(changes)", opts...)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1), query.Result)
+
+	graph, err := Graph(ctx, p, append(opts, WithUnfilteredOnly())...)
+	assert.Nil(t, err)
+	assert.Len(t, graph.Nodes, 1)
+
+	fingerprint, err := Fingerprint(ctx, p, opts...)
+	assert.Nil(t, err)
+	assert.NotEqual(t, fingerprint.All, fingerprint.Remaining)
+
+	report, err := Report(ctx, p, opts...)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.BlockedCount())
+
+	snapshot, err := SaveSnapshot(ctx, p, opts...)
+	assert.Nil(t, err)
+	verified, err := VerifySnapshot(ctx, snapshot, p)
+	assert.Nil(t, err)
+	assert.True(t, verified.IsEmpty())
+
+	lint := LintFilter(&InspectFilter{ResourceChanges: []Filter{{Mode: "any"}}})
+	assert.True(t, lint.HasErrors())
+}
+
+func Test_Approve(t *testing.T) {
+	p := mustParsePlan(t, testPlan)
+	key, err := ParsePrivateKey([]byte(testKey))
+	assert.Nil(t, err)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	a, err := Approve(context.Background(), p, "alice", key, WithClock(func() time.Time { return now }))
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-02T03:04:05Z", a.ApprovedAt)
+
+	allowed := &AllowedSigners{Signers: []AllowedSigner{{Name: "alice", PublicKey: a.PublicKey}}}
+	assert.Nil(t, VerifyApproval(context.Background(), a, p, allowed))
+	assert.EqualError(t, VerifyApproval(context.Background(), a, p, &AllowedSigners{}), "signer alice is not allowed")
+}
+
+func Test_Context(t *testing.T) {
+	p := mustParsePlan(t, testPlan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Inspect(ctx, p)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = run(context.Background(), "slow", func(ctx context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 0, nil
+	})
+	assert.Nil(t, err)
+
+	// The operation has stopped by the time run returns
+	stopped := false
+	timeout, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+	_, err = run(timeout, "slow", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		stopped = true
+		return 0, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, stopped)
+}
+
+func Test_NilPlan(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := Inspect(ctx, nil)
+	assert.ErrorIs(t, err, ErrNilPlan)
+	_, err = Compare(ctx, mustParsePlan(t, testPlan), nil)
+	assert.ErrorIs(t, err, ErrNilPlan)
+	_, err = Summary(ctx, nil)
+	assert.ErrorIs(t, err, ErrNilPlan)
+	assert.ErrorIs(t, VerifyApproval(ctx, &Approval{}, nil, nil), ErrNilPlan)
+}
@@ -0,0 +1,132 @@
+package tfplan
+
+import "github.com/orange-car/tfplan/internal/plan"
+
+// A JSON Terraform plan, as produced by terraform show --json. Its fields
+// are those of the plan JSON, it is inspected with the operations of the
+// package.
+type Plan plan.Plan
+
+// Filter criteria and the values they match.
+type (
+	InspectFilter = plan.InspectFilter
+	Filter        = plan.Filter
+	DiffPattern   = plan.DiffPattern
+	Patterns      = plan.Patterns
+	RewriteRules  = plan.RewriteRules
+	RewriteRule   = plan.RewriteRule
+	PlanMetadata  = plan.PlanMetadata
+)
+
+// Results of inspecting and comparing plans.
+type (
+	Diff                  = plan.Diff
+	EntityDiff            = plan.EntityDiff
+	InspectDiff           = plan.InspectDiff
+	InspectOutput         = plan.InspectOutput
+	SourceLocation        = plan.SourceLocation
+	CompareOptions        = plan.CompareOptions
+	CompareInspectsOutput = plan.CompareInspectsOutput
+	CompareDiff           = plan.CompareDiff
+	CompareEntityDiff     = plan.CompareEntityDiff
+	CompareValue          = plan.CompareValue
+	SuppressedDiff        = plan.SuppressedDiff
+	LabelledInspect       = plan.LabelledInspect
+	CompareMatrixOutput   = plan.CompareMatrixOutput
+	CompareMatrix         = plan.CompareMatrix
+	CompareMatrixRow      = plan.CompareMatrixRow
+	PrettyOptions         = plan.PrettyOptions
+)
+
+// Results of the other operations on a plan.
+type (
+	SummaryOutput       = plan.SummaryOutput
+	SummaryCounts       = plan.SummaryCounts
+	QueryOutput         = plan.QueryOutput
+	QueryChange         = plan.QueryChange
+	QueryAttribute      = plan.QueryAttribute
+	ImpactOutput        = plan.ImpactOutput
+	ImpactEntry         = plan.ImpactEntry
+	GraphOutput         = plan.GraphOutput
+	GraphNode           = plan.GraphNode
+	GraphEdge           = plan.GraphEdge
+	FingerprintOutput   = plan.FingerprintOutput
+	LintOutput          = plan.LintOutput
+	LintIssue           = plan.LintIssue
+	CIReport            = plan.CIReport
+	CodeQualityIssue    = plan.CodeQualityIssue
+	CodeQualityLocation = plan.CodeQualityLocation
+	CodeQualityLines    = plan.CodeQualityLines
+)
+
+// Policies, snapshots and approvals.
+type (
+	PolicySet       = plan.PolicySet
+	Policy          = plan.Policy
+	PolicyOutput    = plan.PolicyOutput
+	PolicyResult    = plan.PolicyResult
+	SnapshotContent = plan.SnapshotContent
+	ApprovalPayload = plan.ApprovalPayload
+	AllowedSigners  = plan.AllowedSigners
+	AllowedSigner   = plan.AllowedSigner
+)
+
+// A snapshot of an inspected plan, verified with VerifySnapshot.
+type Snapshot plan.Snapshot
+
+// A signed record of a human approving a plan, verified with
+// VerifyApproval.
+type Approval plan.Approval
+
+const (
+	FilterModeAttribute = plan.FilterModeAttribute
+	FilterModeAll       = plan.FilterModeAll
+
+	RewriteTypeRegex    = plan.RewriteTypeRegex
+	RewriteTypeWildcard = plan.RewriteTypeWildcard
+
+	CompareModeFull    = plan.CompareModeFull
+	CompareModeAfter   = plan.CompareModeAfter
+	CompareModeBefore  = plan.CompareModeBefore
+	CompareModeActions = plan.CompareModeActions
+
+	CompareScopeRaw       = plan.CompareScopeRaw
+	CompareScopeVariables = plan.CompareScopeVariables
+	CompareScopeProviders = plan.CompareScopeProviders
+	CompareScopeMetadata  = plan.CompareScopeMetadata
+
+	CompareViewBlocks     = plan.CompareViewBlocks
+	CompareViewSideBySide = plan.CompareViewSideBySide
+	CompareViewUnified    = plan.CompareViewUnified
+
+	SuppressedUnknown   = plan.SuppressedUnknown
+	SuppressedTyped     = plan.SuppressedTyped
+	SuppressedUnordered = plan.SuppressedUnordered
+	SuppressedIgnored   = plan.SuppressedIgnored
+
+	SummaryFormatTable    = plan.SummaryFormatTable
+	SummaryFormatJSON     = plan.SummaryFormatJSON
+	SummaryFormatMarkdown = plan.SummaryFormatMarkdown
+
+	QueryFormatJSON     = plan.QueryFormatJSON
+	QueryFormatText     = plan.QueryFormatText
+	QueryFormatMarkdown = plan.QueryFormatMarkdown
+
+	GraphFormatDOT     = plan.GraphFormatDOT
+	GraphFormatMermaid = plan.GraphFormatMermaid
+	GraphFormatJSON    = plan.GraphFormatJSON
+
+	CIOutputGitHub = plan.CIOutputGitHub
+	CIOutputGitLab = plan.CIOutputGitLab
+
+	PolicyStatusPass = plan.PolicyStatusPass
+	PolicyStatusWarn = plan.PolicyStatusWarn
+	PolicyStatusFail = plan.PolicyStatusFail
+
+	LintSeverityError   = plan.LintSeverityError
+	LintSeverityWarning = plan.LintSeverityWarning
+
+	SnapshotVersion    = plan.SnapshotVersion
+	ApprovalVersion    = plan.ApprovalVersion
+	FingerprintVersion = plan.FingerprintVersion
+)